@@ -11,20 +11,44 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"database/sql"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"outlook-mail-manager/internal/database"
 	"outlook-mail-manager/internal/models"
 	"outlook-mail-manager/internal/services"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// defaultRecycleRetentionDays 回收站默认保留天数
+const defaultRecycleRetentionDays = 30
+
+// messagePageSize 邮件列表每页的邮件数
+const messagePageSize = 20
+
+// importVerifyConcurrency 导入后检测Token的最大并发数，避免短时间内大量请求触发限流
+const importVerifyConcurrency = 5
+
+// 邮件操作的超时时间（包括Token刷新和REST API失败后回退到IMAP的时间）
+const (
+	mailListTimeout     = time.Minute     // 获取文件夹列表和邮件列表
+	mailDetailTimeout   = 2 * time.Minute // 获取邮件详情和附件（可能包含大附件）
+	mailActionTimeout   = time.Minute     // 标记已读等修改邮件的操作
+	accountCheckTimeout = time.Minute     // 检测账号可用性
+)
+
 // tokenCache Token缓存结构
 //
 // 用于在内存中缓存已获取的访问令牌，避免频繁刷新Token
@@ -42,11 +66,29 @@ type App struct {
 	ctx        context.Context           // Wails运行时上下文，用于调用系统对话框等功能
 	accountSvc *services.AccountService  // 账号服务：处理账号的CRUD操作
 	groupSvc   *services.GroupService    // 分组服务：处理分组的CRUD操作
+	tagSvc     *services.TagService      // 标签服务：处理标签的CRUD和账号打标签
+	fieldSvc   *services.FieldService    // 自定义字段服务：处理字段定义和字段值
+	settingSvc *services.SettingService  // 设置服务：持久化用户设置
+	tplSvc     *services.TemplateService // 导入模板服务：维护账号文本的行模板
+	exportSvc  *services.ExportService   // 导出服务：按格式导出账号
+	auditSvc   *services.AuditService    // 审计服务：记录所有修改数据的操作
 	graphSvc   *services.GraphService    // Graph服务：封装Microsoft Outlook API调用
 	imapSvc    *services.IMAPService     // IMAP服务：用于Hotmail等个人账户
+	watchSvc   *services.WatchService    // 新邮件监听服务：IMAP IDLE推送或轮询
 	tokenMu    sync.RWMutex              // Token缓存读写锁，保证并发安全
 	tokens     map[int64]*tokenCache     // Token缓存映射表，key为账号ID
 	imapTokens map[int64]*tokenCache     // IMAP Token缓存（使用不同scope）
+
+	importMu     sync.Mutex         // 保护importCancel
+	importCancel context.CancelFunc // 正在进行的文件导入的取消函数，没有时为nil
+
+	opsMu      sync.Mutex                // 保护operations
+	operations map[string]*mailOperation // 进行中的邮件操作: 前端请求ID -> 操作
+}
+
+// mailOperation 一次进行中的邮件操作（见beginOperation）
+type mailOperation struct {
+	cancel context.CancelFunc
 }
 
 // NewApp 创建应用实例
@@ -58,19 +100,27 @@ type App struct {
 //   - *App: 初始化完成的应用实例
 func NewApp() *App {
 	return &App{
-		accountSvc: services.NewAccountService(), // 初始化账号服务
-		groupSvc:   services.NewGroupService(),   // 初始化分组服务
-		graphSvc:   services.NewGraphService(),   // 初始化Graph API服务
-		imapSvc:    services.NewIMAPService(),    // 初始化IMAP服务
-		tokens:     make(map[int64]*tokenCache),  // 初始化空的Token缓存
-		imapTokens: make(map[int64]*tokenCache),  // 初始化IMAP Token缓存
+		accountSvc: services.NewAccountService(),    // 初始化账号服务
+		groupSvc:   services.NewGroupService(),      // 初始化分组服务
+		tagSvc:     services.NewTagService(),        // 初始化标签服务
+		fieldSvc:   services.NewFieldService(),      // 初始化自定义字段服务
+		settingSvc: services.NewSettingService(),    // 初始化设置服务
+		tplSvc:     services.NewTemplateService(),   // 初始化导入模板服务
+		exportSvc:  services.NewExportService(),     // 初始化导出服务
+		auditSvc:   services.NewAuditService(),      // 初始化审计服务
+		graphSvc:   services.NewGraphService(),      // 初始化Graph API服务
+		imapSvc:    services.NewIMAPService(),       // 初始化IMAP服务
+		watchSvc:   services.NewWatchService(),      // 初始化新邮件监听服务
+		tokens:     make(map[int64]*tokenCache),     // 初始化空的Token缓存
+		imapTokens: make(map[int64]*tokenCache),     // 初始化IMAP Token缓存
+		operations: make(map[string]*mailOperation), // 初始化进行中的邮件操作
 	}
 }
 
 // startup Wails应用启动回调
 //
 // 在应用窗口显示前由Wails框架自动调用
-// 负责初始化数据库连接和执行数据迁移
+// 负责初始化数据库连接、执行数据迁移和清理回收站中过期的账号
 //
 // 参数：
 //   - ctx: Wails运行时上下文，包含窗口操作、对话框等功能
@@ -79,18 +129,35 @@ func (a *App) startup(ctx context.Context) {
 	if err := database.Init(); err != nil {
 		// 数据库初始化失败时记录错误日志，但不阻止应用启动
 		runtime.LogError(ctx, "database init failed: "+err.Error())
+		return
+	}
+	// 自动清理回收站中超过保留天数的账号
+	days := a.settingSvc.GetInt(services.SettingRecycleRetentionDays, defaultRecycleRetentionDays)
+	if n, err := a.accountSvc.PurgeExpired(days); err != nil {
+		log.Printf("[App] 回收站自动清理失败: %v", err)
+	} else if n > 0 {
+		log.Printf("[App] 回收站自动清理 %d 个账号（保留 %d 天）", n, days)
+		a.auditSvc.RecordSystem(services.AuditAccountPurge, services.AuditTargetAccount, 0, "",
+			fmt.Sprintf("自动清理回收站中超过 %d 天的 %d 个账号", days, n), nil, map[string]int{"purged": n})
+	}
+	// 恢复新邮件提醒（回收站中的和已删除的账号不再监听）
+	a.pruneWatchedAccounts()
+	for _, id := range a.GetWatchedAccounts() {
+		a.startWatch(id)
 	}
 }
 
 // shutdown Wails应用关闭回调
 //
 // 在应用窗口关闭时由Wails框架自动调用
-// 负责清理资源，关闭数据库连接
+// 负责清理资源，关闭新邮件监听连接、IMAP连接池和数据库连接
 //
 // 参数：
 //   - ctx: Wails运行时上下文
 func (a *App) shutdown(ctx context.Context) {
-	database.Close() // 关闭SQLite数据库连接
+	a.watchSvc.Close() // 停止所有新邮件监听
+	a.imapSvc.Close()  // 关闭IMAP连接池中的连接
+	database.Close()   // 关闭SQLite数据库连接
 }
 
 // ============================================================================
@@ -100,16 +167,263 @@ func (a *App) shutdown(ctx context.Context) {
 // ImportAccounts 批量导入账号
 //
 // 解析用户输入的文本内容，批量创建账号记录
-// 支持的格式：邮箱----密码----ClientID----RefreshToken 或 Tab分隔
+// 支持的格式：邮箱----密码----ClientID----RefreshToken 或 Tab分隔，其他格式通过导入模板指定，
+// 也支持带表头的CSV和JSON对象数组
 //
 // 参数：
 //   - content: 包含账号信息的多行文本
+//   - opts: 导入选项
+//   - Policy: 邮箱已存在时的处理策略（skip/credentials/credentials-group/replace），为空时为replace
+//   - Format: 数据格式（text/csv/json），为空时为text
+//   - Template: 导入模板名称（standard/auto/自定义模板，text格式），为空时为standard
+//   - Mapping: 表头映射（csv/json格式），表头名 -> 字段名
+//   - Passphrase/Group: 加密包口令、目标分组
+//   - Verify: 导入后立即检测新建和更新账号的Token（有限并发），结果写入报告
 //
 // 返回值：
-//   - int: 成功导入的账号数量
+//   - *models.ImportReport: 导入报告，包含新建/更新/跳过/无效的逐行结果
 //   - error: 导入过程中的错误
-func (a *App) ImportAccounts(content string) (int, error) {
-	return a.accountSvc.Import(content)
+func (a *App) ImportAccounts(content string, opts models.ImportOptions) (*models.ImportReport, error) {
+	report, err := a.accountSvc.Import(content, opts)
+	if err == nil {
+		a.afterImport(report, opts)
+	}
+	return report, err
+}
+
+// ImportAccountsFromFile 从用户选择的文件导入账号
+//
+// 弹出打开文件对话框，之后流式读取文件、按批次在事务中写入，
+// 不需要把整个文件通过前端传递；同一时间只能进行一个文件导入
+// 导入过程中发送import-progress事件（已处理记录数, 已读取字节数, 文件总字节数），
+// 可以调用CancelImport中途取消，已写入的批次保留
+//
+// 参数：
+//   - opts: 导入选项，Format为空时按文件扩展名识别（.csv、.json、.ombundle，其他按文本行）
+//
+// 返回值：
+//   - *models.ImportReport: 导入报告（用户取消选择文件时为nil；取消导入时Cancelled为true）
+//   - error: 已有导入在进行、文件无法读取、格式或策略无效时返回错误
+func (a *App) ImportAccountsFromFile(opts models.ImportOptions) (*models.ImportReport, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "选择账号文件",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Account Files", Pattern: "*.txt;*.csv;*.json;*.ombundle"},
+			{DisplayName: "All Files", Pattern: "*.*"},
+		},
+	})
+	if err != nil || path == "" {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.importMu.Lock()
+	if a.importCancel != nil {
+		a.importMu.Unlock()
+		return nil, fmt.Errorf("import already in progress")
+	}
+	a.importCancel = cancel
+	a.importMu.Unlock()
+	defer func() {
+		a.importMu.Lock()
+		a.importCancel = nil
+		a.importMu.Unlock()
+	}()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Format == "" {
+		opts.Format = importFormatByExt(path)
+	}
+
+	counter := &countingReader{r: f}
+	report, err := a.accountSvc.ImportStream(ctx, counter, opts, func(records int) {
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, "import-progress", records, counter.Count(), info.Size())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[App] 文件导入账号: path=%s, total=%d, cancelled=%v", path, report.Total, report.Cancelled)
+	a.afterImport(report, opts)
+	return report, nil
+}
+
+// CancelImport 取消正在进行的文件导入
+//
+// 导入在当前批次提交后停止，没有进行中的导入时不做任何操作
+func (a *App) CancelImport() {
+	a.importMu.Lock()
+	defer a.importMu.Unlock()
+	if a.importCancel != nil {
+		a.importCancel()
+		log.Printf("[App] 取消文件导入")
+	}
+}
+
+// importFormatByExt 按文件扩展名识别导入格式，无法识别时为空（文本行或自动识别加密包）
+func importFormatByExt(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return models.ImportFormatCSV
+	case ".json":
+		return models.ImportFormatJSON
+	case ".ombundle":
+		return models.ImportFormatBundle
+	}
+	return ""
+}
+
+// countingReader 统计已读取字节数的Reader，用于报告文件导入进度
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+// Read 读取并累计字节数
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Count 返回已读取的字节数
+func (c *countingReader) Count() int64 {
+	return c.n.Load()
+}
+
+// afterImport 导入完成后的处理
+//
+// 清除被更新账号的内存Token缓存（凭据可能已变化），按需检测导入的账号，并记录审计日志
+// 审计日志中记录新建和更新的每个账号（ID、邮箱和处理结果）
+func (a *App) afterImport(report *models.ImportReport, opts models.ImportOptions) {
+	var ids []int64
+	var emails []string
+	accounts := []map[string]interface{}{}
+	for _, l := range report.Lines {
+		if l.Status == models.ImportUpdated {
+			a.clearTokenCache(l.AccountID)
+		}
+		if l.Status == models.ImportCreated || l.Status == models.ImportUpdated {
+			ids = append(ids, l.AccountID)
+			emails = append(emails, l.Email)
+			accounts = append(accounts, map[string]interface{}{"id": l.AccountID, "email": l.Email, "status": l.Status})
+		}
+	}
+	if opts.Verify {
+		a.verifyImported(report)
+	}
+	a.auditSvc.Record(services.AuditAccountImport, services.AuditTargetAccount, singleID(ids), strings.Join(emails, ", "),
+		fmt.Sprintf("导入账号：新建 %d，更新 %d，跳过 %d，无效 %d，失败 %d",
+			report.Created, report.Updated, report.Skipped, report.Invalid, report.Failed),
+		nil, map[string]interface{}{
+			"policy": opts.Policy, "format": opts.Format, "template": opts.Template,
+			"total": report.Total, "created": report.Created, "updated": report.Updated,
+			"skipped": report.Skipped, "invalid": report.Invalid, "failed": report.Failed,
+			"verified": report.Verified, "dead": report.Dead,
+			"groupsCreated": report.GroupsCreated,
+			"accounts":      accounts,
+		})
+}
+
+// verifyImported 导入后批量检测新建和更新账号的Token
+//
+// 以有限并发（importVerifyConcurrency）检测账号（见checkAccount），结果写回报告的每一行，
+// 账号状态由checkAccount更新；每完成一个账号发送import-verify-progress事件（已完成数, 总数）
+func (a *App) verifyImported(report *models.ImportReport) {
+	var targets []int // 需要检测的行在report.Lines中的下标
+	for i, l := range report.Lines {
+		if l.Status == models.ImportCreated || l.Status == models.ImportUpdated {
+			targets = append(targets, i)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, importVerifyConcurrency)
+	done := 0
+	for _, i := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(line *models.ImportLineResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := a.checkAccount(line.AccountID)
+
+			mu.Lock()
+			defer mu.Unlock()
+			live := err == nil
+			line.Live = &live
+			report.Verified++
+			if err != nil {
+				line.LiveError = err.Error()
+				report.Dead++
+			}
+			done++
+			if a.ctx != nil {
+				runtime.EventsEmit(a.ctx, "import-verify-progress", done, len(targets))
+			}
+		}(&report.Lines[i])
+	}
+	wg.Wait()
+	log.Printf("[App] 导入后检测完成: verified=%d, dead=%d", report.Verified, report.Dead)
+}
+
+// ExportAccounts 导出账号到用户选择的文件
+//
+// 弹出保存对话框，按筛选条件查询账号后逐条写入文件
+//
+// 参数：
+//   - filter: 筛选条件（分组、标签、状态、协议、关键字）
+//   - opts: 导出选项
+//   - Format: 导出格式（line/tab/csv/json），为空时为line
+//   - Fields: 导出字段及顺序，为空时导出全部可重新导入的字段（如去掉refresh_token可避免泄露凭据）
+//
+// 返回值：
+//   - int: 导出的账号数量（用户取消时为0）
+//   - error: 查询或写入失败时返回错误
+func (a *App) ExportAccounts(filter models.AccountFilter, opts models.ExportOptions) (int, error) {
+	filter.Deleted = false
+	accounts, err := a.accountSvc.List(filter)
+	if err != nil {
+		return 0, err
+	}
+	ext := services.ExportExtension(opts.Format)
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: "accounts-" + time.Now().Format("20060102") + "." + ext,
+		Filters: []runtime.FileFilter{
+			{DisplayName: strings.ToUpper(ext) + " Files", Pattern: "*." + ext},
+		},
+	})
+	if err != nil || path == "" {
+		return 0, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := a.exportSvc.Export(w, accounts, opts); err != nil {
+		return 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	log.Printf("[App] 导出账号: count=%d, format=%s, path=%s", len(accounts), opts.Format, path)
+	return len(accounts), nil
 }
 
 // GetAccounts 获取账号列表
@@ -123,23 +437,40 @@ func (a *App) ImportAccounts(content string) (int, error) {
 //   - []models.Account: 账号列表
 //   - error: 查询错误
 func (a *App) GetAccounts(groupID *int64) ([]models.Account, error) {
-	return a.accountSvc.List(groupID)
+	return a.accountSvc.List(models.AccountFilter{GroupID: groupID})
+}
+
+// FilterAccounts 按组合条件获取账号列表
+//
+// 分组和标签条件可同时使用（AND关系），标签支持任一匹配和全部匹配
+//
+// 参数：
+//   - filter: 筛选条件
+//
+// 返回值：
+//   - []models.Account: 账号列表
+//   - error: 查询错误
+func (a *App) FilterAccounts(filter models.AccountFilter) ([]models.Account, error) {
+	return a.accountSvc.List(filter)
 }
 
 // DeleteAccount 删除单个账号
 //
+// 账号会被移入回收站，可通过RestoreAccounts恢复
+//
 // 参数：
 //   - id: 要删除的账号ID
 //
 // 返回值：
 //   - error: 删除失败时返回错误
 func (a *App) DeleteAccount(id int64) error {
-	return a.accountSvc.Delete(id)
+	return a.DeleteAccounts([]int64{id})
 }
 
 // DeleteAccounts 批量删除账号
 //
-// 遍历ID列表逐个删除，遇到错误立即返回
+// 遍历ID列表逐个移入回收站，遇到错误立即返回
+// 已删除的账号同时关闭新邮件提醒（恢复后需要重新开启）
 //
 // 参数：
 //   - ids: 要删除的账号ID列表
@@ -147,10 +478,15 @@ func (a *App) DeleteAccount(id int64) error {
 // 返回值：
 //   - error: 删除过程中的第一个错误
 func (a *App) DeleteAccounts(ids []int64) error {
+	defer a.pruneWatchedAccounts()
 	for _, id := range ids {
+		a.clearTokenCache(id)
+		email := strings.Join(a.accountSvc.Emails([]int64{id}), "")
 		if err := a.accountSvc.Delete(id); err != nil {
 			return err
 		}
+		a.auditSvc.Record(services.AuditAccountDelete, services.AuditTargetAccount, id, email,
+			"删除账号（移入回收站）", map[string]bool{"deleted": false}, map[string]bool{"deleted": true})
 	}
 	return nil
 }
@@ -165,7 +501,7 @@ func (a *App) DeleteAccounts(ids []int64) error {
 //   - error: 移动过程中的第一个错误
 func (a *App) MoveAccountsToGroup(ids []int64, groupID int64) error {
 	for _, id := range ids {
-		if err := a.accountSvc.UpdateGroup(id, groupID); err != nil {
+		if err := a.MoveAccountToGroup(id, groupID); err != nil {
 			return err
 		}
 	}
@@ -174,7 +510,7 @@ func (a *App) MoveAccountsToGroup(ids []int64, groupID int64) error {
 
 // CheckAccountToken 检测账号Token有效性
 //
-// 强制刷新Token以验证账号的RefreshToken是否仍然有效
+// 强制刷新Token以验证账号的RefreshToken是否仍然有效；密码登录账号尝试登录IMAP服务器
 //
 // 参数：
 //   - accountID: 要检测的账号ID
@@ -183,7 +519,7 @@ func (a *App) MoveAccountsToGroup(ids []int64, groupID int64) error {
 //   - bool: Token是否有效
 //   - error: 刷新Token时的错误信息
 func (a *App) CheckAccountToken(accountID int64) (bool, error) {
-	_, err := a.getToken(accountID, true)
+	err := a.checkAccount(accountID)
 	return err == nil, err
 }
 
@@ -196,7 +532,21 @@ func (a *App) CheckAccountToken(accountID int64) (bool, error) {
 // 返回值：
 //   - error: 更新失败时返回错误
 func (a *App) MoveAccountToGroup(accountID, groupID int64) error {
-	return a.accountSvc.UpdateGroup(accountID, groupID)
+	account, err := a.accountSvc.GetByID(accountID)
+	if err != nil {
+		return err
+	}
+	if err := a.accountSvc.UpdateGroup(accountID, groupID); err != nil {
+		return err
+	}
+	before := map[string]interface{}{"groupId": account.GroupID}
+	if account.GroupID != nil {
+		before["groupName"] = a.groupSvc.GetName(*account.GroupID)
+	}
+	groupName := a.groupSvc.GetName(groupID)
+	a.auditSvc.Record(services.AuditAccountMove, services.AuditTargetAccount, accountID, account.Email,
+		"移动账号到分组 "+groupName, before, map[string]interface{}{"groupId": groupID, "groupName": groupName})
+	return nil
 }
 
 // ============================================================================
@@ -221,7 +571,11 @@ func (a *App) GetGroups() ([]models.Group, error) {
 //   - *models.Group: 创建成功的分组对象
 //   - error: 创建失败时返回错误
 func (a *App) CreateGroup(name string) (*models.Group, error) {
-	return a.groupSvc.Create(name, nil) // 第二个参数为父分组ID，暂不支持嵌套分组
+	group, err := a.groupSvc.Create(name, nil) // 第二个参数为父分组ID，暂不支持嵌套分组
+	if err == nil {
+		a.auditSvc.Record(services.AuditGroupCreate, services.AuditTargetGroup, group.ID, name, "创建分组", nil, group)
+	}
+	return group, err
 }
 
 // DeleteGroup 删除分组
@@ -229,37 +583,605 @@ func (a *App) CreateGroup(name string) (*models.Group, error) {
 // 删除分组后，该分组下的账号会被设置为无分组状态(group_id=NULL)
 //
 // 参数：
-//   - id: 要删除的分组ID
+//   - id: 要删除的分组ID
+//
+// 返回值：
+//   - error: 删除失败时返回错误
+func (a *App) DeleteGroup(id int64) error {
+	name := a.groupSvc.GetName(id)
+	if err := a.groupSvc.Delete(id); err != nil {
+		return err
+	}
+	a.auditSvc.Record(services.AuditGroupDelete, services.AuditTargetGroup, id, name,
+		"删除分组（账号移至默认分组）", map[string]string{"name": name}, nil)
+	return nil
+}
+
+// ClearGroup 清空分组内所有账号
+//
+// 将指定分组下的所有账号移入回收站，误操作后可在回收站中恢复
+//
+// 参数：
+//   - groupID: 要清空的分组ID
+//
+// 返回值：
+//   - error: 删除失败时返回错误
+func (a *App) ClearGroup(groupID int64) error {
+	n, err := a.accountSvc.DeleteByGroup(groupID)
+	if err != nil {
+		return err
+	}
+	a.pruneWatchedAccounts()
+	a.auditSvc.Record(services.AuditGroupClear, services.AuditTargetGroup, groupID, a.groupSvc.GetName(groupID),
+		fmt.Sprintf("清空分组，%d 个账号移入回收站", n), nil, map[string]int{"deleted": n})
+	return nil
+}
+
+// ============================================================================
+// 回收站API - 提供已删除账号的查看、恢复和彻底删除
+// ============================================================================
+
+// GetDeletedAccounts 获取回收站中的账号列表
+//
+// 返回值：
+//   - []models.Account: 已删除的账号列表，最近删除的在前（含deletedAt）
+//   - error: 查询错误
+func (a *App) GetDeletedAccounts() ([]models.Account, error) {
+	return a.accountSvc.List(models.AccountFilter{Deleted: true})
+}
+
+// RestoreAccounts 从回收站恢复账号
+//
+// 删除时已关闭的新邮件提醒不会自动恢复，需要重新开启
+//
+// 参数：
+//   - ids: 要恢复的账号ID列表
+//
+// 返回值：
+//   - int: 实际恢复的账号数量
+//   - error: 恢复失败时返回错误
+func (a *App) RestoreAccounts(ids []int64) (int, error) {
+	emails := a.accountSvc.Emails(ids)
+	n, err := a.accountSvc.Restore(ids)
+	if err == nil && n > 0 {
+		a.auditSvc.Record(services.AuditAccountRestore, services.AuditTargetAccount, singleID(ids), strings.Join(emails, ", "),
+			fmt.Sprintf("从回收站恢复 %d 个账号", n), map[string]bool{"deleted": true}, map[string]bool{"deleted": false})
+	}
+	return n, err
+}
+
+// PurgeAccounts 彻底删除回收站中的账号
+//
+// 彻底删除后账号的凭据、标签和自定义字段都将无法恢复
+//
+// 参数：
+//   - ids: 要彻底删除的账号ID列表
+//
+// 返回值：
+//   - int: 实际删除的账号数量
+//   - error: 删除失败时返回错误
+func (a *App) PurgeAccounts(ids []int64) (int, error) {
+	emails := a.accountSvc.Emails(ids)
+	n, err := a.accountSvc.Purge(ids)
+	if err == nil && n > 0 {
+		a.auditSvc.Record(services.AuditAccountPurge, services.AuditTargetAccount, singleID(ids), strings.Join(emails, ", "),
+			fmt.Sprintf("彻底删除 %d 个账号", n), map[string]bool{"deleted": true}, nil)
+	}
+	return n, err
+}
+
+// EmptyRecycleBin 清空回收站
+//
+// 返回值：
+//   - int: 实际删除的账号数量
+//   - error: 删除失败时返回错误
+func (a *App) EmptyRecycleBin() (int, error) {
+	n, err := a.accountSvc.PurgeAll()
+	if err == nil && n > 0 {
+		a.auditSvc.Record(services.AuditAccountPurge, services.AuditTargetAccount, 0, "",
+			fmt.Sprintf("清空回收站，彻底删除 %d 个账号", n), nil, map[string]int{"purged": n})
+	}
+	return n, err
+}
+
+// GetRecycleRetentionDays 获取回收站保留天数
+//
+// 返回值：
+//   - int: 保留天数，0表示永不自动清理
+func (a *App) GetRecycleRetentionDays() int {
+	return a.settingSvc.GetInt(services.SettingRecycleRetentionDays, defaultRecycleRetentionDays)
+}
+
+// SetRecycleRetentionDays 设置回收站保留天数
+//
+// 超过保留天数的已删除账号会在下次启动时被自动彻底删除
+//
+// 参数：
+//   - days: 保留天数，0表示永不自动清理
+//
+// 返回值：
+//   - error: 天数为负数或保存失败时返回错误
+func (a *App) SetRecycleRetentionDays(days int) error {
+	if days < 0 {
+		return fmt.Errorf("retention days must be >= 0")
+	}
+	before := a.GetRecycleRetentionDays()
+	if err := a.settingSvc.Set(services.SettingRecycleRetentionDays, strconv.Itoa(days)); err != nil {
+		return err
+	}
+	a.auditSvc.Record(services.AuditSettingUpdate, services.AuditTargetSetting, 0, services.SettingRecycleRetentionDays,
+		"修改回收站保留天数", before, days)
+	return nil
+}
+
+// GetAutoMarkRead 获取查看邮件时是否自动标记为已读
+//
+// 返回值：
+//   - bool: true表示打开邮件详情时自动标记为已读
+func (a *App) GetAutoMarkRead() bool {
+	return a.settingSvc.Get(services.SettingAutoMarkRead, "0") == "1"
+}
+
+// SetAutoMarkRead 设置查看邮件时是否自动标记为已读
+//
+// 参数：
+//   - enabled: true表示打开邮件详情时自动标记为已读
+//
+// 返回值：
+//   - error: 保存失败时返回错误
+func (a *App) SetAutoMarkRead(enabled bool) error {
+	before := a.GetAutoMarkRead()
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	if err := a.settingSvc.Set(services.SettingAutoMarkRead, value); err != nil {
+		return err
+	}
+	a.auditSvc.Record(services.AuditSettingUpdate, services.AuditTargetSetting, 0, services.SettingAutoMarkRead,
+		"修改查看邮件时自动标记已读", before, enabled)
+	return nil
+}
+
+// ============================================================================
+// 标签管理API - 提供标签的增删改查和批量打标签操作
+// ============================================================================
+
+// GetTags 获取所有标签列表
+//
+// 返回值：
+//   - []models.Tag: 标签列表，包含每个标签的账号数量
+//   - error: 查询错误
+func (a *App) GetTags() ([]models.Tag, error) {
+	return a.tagSvc.List()
+}
+
+// CreateTag 创建新标签
+//
+// 参数：
+//   - name: 标签名称
+//   - color: 标签颜色（可为空）
+//
+// 返回值：
+//   - *models.Tag: 创建成功的标签对象
+//   - error: 创建失败时返回错误（如重名）
+func (a *App) CreateTag(name, color string) (*models.Tag, error) {
+	tag, err := a.tagSvc.Create(name, color)
+	if err == nil {
+		a.auditSvc.Record(services.AuditTagCreate, services.AuditTargetTag, tag.ID, tag.Name, "创建标签", nil, tag)
+	}
+	return tag, err
+}
+
+// UpdateTag 更新标签名称和颜色
+//
+// 参数：
+//   - id: 标签ID
+//   - name: 新的标签名称
+//   - color: 新的标签颜色
+//
+// 返回值：
+//   - error: 更新失败时返回错误
+func (a *App) UpdateTag(id int64, name, color string) error {
+	before, _ := a.tagSvc.Get(id)
+	if err := a.tagSvc.Update(id, name, color); err != nil {
+		return err
+	}
+	a.auditSvc.Record(services.AuditTagUpdate, services.AuditTargetTag, id, name, "修改标签",
+		before, models.Tag{ID: id, Name: name, Color: color})
+	return nil
+}
+
+// DeleteTag 删除标签
+//
+// 仅删除标签及其关联，不影响账号本身
+//
+// 参数：
+//   - id: 要删除的标签ID
+//
+// 返回值：
+//   - error: 删除失败时返回错误
+func (a *App) DeleteTag(id int64) error {
+	before, _ := a.tagSvc.Get(id)
+	if err := a.tagSvc.Delete(id); err != nil {
+		return err
+	}
+	name := ""
+	if before != nil {
+		name = before.Name
+	}
+	a.auditSvc.Record(services.AuditTagDelete, services.AuditTargetTag, id, name, "删除标签", before, nil)
+	return nil
+}
+
+// TagAccounts 为多个账号批量打上标签
+//
+// 参数：
+//   - ids: 账号ID列表
+//   - tagID: 标签ID
+//
+// 返回值：
+//   - error: 写入失败时返回错误
+func (a *App) TagAccounts(ids []int64, tagID int64) error {
+	if err := a.tagSvc.AddToAccounts(tagID, ids); err != nil {
+		return err
+	}
+	a.recordTagChange(services.AuditAccountTag, ids, tagID, "为 %d 个账号添加标签 %s")
+	return nil
+}
+
+// UntagAccounts 批量移除多个账号的标签
+//
+// 参数：
+//   - ids: 账号ID列表
+//   - tagID: 标签ID
+//
+// 返回值：
+//   - error: 删除失败时返回错误
+func (a *App) UntagAccounts(ids []int64, tagID int64) error {
+	if err := a.tagSvc.RemoveFromAccounts(tagID, ids); err != nil {
+		return err
+	}
+	a.recordTagChange(services.AuditAccountUntag, ids, tagID, "移除 %d 个账号的标签 %s")
+	return nil
+}
+
+// recordTagChange 记录批量打标签/取消标签的审计日志
+func (a *App) recordTagChange(action string, ids []int64, tagID int64, summaryFormat string) {
+	tagName := ""
+	if tag, err := a.tagSvc.Get(tagID); err == nil {
+		tagName = tag.Name
+	}
+	a.auditSvc.Record(action, services.AuditTargetAccount, singleID(ids), strings.Join(a.accountSvc.Emails(ids), ", "),
+		fmt.Sprintf(summaryFormat, len(ids), tagName), nil, map[string]interface{}{"tagId": tagID, "tagName": tagName})
+}
+
+// ============================================================================
+// 自定义字段和备注API - 提供账号附加信息的维护
+// ============================================================================
+
+// GetCustomFields 获取所有自定义字段定义
+//
+// 返回值：
+//   - []models.CustomField: 字段列表
+//   - error: 查询错误
+func (a *App) GetCustomFields() ([]models.CustomField, error) {
+	return a.fieldSvc.List()
+}
+
+// CreateCustomField 创建自定义字段
+//
+// 参数：
+//   - name: 字段名称
+//   - fieldType: 字段类型（text/number/date/email/phone）
+//
+// 返回值：
+//   - *models.CustomField: 创建成功的字段对象
+//   - error: 创建失败时返回错误
+func (a *App) CreateCustomField(name, fieldType string) (*models.CustomField, error) {
+	field, err := a.fieldSvc.Create(name, fieldType)
+	if err == nil {
+		a.auditSvc.Record(services.AuditFieldCreate, services.AuditTargetField, field.ID, field.Name, "创建自定义字段", nil, field)
+	}
+	return field, err
+}
+
+// UpdateCustomField 更新自定义字段的名称和类型
+//
+// 参数：
+//   - id: 字段ID
+//   - name: 新的字段名称
+//   - fieldType: 新的字段类型
+//
+// 返回值：
+//   - error: 更新失败或已有字段值不符合新类型时返回错误
+func (a *App) UpdateCustomField(id int64, name, fieldType string) error {
+	before, _ := a.fieldSvc.Get(id)
+	if err := a.fieldSvc.Update(id, name, fieldType); err != nil {
+		return err
+	}
+	a.auditSvc.Record(services.AuditFieldUpdate, services.AuditTargetField, id, name, "修改自定义字段",
+		before, models.CustomField{ID: id, Name: name, Type: fieldType})
+	return nil
+}
+
+// DeleteCustomField 删除自定义字段及所有账号在该字段上的值
+//
+// 参数：
+//   - id: 字段ID
+//
+// 返回值：
+//   - error: 删除失败时返回错误
+func (a *App) DeleteCustomField(id int64) error {
+	before, _ := a.fieldSvc.Get(id)
+	if err := a.fieldSvc.Delete(id); err != nil {
+		return err
+	}
+	name := ""
+	if before != nil {
+		name = before.Name
+	}
+	a.auditSvc.Record(services.AuditFieldDelete, services.AuditTargetField, id, name, "删除自定义字段", before, nil)
+	return nil
+}
+
+// UpdateAccountFields 更新账号的自定义字段值
+//
+// 参数：
+//   - accountID: 账号ID
+//   - values: 字段名 -> 值，值为空表示清除
+//
+// 返回值：
+//   - error: 字段不存在或值校验失败时返回错误
+func (a *App) UpdateAccountFields(accountID int64, values map[string]string) error {
+	account, err := a.accountSvc.GetByID(accountID)
+	if err != nil {
+		return err
+	}
+	before := a.fieldSvc.AccountFields(accountID)
+	if err := a.fieldSvc.SetAccountFields(accountID, values); err != nil {
+		return err
+	}
+	a.auditSvc.Record(services.AuditAccountFields, services.AuditTargetAccount, accountID, account.Email,
+		"修改自定义字段", before, a.fieldSvc.AccountFields(accountID))
+	return nil
+}
+
+// UpdateAccountNotes 更新账号备注
+//
+// 参数：
+//   - accountID: 账号ID
+//   - notes: 备注内容
+//
+// 返回值：
+//   - error: 更新失败时返回错误
+func (a *App) UpdateAccountNotes(accountID int64, notes string) error {
+	account, err := a.accountSvc.GetByID(accountID)
+	if err != nil {
+		return err
+	}
+	if err := a.accountSvc.UpdateNotes(accountID, notes); err != nil {
+		return err
+	}
+	a.auditSvc.Record(services.AuditAccountNotes, services.AuditTargetAccount, accountID, account.Email,
+		"修改备注", account.Notes, notes)
+	return nil
+}
+
+// ============================================================================
+// 导入模板API - 提供导入行模板的维护和导入预览
+// ============================================================================
+
+// PreviewImport 预览导入结果
+//
+// 按所选模板解析文本，返回每一行解析出的字段和预计的处理结果，不写入数据库
+//
+// 参数：
+//   - content: 包含账号信息的多行文本
+//   - opts: 导入选项（与ImportAccounts相同）
+//
+// 返回值：
+//   - *models.ImportPreview: 导入预览
+//   - error: 模板不存在或策略无效时返回错误
+func (a *App) PreviewImport(content string, opts models.ImportOptions) (*models.ImportPreview, error) {
+	return a.accountSvc.PreviewImport(content, opts)
+}
+
+// GetImportTemplates 获取所有导入模板（内置模板在前）
+//
+// 返回值：
+//   - []models.ImportTemplate: 模板列表
+//   - error: 查询错误
+func (a *App) GetImportTemplates() ([]models.ImportTemplate, error) {
+	return a.tplSvc.List()
+}
+
+// CreateImportTemplate 创建导入模板
+//
+// 参数：
+//   - tpl: 模板定义（名称、分隔符、字段顺序，忽略的列用"-"表示）
+//
+// 返回值：
+//   - *models.ImportTemplate: 创建成功的模板
+//   - error: 校验失败或重名时返回错误
+func (a *App) CreateImportTemplate(tpl models.ImportTemplate) (*models.ImportTemplate, error) {
+	created, err := a.tplSvc.Create(tpl)
+	if err == nil {
+		a.auditSvc.Record(services.AuditTemplateCreate, services.AuditTargetTemplate, created.ID, created.Name, "创建导入模板", nil, created)
+	}
+	return created, err
+}
+
+// UpdateImportTemplate 修改导入模板
+//
+// 参数：
+//   - tpl: 模板定义，按ID更新
+//
+// 返回值：
+//   - error: 校验失败或更新失败时返回错误
+func (a *App) UpdateImportTemplate(tpl models.ImportTemplate) error {
+	before, _ := a.tplSvc.GetByID(tpl.ID)
+	if err := a.tplSvc.Update(tpl); err != nil {
+		return err
+	}
+	a.auditSvc.Record(services.AuditTemplateUpdate, services.AuditTargetTemplate, tpl.ID, tpl.Name, "修改导入模板", before, tpl)
+	return nil
+}
+
+// DeleteImportTemplate 删除导入模板
+//
+// 参数：
+//   - id: 模板ID
+//
+// 返回值：
+//   - error: 删除失败时返回错误
+func (a *App) DeleteImportTemplate(id int64) error {
+	before, _ := a.tplSvc.GetByID(id)
+	if err := a.tplSvc.Delete(id); err != nil {
+		return err
+	}
+	name := ""
+	if before != nil {
+		name = before.Name
+	}
+	a.auditSvc.Record(services.AuditTemplateDelete, services.AuditTargetTemplate, id, name, "删除导入模板", before, nil)
+	return nil
+}
+
+// ============================================================================
+// 审计日志API - 提供操作审计记录的查询和导出
+// ============================================================================
+
+// GetAuditLogs 查询操作审计日志
+//
+// 参数：
+//   - filter: 筛选条件（操作类型、对象、时间范围、关键字、分页）
+//
+// 返回值：
+//   - []models.AuditLog: 审计日志列表，最新的在前
+//   - error: 查询错误
+func (a *App) GetAuditLogs(filter models.AuditFilter) ([]models.AuditLog, error) {
+	return a.auditSvc.List(filter)
+}
+
+// ExportAuditLogs 导出操作审计日志
+//
+// 弹出系统文件保存对话框，将满足条件的审计日志导出为CSV或JSON
+//
+// 参数：
+//   - filter: 筛选条件（忽略分页参数，导出全部匹配记录）
+//   - format: 导出格式，"csv"或"json"
+//
+// 返回值：
+//   - bool: 是否导出成功（用户取消返回false）
+//   - error: 查询或文件写入错误
+func (a *App) ExportAuditLogs(filter models.AuditFilter, format string) (bool, error) {
+	if format != "json" {
+		format = "csv"
+	}
+	filter.Limit, filter.Offset = 0, 0
+	logs, err := a.auditSvc.List(filter)
+	if err != nil {
+		return false, err
+	}
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: "audit-log-" + time.Now().Format("20060102") + "." + format,
+		Filters: []runtime.FileFilter{
+			{DisplayName: strings.ToUpper(format) + " Files", Pattern: "*." + format},
+		},
+	})
+	if err != nil || path == "" {
+		return false, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if err := a.auditSvc.Export(f, logs, format); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// singleID 批量操作只涉及一个对象时返回其ID，否则返回0（审计记录的target_id）
+func singleID(ids []int64) int64 {
+	if len(ids) == 1 {
+		return ids[0]
+	}
+	return 0
+}
+
+// ============================================================================
+// 邮件操作API - 提供邮件的查看等操作
+// 所有邮件操作都需要有效的OAuth2 Token，支持Token过期自动重试
+// 每个操作带有超时时间，前端传入的请求ID可用于CancelRequest取消（如切换账号时）
+// ============================================================================
+
+// beginOperation 开始一次邮件操作
+//
+// 参数：
+//   - requestID: 前端生成的请求ID，为空时操作不能被CancelRequest取消
+//   - timeout: 操作的超时时间
 //
 // 返回值：
-//   - error: 删除失败时返回错误
-func (a *App) DeleteGroup(id int64) error {
-	return a.groupSvc.Delete(id)
+//   - context.Context: 操作的上下文，超时或被取消时中止Token刷新、HTTP请求和IMAP命令
+//   - func(): 结束操作的函数，操作完成后必须调用
+func (a *App) beginOperation(requestID string, timeout time.Duration) (context.Context, func()) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	if requestID == "" {
+		return ctx, cancel
+	}
+	op := &mailOperation{cancel: cancel}
+	a.opsMu.Lock()
+	a.operations[requestID] = op
+	a.opsMu.Unlock()
+	return ctx, func() {
+		a.opsMu.Lock()
+		if a.operations[requestID] == op {
+			delete(a.operations, requestID)
+		}
+		a.opsMu.Unlock()
+		cancel()
+	}
 }
 
-// ClearGroup 清空分组内所有账号
+// CancelRequest 取消进行中的邮件操作
 //
-// 删除指定分组下的所有账号记录
+// 被取消的操作立即中止网络请求并返回"context canceled"错误；请求已完成或不存在时不做任何操作
 //
 // 参数：
-//   - groupID: 要清空的分组ID
-//
-// 返回值：
-//   - error: 删除失败时返回错误
-func (a *App) ClearGroup(groupID int64) error {
-	return a.accountSvc.DeleteByGroup(groupID)
+//   - requestID: 调用邮件操作API时传入的请求ID
+func (a *App) CancelRequest(requestID string) {
+	a.opsMu.Lock()
+	op, ok := a.operations[requestID]
+	delete(a.operations, requestID)
+	a.opsMu.Unlock()
+	if ok {
+		log.Printf("[App] 取消邮件操作 - requestID: %s", requestID)
+		op.cancel()
+	}
 }
 
-// ============================================================================
-// 邮件操作API - 提供邮件的查看等操作
-// 所有邮件操作都需要有效的OAuth2 Token，支持Token过期自动重试
-// ============================================================================
+// operationError 操作被取消或超时时返回上下文的错误（而不是由此导致的网络错误），否则原样返回err
+func operationError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
 
 // GetMailFolders 获取邮箱文件夹列表
 //
 // 策略：已标记imap的直接用IMAP，否则先尝试REST API，失败后回退到IMAP并标记
-func (a *App) GetMailFolders(accountID int64) ([]models.MailFolder, error) {
+//
+// 参数：
+//   - accountID: 账号ID
+//   - requestID: 请求ID（用于CancelRequest），可为空
+func (a *App) GetMailFolders(accountID int64, requestID string) ([]models.MailFolder, error) {
 	log.Printf("[App] GetMailFolders 开始 - accountID: %d", accountID)
+	ctx, done := a.beginOperation(requestID, mailListTimeout)
+	defer done()
 
 	account, err := a.accountSvc.GetByID(accountID)
 	if err != nil {
@@ -269,22 +1191,23 @@ func (a *App) GetMailFolders(accountID int64) ([]models.MailFolder, error) {
 	log.Printf("[App] 账号信息: email=%s, protocol=%s, status=%s", account.Email, account.Protocol, account.Status)
 
 	// 已标记为 IMAP 的账号直接使用 IMAP
-	if account.Protocol == "imap" {
+	if isIMAPAccount(account) {
 		log.Printf("[App] 账号已标记为 IMAP，直接使用 IMAP 协议")
-		imapToken, err := a.getIMAPToken(accountID, false)
+		auth, err := a.imapAuth(ctx, account)
 		if err != nil {
-			log.Printf("[App] getIMAPToken 失败: %v", err)
+			log.Printf("[App] imapAuth 失败: %v", err)
 			return nil, err
 		}
-		log.Printf("[App] IMAP Token 获取成功，调用 imapSvc.GetMailFolders")
-		return a.imapSvc.GetMailFolders(account.Email, imapToken)
+		log.Printf("[App] IMAP 凭据获取成功，调用 imapSvc.GetMailFolders")
+		result, err := a.imapSvc.GetMailFolders(ctx, auth)
+		return result, operationError(ctx, err)
 	}
 
 	// 先尝试 REST API
 	log.Printf("[App] 尝试 REST API (O2)...")
-	if token, err := a.ensureValidToken(accountID); err == nil {
+	if token, err := a.ensureValidToken(ctx, accountID); err == nil {
 		log.Printf("[App] O2 Token 获取成功，调用 graphSvc.GetMailFolders")
-		if result, err := a.graphSvc.GetMailFolders(token); err == nil {
+		if result, err := a.graphSvc.GetMailFolders(ctx, token); err == nil {
 			log.Printf("[App] O2 成功，返回 %d 个文件夹", len(result))
 			return result, nil
 		} else {
@@ -292,9 +1215,9 @@ func (a *App) GetMailFolders(accountID int64) ([]models.MailFolder, error) {
 			if strings.Contains(err.Error(), "unauthorized") {
 				log.Printf("[App] Token 过期，清除缓存并重试...")
 				a.clearTokenCache(accountID)
-				if token, err = a.getToken(accountID, true); err == nil {
+				if token, err = a.getToken(ctx, accountID, true); err == nil {
 					log.Printf("[App] 重新获取 Token 成功，再次调用 graphSvc.GetMailFolders")
-					if result, err := a.graphSvc.GetMailFolders(token); err == nil {
+					if result, err := a.graphSvc.GetMailFolders(ctx, token); err == nil {
 						log.Printf("[App] O2 重试成功，返回 %d 个文件夹", len(result))
 						return result, nil
 					} else {
@@ -308,31 +1231,46 @@ func (a *App) GetMailFolders(accountID int64) ([]models.MailFolder, error) {
 	} else {
 		log.Printf("[App] ensureValidToken 失败: %v", err)
 	}
+	// 操作被取消或超时导致的失败不回退到 IMAP
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// REST API 失败，回退到 IMAP 并标记
 	log.Printf("[App] O2 失败，回退到 IMAP...")
-	imapToken, err := a.getIMAPToken(accountID, false)
+	auth, err := a.imapAuth(ctx, account)
 	if err != nil {
-		log.Printf("[App] getIMAPToken 失败: %v", err)
+		log.Printf("[App] imapAuth 失败: %v", err)
 		return nil, err
 	}
 	log.Printf("[App] IMAP Token 获取成功，调用 imapSvc.GetMailFolders")
-	result, err := a.imapSvc.GetMailFolders(account.Email, imapToken)
+	result, err := a.imapSvc.GetMailFolders(ctx, auth)
 	if err == nil {
 		log.Printf("[App] IMAP 成功，返回 %d 个文件夹，标记账号为 IMAP", len(result))
-		a.accountSvc.UpdateProtocol(accountID, "imap")
-		runtime.EventsEmit(a.ctx, "protocol-updated", accountID, "imap")
+		a.markIMAP(account)
 	} else {
 		log.Printf("[App] IMAP 也失败: %v", err)
 	}
-	return result, err
+	return result, operationError(ctx, err)
 }
 
-// GetMessages 获取指定文件夹的邮件列表
+// GetMessages 获取指定文件夹的一页邮件列表
 //
 // 策略：已标记imap的直接用IMAP，否则先尝试REST API，失败后回退到IMAP并标记
-func (a *App) GetMessages(accountID int64, folderID string, page int) ([]models.Message, error) {
-	log.Printf("[App] GetMessages 开始 - accountID: %d, folderID: %s, page: %d", accountID, folderID, page)
+//
+// 参数：
+//   - accountID: 账号ID
+//   - folderID: 文件夹ID
+//   - cursor: 上一页返回的NextCursor，空字符串表示第一页
+//   - requestID: 请求ID（用于CancelRequest），可为空
+//
+// 返回值：
+//   - *models.MessagePage: 邮件列表和下一页游标
+//   - error: 获取失败时返回错误；游标失效（错误信息包含"cursor expired"）时需要从第一页重新加载
+func (a *App) GetMessages(accountID int64, folderID string, cursor string, requestID string) (*models.MessagePage, error) {
+	log.Printf("[App] GetMessages 开始 - accountID: %d, folderID: %s, cursor: %s", accountID, folderID, cursor)
+	ctx, done := a.beginOperation(requestID, mailListTimeout)
+	defer done()
 
 	account, err := a.accountSvc.GetByID(accountID)
 	if err != nil {
@@ -342,31 +1280,32 @@ func (a *App) GetMessages(accountID int64, folderID string, page int) ([]models.
 	log.Printf("[App] 账号: email=%s, protocol=%s", account.Email, account.Protocol)
 
 	// 已标记为 IMAP 的账号直接使用 IMAP
-	if account.Protocol == "imap" {
+	if isIMAPAccount(account) {
 		log.Printf("[App] 账号已标记为 IMAP，直接使用 IMAP")
-		imapToken, err := a.getIMAPToken(accountID, false)
+		auth, err := a.imapAuth(ctx, account)
 		if err != nil {
-			log.Printf("[App] getIMAPToken 失败: %v", err)
+			log.Printf("[App] imapAuth 失败: %v", err)
 			return nil, err
 		}
 		log.Printf("[App] 调用 imapSvc.GetMessages")
-		return a.imapSvc.GetMessages(account.Email, imapToken, folderID, page*20, 20)
+		result, err := a.imapSvc.GetMessages(ctx, auth, folderID, cursor, messagePageSize)
+		return result, operationError(ctx, err)
 	}
 
 	// 先尝试 REST API
 	log.Printf("[App] 尝试 REST API (O2)...")
-	if token, err := a.ensureValidToken(accountID); err == nil {
+	if token, err := a.ensureValidToken(ctx, accountID); err == nil {
 		log.Printf("[App] O2 Token 获取成功")
-		if result, err := a.graphSvc.GetMessages(token, folderID, page*20, 20); err == nil {
-			log.Printf("[App] O2 成功，返回 %d 封邮件", len(result))
+		if result, err := a.graphSvc.GetMessages(ctx, token, folderID, cursor, messagePageSize); err == nil {
+			log.Printf("[App] O2 成功，返回 %d 封邮件", len(result.Messages))
 			return result, nil
 		} else {
 			log.Printf("[App] O2 GetMessages 失败: %v", err)
 			if strings.Contains(err.Error(), "unauthorized") {
 				log.Printf("[App] Token 过期，重试...")
 				a.clearTokenCache(accountID)
-				if token, err = a.getToken(accountID, true); err == nil {
-					if result, err := a.graphSvc.GetMessages(token, folderID, page*20, 20); err == nil {
+				if token, err = a.getToken(ctx, accountID, true); err == nil {
+					if result, err := a.graphSvc.GetMessages(ctx, token, folderID, cursor, messagePageSize); err == nil {
 						log.Printf("[App] O2 重试成功")
 						return result, nil
 					}
@@ -376,29 +1315,40 @@ func (a *App) GetMessages(accountID int64, folderID string, page int) ([]models.
 	} else {
 		log.Printf("[App] ensureValidToken 失败: %v", err)
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// REST API 失败，回退到 IMAP 并标记
 	log.Printf("[App] O2 失败，回退到 IMAP...")
-	imapToken, err := a.getIMAPToken(accountID, false)
+	auth, err := a.imapAuth(ctx, account)
 	if err != nil {
-		log.Printf("[App] getIMAPToken 失败: %v", err)
+		log.Printf("[App] imapAuth 失败: %v", err)
 		return nil, err
 	}
-	result, err := a.imapSvc.GetMessages(account.Email, imapToken, folderID, page*20, 20)
+	result, err := a.imapSvc.GetMessages(ctx, auth, folderID, cursor, messagePageSize)
 	if err == nil {
-		log.Printf("[App] IMAP 成功，返回 %d 封邮件，标记账号为 IMAP", len(result))
-		a.accountSvc.UpdateProtocol(accountID, "imap")
-		runtime.EventsEmit(a.ctx, "protocol-updated", accountID, "imap")
+		log.Printf("[App] IMAP 成功，返回 %d 封邮件，标记账号为 IMAP", len(result.Messages))
+		a.markIMAP(account)
 	} else {
 		log.Printf("[App] IMAP 也失败: %v", err)
 	}
-	return result, err
+	return result, operationError(ctx, err)
 }
 
 // GetMessageDetail 获取邮件详情
 //
 // 策略：已标记imap的直接用IMAP，否则先尝试REST API，失败后回退到IMAP并标记
-func (a *App) GetMessageDetail(accountID int64, messageID string, folderID string) (*models.Message, error) {
+//
+// 参数：
+//   - accountID: 账号ID
+//   - messageID: 邮件ID（IMAP为UID）
+//   - folderID: 邮件所在文件夹（IMAP需要，为空时为收件箱）
+//   - requestID: 请求ID（用于CancelRequest），可为空
+func (a *App) GetMessageDetail(accountID int64, messageID string, folderID string, requestID string) (*models.Message, error) {
+	ctx, done := a.beginOperation(requestID, mailDetailTimeout)
+	defer done()
+
 	account, err := a.accountSvc.GetByID(accountID)
 	if err != nil {
 		return nil, err
@@ -407,50 +1357,52 @@ func (a *App) GetMessageDetail(accountID int64, messageID string, folderID strin
 	var msg *models.Message
 
 	// 已标记为 IMAP 的账号直接使用 IMAP
-	if account.Protocol == "imap" {
-		imapToken, err := a.getIMAPToken(accountID, false)
+	if isIMAPAccount(account) {
+		auth, err := a.imapAuth(ctx, account)
 		if err != nil {
 			return nil, err
 		}
 		if folderID == "" {
 			folderID = "inbox"
 		}
-		msg, err = a.imapSvc.GetMessage(account.Email, imapToken, folderID, messageID)
+		msg, err = a.imapSvc.GetMessage(ctx, auth, folderID, messageID)
 		if err != nil {
-			return nil, err
+			return nil, operationError(ctx, err)
 		}
 		goto sanitize
 	}
 
 	// 先尝试 REST API
-	if token, err := a.ensureValidToken(accountID); err == nil {
-		if msg, err = a.graphSvc.GetMessage(token, messageID); err == nil {
+	if token, err := a.ensureValidToken(ctx, accountID); err == nil {
+		if msg, err = a.graphSvc.GetMessage(ctx, token, messageID); err == nil {
 			goto sanitize
 		} else if strings.Contains(err.Error(), "unauthorized") {
 			a.clearTokenCache(accountID)
-			if token, err = a.getToken(accountID, true); err == nil {
-				if msg, err = a.graphSvc.GetMessage(token, messageID); err == nil {
+			if token, err = a.getToken(ctx, accountID, true); err == nil {
+				if msg, err = a.graphSvc.GetMessage(ctx, token, messageID); err == nil {
 					goto sanitize
 				}
 			}
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// REST API 失败，回退到 IMAP 并标记
 	{
-		imapToken, err := a.getIMAPToken(accountID, false)
+		auth, err := a.imapAuth(ctx, account)
 		if err != nil {
 			return nil, err
 		}
 		if folderID == "" {
 			folderID = "inbox"
 		}
-		msg, err = a.imapSvc.GetMessage(account.Email, imapToken, folderID, messageID)
+		msg, err = a.imapSvc.GetMessage(ctx, auth, folderID, messageID)
 		if err != nil {
-			return nil, err
+			return nil, operationError(ctx, err)
 		}
-		a.accountSvc.UpdateProtocol(accountID, "imap")
-		runtime.EventsEmit(a.ctx, "protocol-updated", accountID, "imap")
+		a.markIMAP(account)
 	}
 
 sanitize:
@@ -458,6 +1410,14 @@ sanitize:
 	if msg != nil && msg.Body != nil && strings.ToLower(msg.Body.ContentType) == "html" {
 		msg.Body.Content = sanitizeHTML(msg.Body.Content)
 	}
+	// 获取邮件不会改变已读状态，开启自动标记已读时单独标记（失败不影响查看）
+	if msg != nil && !msg.IsRead && a.GetAutoMarkRead() {
+		if err := a.setReadState(ctx, account, []string{messageID}, folderID, true); err != nil {
+			log.Printf("[App] 自动标记已读失败 - messageID: %s, error: %v", messageID, err)
+		} else {
+			msg.IsRead = true
+		}
+	}
 	return msg, nil
 }
 
@@ -486,34 +1446,433 @@ func sanitizeHTML(s string) string {
 
 // GetAttachments 获取邮件附件列表
 //
-// 策略：已标记imap的直接返回空，否则尝试REST API
-func (a *App) GetAttachments(accountID int64, messageID string) ([]models.Attachment, error) {
+// 策略：已标记imap的直接用IMAP，否则先尝试REST API，失败后回退到IMAP（不标记账号）
+//
+// 参数：
+//   - accountID: 账号ID
+//   - messageID: 邮件ID（IMAP为UID）
+//   - folderID: 邮件所在文件夹（IMAP需要，为空时为收件箱）
+//   - requestID: 请求ID（用于CancelRequest），可为空
+//
+// 返回值：
+//   - []models.Attachment: 附件列表（含Base64编码的内容）
+//   - error: 账号不存在、获取凭据或附件失败、操作被取消或超时时返回错误
+func (a *App) GetAttachments(accountID int64, messageID string, folderID string, requestID string) ([]models.Attachment, error) {
+	ctx, done := a.beginOperation(requestID, mailDetailTimeout)
+	defer done()
+
 	account, err := a.accountSvc.GetByID(accountID)
 	if err != nil {
 		return nil, err
 	}
-
-	// 已标记为 IMAP 的账号，附件已在GetMessage中解析
-	if account.Protocol == "imap" {
-		return []models.Attachment{}, nil
+	if folderID == "" {
+		folderID = "inbox"
 	}
 
-	// 尝试 REST API
-	if token, err := a.ensureValidToken(accountID); err == nil {
-		if result, err := a.graphSvc.GetAttachments(token, messageID); err == nil {
-			return result, nil
-		} else if strings.Contains(err.Error(), "unauthorized") {
-			a.clearTokenCache(accountID)
-			if token, err = a.getToken(accountID, true); err == nil {
-				if result, err := a.graphSvc.GetAttachments(token, messageID); err == nil {
-					return result, nil
+	if !isIMAPAccount(account) {
+		// 尝试 REST API
+		if token, err := a.ensureValidToken(ctx, accountID); err == nil {
+			if result, err := a.graphSvc.GetAttachments(ctx, token, messageID); err == nil {
+				return result, nil
+			} else if strings.Contains(err.Error(), "unauthorized") {
+				a.clearTokenCache(accountID)
+				if token, err = a.getToken(ctx, accountID, true); err == nil {
+					if result, err := a.graphSvc.GetAttachments(ctx, token, messageID); err == nil {
+						return result, nil
+					}
 				}
 			}
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// IMAP账号或REST API失败
+	auth, err := a.imapAuth(ctx, account)
+	if err != nil {
+		log.Printf("[App] GetAttachments imapAuth 失败: %v", err)
+		return nil, operationError(ctx, err)
+	}
+	result, err := a.imapSvc.GetAttachments(ctx, auth, folderID, messageID)
+	if err != nil {
+		log.Printf("[App] GetAttachments IMAP 失败: %v", err)
+		return nil, operationError(ctx, err)
+	}
+	return result, nil
+}
+
+// MarkRead 将邮件标记为已读
+//
+// 参数：
+//   - accountID: 账号ID
+//   - messageID: 邮件ID（IMAP为UID）
+//   - folderID: 邮件所在文件夹（IMAP需要，为空时为收件箱）
+//   - requestID: 请求ID（用于CancelRequest），可为空
+//
+// 返回值：
+//   - error: 账号不存在或标记失败时返回错误
+func (a *App) MarkRead(accountID int64, messageID string, folderID string, requestID string) error {
+	return a.MarkMessages(accountID, []string{messageID}, folderID, true, requestID)
+}
+
+// MarkUnread 将邮件标记为未读
+//
+// 参数：
+//   - accountID: 账号ID
+//   - messageID: 邮件ID（IMAP为UID）
+//   - folderID: 邮件所在文件夹（IMAP需要，为空时为收件箱）
+//   - requestID: 请求ID（用于CancelRequest），可为空
+//
+// 返回值：
+//   - error: 账号不存在或标记失败时返回错误
+func (a *App) MarkUnread(accountID int64, messageID string, folderID string, requestID string) error {
+	return a.MarkMessages(accountID, []string{messageID}, folderID, false, requestID)
+}
+
+// MarkMessages 批量设置邮件的已读状态
+//
+// 参数：
+//   - accountID: 账号ID
+//   - messageIDs: 邮件ID列表（IMAP为UID）
+//   - folderID: 邮件所在文件夹（IMAP需要，为空时为收件箱）
+//   - isRead: true标记为已读，false标记为未读
+//   - requestID: 请求ID（用于CancelRequest），可为空
+//
+// 返回值：
+//   - error: 账号不存在或标记失败时返回错误（REST API部分邮件失败时返回失败数量和第一个错误）
+func (a *App) MarkMessages(accountID int64, messageIDs []string, folderID string, isRead bool, requestID string) error {
+	ctx, done := a.beginOperation(requestID, mailActionTimeout)
+	defer done()
+
+	account, err := a.accountSvc.GetByID(accountID)
+	if err != nil {
+		return err
+	}
+	return a.setReadState(ctx, account, messageIDs, folderID, isRead)
+}
+
+// FlagMessages 批量为邮件加旗标或清除旗标
+//
+// 参数：
+//   - accountID: 账号ID
+//   - messageIDs: 邮件ID列表（IMAP为UID）
+//   - folderID: 邮件所在文件夹（IMAP需要，为空时为收件箱）
+//   - flagged: true加旗标，false清除旗标
+//   - requestID: 请求ID（用于CancelRequest），可为空
+//
+// 返回值：
+//   - error: 账号不存在或操作失败时返回错误
+func (a *App) FlagMessages(accountID int64, messageIDs []string, folderID string, flagged bool, requestID string) error {
+	ctx, done := a.beginOperation(requestID, mailActionTimeout)
+	defer done()
+
+	account, err := a.accountSvc.GetByID(accountID)
+	if err != nil {
+		return err
+	}
+	log.Printf("[App] 设置旗标 - accountID: %d, messages: %d, flagged: %v", accountID, len(messageIDs), flagged)
+	return a.mailAction(ctx, account, messageIDs,
+		func(token, id string) error {
+			return a.graphSvc.SetFlag(ctx, token, id, flagged)
+		},
+		func(auth services.IMAPAuth) error {
+			return a.imapSvc.SetFlagged(ctx, auth, defaultFolder(folderID), messageIDs, flagged)
+		})
+}
+
+// MoveMessages 批量移动邮件到其他文件夹
+//
+// 从垃圾邮件文件夹移到收件箱即"不是垃圾邮件"，移到垃圾邮件文件夹即"标记为垃圾邮件"；
+// REST API移动后邮件ID会改变，前端需要重新加载邮件列表
+//
+// 参数：
+//   - accountID: 账号ID
+//   - messageIDs: 邮件ID列表（IMAP为UID）
+//   - folderID: 邮件所在文件夹（IMAP需要，为空时为收件箱）
+//   - destinationID: 目标文件夹ID（如"inbox"、"junkemail"或GetMailFolders返回的ID）
+//   - requestID: 请求ID（用于CancelRequest），可为空
+//
+// 返回值：
+//   - error: 账号不存在、目标与来源相同或移动失败时返回错误
+func (a *App) MoveMessages(accountID int64, messageIDs []string, folderID string, destinationID string, requestID string) error {
+	if destinationID == "" {
+		return fmt.Errorf("destination folder is required")
+	}
+	folderID = defaultFolder(folderID)
+	if strings.EqualFold(folderID, destinationID) {
+		return fmt.Errorf("messages are already in folder %s", destinationID)
+	}
+	ctx, done := a.beginOperation(requestID, mailActionTimeout)
+	defer done()
+
+	account, err := a.accountSvc.GetByID(accountID)
+	if err != nil {
+		return err
+	}
+	log.Printf("[App] 移动邮件 - accountID: %d, messages: %d, %s -> %s", accountID, len(messageIDs), folderID, destinationID)
+	err = a.mailAction(ctx, account, messageIDs,
+		func(token, id string) error {
+			return a.graphSvc.MoveMessage(ctx, token, id, destinationID)
+		},
+		func(auth services.IMAPAuth) error {
+			return a.imapSvc.MoveMessages(ctx, auth, folderID, messageIDs, destinationID)
+		})
+	if err == nil {
+		a.auditSvc.Record(services.AuditMessageMove, services.AuditTargetAccount, account.ID, account.Email,
+			fmt.Sprintf("移动 %d 封邮件", len(messageIDs)),
+			map[string]interface{}{"folder": folderID, "messages": messageIDs}, map[string]string{"folder": destinationID})
+	}
+	return err
+}
+
+// DeleteMessages 批量删除邮件
+//
+// 邮件被移到"已删除"文件夹，已在该文件夹中的邮件被永久删除
+//
+// 参数：
+//   - accountID: 账号ID
+//   - messageIDs: 邮件ID列表（IMAP为UID）
+//   - folderID: 邮件所在文件夹（IMAP需要，为空时为收件箱）
+//   - requestID: 请求ID（用于CancelRequest），可为空
+//
+// 返回值：
+//   - error: 账号不存在或删除失败时返回错误
+func (a *App) DeleteMessages(accountID int64, messageIDs []string, folderID string, requestID string) error {
+	ctx, done := a.beginOperation(requestID, mailActionTimeout)
+	defer done()
+
+	account, err := a.accountSvc.GetByID(accountID)
+	if err != nil {
+		return err
+	}
+	folderID = defaultFolder(folderID)
+	log.Printf("[App] 删除邮件 - accountID: %d, folderID: %s, messages: %d", accountID, folderID, len(messageIDs))
+	err = a.mailAction(ctx, account, messageIDs,
+		func(token, id string) error {
+			return a.graphSvc.DeleteMessage(ctx, token, id)
+		},
+		func(auth services.IMAPAuth) error {
+			return a.imapSvc.DeleteMessages(ctx, auth, folderID, messageIDs)
+		})
+	if err == nil {
+		a.auditSvc.Record(services.AuditMessageDelete, services.AuditTargetAccount, account.ID, account.Email,
+			fmt.Sprintf("删除 %d 封邮件", len(messageIDs)),
+			map[string]interface{}{"folder": folderID, "messages": messageIDs}, nil)
+	}
+	return err
+}
+
+// setReadState 设置邮件的已读状态
+//
+// 参数：
+//   - ctx: 操作的上下文
+//   - account: 账号信息
+//   - messageIDs: 邮件ID列表
+//   - folderID: 邮件所在文件夹（IMAP需要，为空时为收件箱）
+//   - isRead: true标记为已读，false标记为未读
+//
+// 返回值：
+//   - error: 标记失败时返回错误
+func (a *App) setReadState(ctx context.Context, account *models.Account, messageIDs []string, folderID string, isRead bool) error {
+	log.Printf("[App] 设置已读状态 - accountID: %d, messages: %d, isRead: %v", account.ID, len(messageIDs), isRead)
+	return a.mailAction(ctx, account, messageIDs,
+		func(token, id string) error {
+			return a.graphSvc.SetReadState(ctx, token, id, isRead)
+		},
+		func(auth services.IMAPAuth) error {
+			return a.imapSvc.SetSeen(ctx, auth, defaultFolder(folderID), messageIDs, isRead)
+		})
+}
+
+// mailAction 按账号当前的协议执行修改邮件的操作
+//
+// 邮件ID来自该协议的邮件列表（REST API的ID与IMAP的UID不通用），因此失败时不回退到另一种协议。
+// REST API逐封发送请求，某封邮件失败时继续处理其余邮件，返回401时刷新Token后重试该邮件；
+// IMAP一条命令处理所有邮件
+//
+// 参数：
+//   - ctx: 操作的上下文
+//   - account: 账号信息
+//   - messageIDs: 邮件ID列表
+//   - rest: 使用REST API处理一封邮件
+//   - imap: 使用IMAP处理所有邮件
+//
+// 返回值：
+//   - error: 获取凭据或执行操作失败时返回错误，操作被取消或超时时返回上下文的错误
+func (a *App) mailAction(ctx context.Context, account *models.Account, messageIDs []string, rest func(token, messageID string) error, imap func(auth services.IMAPAuth) error) error {
+	if len(messageIDs) == 0 {
+		return fmt.Errorf("no messages specified")
+	}
+	if isIMAPAccount(account) {
+		auth, err := a.imapAuth(ctx, account)
+		if err != nil {
+			return err
+		}
+		return operationError(ctx, imap(auth))
+	}
+
+	token, err := a.ensureValidToken(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	var firstErr error
+	for _, id := range messageIDs {
+		err := rest(token, id)
+		if err != nil && strings.Contains(err.Error(), "unauthorized") {
+			a.clearTokenCache(account.ID)
+			if token, err = a.getToken(ctx, account.ID, true); err != nil {
+				return err
+			}
+			err = rest(token, id)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("[App] 邮件操作失败 - messageID: %s, error: %v", id, err)
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d messages failed: %w", failed, len(messageIDs), firstErr)
+	}
+	return nil
+}
+
+// defaultFolder 未指定文件夹时使用收件箱
+func defaultFolder(folderID string) string {
+	if folderID == "" {
+		return "inbox"
+	}
+	return folderID
+}
+
+// ============================================================================
+// 新邮件提醒API - 通过IMAP IDLE监听收件箱，收到新邮件时通知前端
+// 开启提醒的账号保存在设置中，应用启动时自动恢复监听
+// ============================================================================
+
+// GetWatchedAccounts 获取开启新邮件提醒的账号ID列表
+//
+// 返回值：
+//   - []int64: 账号ID列表
+func (a *App) GetWatchedAccounts() []int64 {
+	ids := []int64{}
+	for _, s := range strings.Split(a.settingSvc.Get(services.SettingWatchedAccounts, ""), ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// SetAccountWatch 开启或关闭账号的新邮件提醒
+//
+// 开启后使用独立的IMAP连接监听收件箱（服务器不支持IDLE时定时轮询），
+// 收到新邮件时发送"new-mail"事件，参数为账号ID和新邮件摘要（models.Message，没有正文）
+//
+// 参数：
+//   - accountID: 账号ID
+//   - watch: true开启，false关闭
+//
+// 返回值：
+//   - error: 账号不存在或保存设置失败时返回错误
+func (a *App) SetAccountWatch(accountID int64, watch bool) error {
+	account, err := a.accountSvc.GetByID(accountID)
+	if err != nil {
+		return err
+	}
+	before := a.GetWatchedAccounts()
+	after := []int64{}
+	for _, id := range before {
+		if id != accountID {
+			after = append(after, id)
+		}
+	}
+	if watch {
+		after = append(after, accountID)
+	}
+	if err := a.saveWatchedAccounts(after); err != nil {
+		return err
+	}
+
+	summary := "关闭新邮件提醒：" + account.Email
+	if watch {
+		a.startWatch(accountID)
+		summary = "开启新邮件提醒：" + account.Email
+	} else {
+		a.watchSvc.Unwatch(accountID)
+	}
+	a.auditSvc.Record(services.AuditSettingUpdate, services.AuditTargetSetting, 0, services.SettingWatchedAccounts,
+		summary, before, after)
+	return nil
+}
+
+// saveWatchedAccounts 保存开启新邮件提醒的账号ID列表
+func (a *App) saveWatchedAccounts(ids []int64) error {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return a.settingSvc.Set(services.SettingWatchedAccounts, strings.Join(parts, ","))
+}
+
+// pruneWatchedAccounts 停止监听已删除（在回收站中或已彻底删除）的账号，并从设置中移除
+//
+// 使保存的设置与实际运行的监听一致
+func (a *App) pruneWatchedAccounts() {
+	before := a.GetWatchedAccounts()
+	after := []int64{}
+	var removed []string
+	for _, id := range before {
+		_, err := a.accountSvc.GetByID(id)
+		if err == nil {
+			after = append(after, id)
+			continue
+		}
+		if err != sql.ErrNoRows {
+			log.Printf("[App] 查询监听账号失败: id=%d, error=%v", id, err)
+			after = append(after, id) // 无法确认时保留
+			continue
+		}
+		a.watchSvc.Unwatch(id)
+		removed = append(removed, strconv.FormatInt(id, 10))
+	}
+	if len(removed) == 0 {
+		return
+	}
+	if err := a.saveWatchedAccounts(after); err != nil {
+		log.Printf("[App] 更新新邮件提醒设置失败: %v", err)
+		return
+	}
+	a.auditSvc.Record(services.AuditSettingUpdate, services.AuditTargetSetting, 0, services.SettingWatchedAccounts,
+		"账号已删除，关闭新邮件提醒：账号ID "+strings.Join(removed, ", "), before, after)
+}
 
-	// REST API 失败，返回空（IMAP附件已在GetMessage中解析）
-	return []models.Attachment{}, nil
+// startWatch 开始监听账号的收件箱
+//
+// 每次（重新）连接时重新读取账号以获取最新凭据；账号已不存在（如被删除）时停止监听
+func (a *App) startWatch(accountID int64) {
+	auth := func(ctx context.Context) (services.IMAPAuth, error) {
+		account, err := a.accountSvc.GetByID(accountID)
+		if err == sql.ErrNoRows {
+			return services.IMAPAuth{}, fmt.Errorf("account %d not found: %w", accountID, services.ErrStopWatch)
+		}
+		if err != nil {
+			return services.IMAPAuth{}, err
+		}
+		return a.imapAuth(ctx, account)
+	}
+	a.watchSvc.Watch(accountID, auth, func(accountID int64, messages []models.Message) {
+		for _, msg := range messages {
+			runtime.EventsEmit(a.ctx, "new-mail", accountID, msg)
+		}
+	})
 }
 
 // ============================================================================
@@ -526,13 +1885,14 @@ func (a *App) GetAttachments(accountID int64, messageID string) ([]models.Attach
 // 内部方法，封装getToken的非强制刷新调用
 //
 // 参数：
+//   - ctx: 所属邮件操作的上下文
 //   - accountID: 账号ID
 //
 // 返回值：
 //   - string: 有效的访问令牌
 //   - error: 获取失败时返回错误
-func (a *App) ensureValidToken(accountID int64) (string, error) {
-	return a.getToken(accountID, false)
+func (a *App) ensureValidToken(ctx context.Context, accountID int64) (string, error) {
+	return a.getToken(ctx, accountID, false)
 }
 
 // getToken 获取访问令牌（核心Token管理方法）
@@ -545,13 +1905,14 @@ func (a *App) ensureValidToken(accountID int64) (string, error) {
 // Token有效性判断：过期时间必须大于当前时间+1分钟（预留缓冲）
 //
 // 参数：
+//   - ctx: 上下文，取消时中止刷新请求（取消导致的失败不改变账号状态）
 //   - accountID: 账号ID
 //   - forceRefresh: 是否强制刷新（跳过缓存直接请求新Token）
 //
 // 返回值：
 //   - string: 访问令牌
 //   - error: 获取失败时返回错误（如RefreshToken失效）
-func (a *App) getToken(accountID int64, forceRefresh bool) (string, error) {
+func (a *App) getToken(ctx context.Context, accountID int64, forceRefresh bool) (string, error) {
 	// 非强制刷新时，先检查内存缓存
 	if !forceRefresh {
 		a.tokenMu.RLock() // 读锁，允许并发读取
@@ -578,10 +1939,13 @@ func (a *App) getToken(accountID int64, forceRefresh bool) (string, error) {
 	}
 
 	// 缓存未命中或强制刷新，调用Microsoft OAuth2接口刷新Token
-	tokenResp, err := services.RefreshAccessToken(account.ClientID, account.RefreshToken)
+	tokenResp, err := services.RefreshAccessToken(ctx, account.ClientID, account.RefreshToken)
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err() // 操作被取消或超时，不代表账号异常
+		}
 		// Token刷新失败，更新账号状态为error
-		a.accountSvc.UpdateStatus(accountID, "error", err.Error())
+		a.updateAccountStatus(account, "error", err.Error())
 		return "", err
 	}
 
@@ -590,7 +1954,7 @@ func (a *App) getToken(accountID int64, forceRefresh bool) (string, error) {
 	// 持久化新Token到数据库
 	a.accountSvc.UpdateToken(accountID, tokenResp.AccessToken, tokenResp.RefreshToken, expiresAt)
 	// 更新账号状态为active
-	a.accountSvc.UpdateStatus(accountID, "active", "")
+	a.updateAccountStatus(account, "active", "")
 
 	// 同步更新内存缓存
 	a.tokenMu.Lock()
@@ -613,6 +1977,49 @@ func (a *App) clearTokenCache(accountID int64) {
 	a.tokenMu.Unlock()
 }
 
+// updateAccountStatus 更新账号状态并记录审计日志
+//
+// Token刷新成功/失败时调用，仅在状态实际发生变化时写入审计记录，
+// 避免每次刷新都产生日志
+//
+// 参数：
+//   - account: 刷新前的账号信息（用于对比状态）
+//   - status: 新状态（"active"或"error"）
+//   - lastError: 错误信息
+func (a *App) updateAccountStatus(account *models.Account, status, lastError string) {
+	a.accountSvc.UpdateStatus(account.ID, status, lastError)
+	if account.Status == status {
+		return
+	}
+	summary := "Token刷新成功，账号恢复正常"
+	if status == "error" {
+		summary = "Token刷新失败，账号标记为异常"
+	}
+	a.auditSvc.Record(services.AuditAccountStatus, services.AuditTargetAccount, account.ID, account.Email, summary,
+		map[string]string{"status": account.Status, "lastError": account.LastError},
+		map[string]string{"status": status, "lastError": lastError})
+	account.Status = status
+}
+
+// markIMAP 将账号标记为IMAP协议
+//
+// 在REST API失败并成功回退到IMAP后调用：更新数据库、通知前端并记录审计日志
+//
+// 参数：
+//   - account: 账号信息
+func (a *App) markIMAP(account *models.Account) {
+	if err := a.accountSvc.UpdateProtocol(account.ID, "imap"); err != nil {
+		log.Printf("[App] 标记 IMAP 协议失败: %v", err)
+		return
+	}
+	runtime.EventsEmit(a.ctx, "protocol-updated", account.ID, "imap")
+	if account.Protocol != "imap" {
+		a.auditSvc.Record(services.AuditAccountProtocol, services.AuditTargetAccount, account.ID, account.Email,
+			"REST API 不可用，切换为 IMAP 协议", map[string]string{"protocol": account.Protocol}, map[string]string{"protocol": "imap"})
+		account.Protocol = "imap"
+	}
+}
+
 // getIMAPToken 获取IMAP协议专用的访问令牌
 //
 // IMAP协议需要特定的scope权限，与REST API使用的Token不同。
@@ -625,6 +2032,7 @@ func (a *App) clearTokenCache(accountID int64) {
 //  4. 刷新成功后更新数据库和内存缓存
 //
 // 参数：
+//   - ctx: 上下文，取消时中止刷新请求
 //   - accountID: 账号ID，用于查找账号信息和缓存Token
 //   - forceRefresh: 是否强制刷新，true时跳过缓存检查
 //
@@ -633,7 +2041,7 @@ func (a *App) clearTokenCache(accountID int64) {
 //   - error: 账号不存在或Token刷新失败时返回错误
 //
 // 注意：刷新失败时会将账号状态标记为"error"并记录错误信息
-func (a *App) getIMAPToken(accountID int64, forceRefresh bool) (string, error) {
+func (a *App) getIMAPToken(ctx context.Context, accountID int64, forceRefresh bool) (string, error) {
 	if !forceRefresh {
 		a.tokenMu.RLock()
 		if cached, ok := a.imapTokens[accountID]; ok && cached.expiresAt.After(time.Now().Add(time.Minute)) {
@@ -649,16 +2057,19 @@ func (a *App) getIMAPToken(accountID int64, forceRefresh bool) (string, error) {
 	}
 
 	// 使用IMAP专用scope刷新Token
-	tokenResp, err := services.RefreshAccessTokenForIMAP(account.ClientID, account.RefreshToken)
+	tokenResp, err := services.RefreshAccessTokenForIMAP(ctx, account.ClientID, account.RefreshToken)
 	if err != nil {
-		a.accountSvc.UpdateStatus(accountID, "error", err.Error())
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		a.updateAccountStatus(account, "error", err.Error())
 		return "", err
 	}
 
 	expiresAt := time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
 	// 保存新的token和refresh token到数据库
 	a.accountSvc.UpdateToken(accountID, tokenResp.AccessToken, tokenResp.RefreshToken, expiresAt)
-	a.accountSvc.UpdateStatus(accountID, "active", "")
+	a.updateAccountStatus(account, "active", "")
 
 	a.tokenMu.Lock()
 	a.imapTokens[accountID] = &tokenCache{token: tokenResp.AccessToken, expiresAt: expiresAt}
@@ -667,6 +2078,62 @@ func (a *App) getIMAPToken(accountID int64, forceRefresh bool) (string, error) {
 	return tokenResp.AccessToken, nil
 }
 
+// isIMAPAccount 判断账号是否直接使用IMAP协议（已切换为IMAP或密码登录）
+func isIMAPAccount(account *models.Account) bool {
+	return account.Protocol == models.ProtocolIMAP || account.Protocol == models.ProtocolIMAPPassword
+}
+
+// imapAuth 获取账号的IMAP登录凭据
+//
+// 密码登录账号（imap-password协议）直接使用密码，其他账号获取IMAP专用的访问令牌
+//
+// 参数：
+//   - ctx: 上下文，取消时中止Token刷新
+//   - account: 账号信息
+//
+// 返回值：
+//   - services.IMAPAuth: 登录凭据
+//   - error: Token刷新失败时返回错误
+func (a *App) imapAuth(ctx context.Context, account *models.Account) (services.IMAPAuth, error) {
+	if account.Protocol == models.ProtocolIMAPPassword {
+		return services.IMAPAuth{Email: account.Email, Password: account.Password}, nil
+	}
+	token, err := a.getIMAPToken(ctx, account.ID, false)
+	if err != nil {
+		return services.IMAPAuth{}, err
+	}
+	return services.IMAPAuth{Email: account.Email, AccessToken: token}, nil
+}
+
+// checkAccount 检测账号是否可用并更新账号状态
+//
+// 密码登录账号尝试登录IMAP服务器，其他账号强制刷新Token
+//
+// 参数：
+//   - accountID: 账号ID
+//
+// 返回值：
+//   - error: 账号不可用的原因
+func (a *App) checkAccount(accountID int64) error {
+	account, err := a.accountSvc.GetByID(accountID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), accountCheckTimeout)
+	defer cancel()
+	if account.Protocol != models.ProtocolIMAPPassword {
+		_, err = a.getToken(ctx, accountID, true)
+		return err
+	}
+	err = a.imapSvc.CheckLogin(ctx, services.IMAPAuth{Email: account.Email, Password: account.Password})
+	if err != nil {
+		a.updateAccountStatus(account, "error", err.Error())
+		return err
+	}
+	a.updateAccountStatus(account, "active", "")
+	return nil
+}
+
 // ============================================================================
 // 系统功能 - 文件操作等系统级功能
 // ============================================================================
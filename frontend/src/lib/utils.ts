@@ -5,6 +5,7 @@
  * 提供项目中常用的工具函数：
  * - CSS类名合并工具（支持Tailwind CSS）
  * - 日期格式化工具（中文友好显示）
+ * - 邮件地址列表格式化工具
  */
 
 import { clsx, type ClassValue } from 'clsx'
@@ -78,3 +79,22 @@ export function formatDate(dateStr: string): string {
   // 更早：显示月日格式
   return date.toLocaleDateString('zh-CN', { month: 'short', day: 'numeric' })
 }
+
+/**
+ * 地址列表格式化工具函数
+ *
+ * 有显示名称时显示为 "名称 <地址>"，否则只显示地址，多个地址以逗号分隔
+ *
+ * @param addrs - 后端 models.EmailAddr 列表（可为空）
+ * @returns 格式化后的地址字符串
+ *
+ * @example
+ * formatAddressList([{ emailAddress: { name: '张三', address: 'zs@example.com' } }]) // => '张三 <zs@example.com>'
+ */
+export function formatAddressList(addrs?: { emailAddress: { name: string; address: string } }[]): string {
+  return (addrs || [])
+    .map(({ emailAddress: { name, address } }) =>
+      name && address ? `${name} <${address}>` : name || address)
+    .filter(Boolean)
+    .join(', ')
+}
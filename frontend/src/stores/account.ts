@@ -16,8 +16,77 @@ interface Account {
   groupName?: string
   displayName?: string
   status: string       // 账号状态：active/invalid等
-  protocol?: string    // 协议类型：o2/imap
+  protocol?: string    // 协议类型：o2/imap/imap-password
   lastError?: string   // 最后一次错误信息
+  notes?: string       // 备注
+  customFields?: Record<string, string>  // 自定义字段：字段名 -> 值
+}
+
+/** 单行导入结果 */
+export interface ImportLineResult {
+  line: number
+  email?: string
+  status: 'created' | 'updated' | 'skipped-duplicate' | 'skipped-existing' | 'invalid' | 'failed'
+  reason?: string
+  group?: string
+  accountId?: number
+  live?: boolean       // 导入后检测结果
+  liveError?: string   // 检测失败原因
+}
+
+/** 导入选项 */
+export interface ImportOptions {
+  policy?: 'skip' | 'credentials' | 'credentials-group' | 'replace' | ''
+  format?: 'text' | 'csv' | 'json' | 'bundle' | ''  // 数据格式：按行文本、带表头的CSV、JSON对象数组、加密包
+  template?: string  // 导入模板名称：standard/auto/自定义模板（text格式）
+  mapping?: Record<string, string>  // 表头映射（csv/json格式）：表头名 -> 字段名
+  passphrase?: string  // 加密包口令
+  group?: string       // 目标分组，非空时所有账号导入到该分组
+  verify?: boolean     // 导入后立即检测Token
+}
+
+/** 导入模板 */
+export interface ImportTemplate {
+  id: number
+  name: string
+  separator: string   // 分隔符，空表示自动识别"----"或Tab
+  fields: string[]    // 各列字段名：email/password/client_id/refresh_token/group/notes/-（忽略）/自定义字段名
+  builtin: boolean
+}
+
+/** 导入预览单行 */
+export interface ImportPreviewLine extends ImportLineResult {
+  password?: string
+  clientId?: string
+  refreshToken?: string
+  notes?: string
+  customFields?: Record<string, string>
+}
+
+/** 导入预览 */
+export interface ImportPreview {
+  template: string
+  created: number
+  updated: number
+  skipped: number
+  invalid: number
+  lines: ImportPreviewLine[]
+}
+
+/** 导入报告 */
+export interface ImportReport {
+  total: number
+  created: number
+  updated: number
+  skipped: number
+  invalid: number
+  failed: number
+  verified: number     // 导入后检测的账号数
+  dead: number         // 检测为不可用的账号数
+  groupsCreated: string[]
+  lines: ImportLineResult[]
+  cancelled: boolean   // 文件导入被取消（已写入的批次保留）
+  error?: string       // 文件导入中途无法继续读取的原因
 }
 
 /** 分组接口 */
@@ -37,6 +106,7 @@ export const useAccountStore = defineStore('account', () => {
   const selectedAccountId = ref<number | null>(null)
   const selectedGroupId = ref<number | null>(null)
   const loading = ref(false)
+  const watchedAccountIds = ref<number[]>([])  // 开启新邮件提醒的账号ID
 
   // ============================================================================
   // 计算属性
@@ -76,23 +146,65 @@ export const useAccountStore = defineStore('account', () => {
   /**
    * 导入账号（从文本解析）
    * @param content - 账号文本内容
-   * @returns 导入成功的账号数量
+   * @param options - 导入选项：policy 邮箱已存在时的处理策略（skip/credentials/credentials-group/replace），format 数据格式，template 导入模板名称
+   * @returns 导入报告（逐行结果和汇总计数）
    */
-  async function importAccounts(content: string): Promise<number> {
+  async function importAccounts(content: string, options: ImportOptions = {}): Promise<ImportReport> {
     console.log('[AccountStore] importAccounts 开始 - 内容长度:', content.length)
     try {
       // @ts-ignore
-      const count = await window.go.main.App.ImportAccounts(content)
-      console.log('[AccountStore] importAccounts 成功 - 导入数量:', count)
+      const report: ImportReport = await window.go.main.App.ImportAccounts(content, { policy: '', format: '', template: '', ...options })
+      console.log('[AccountStore] importAccounts 成功 - 新建:', report.created, '更新:', report.updated)
       await loadAccounts()
       await loadGroups()
-      return count
+      return report
     } catch (e) {
       console.error('[AccountStore] importAccounts 失败:', e)
       throw e
     }
   }
 
+  /**
+   * 从文件导入账号（后端弹出文件选择框并流式读取，适合大文件）
+   * @param options - 导入选项（与importAccounts相同，format为空时按扩展名识别）
+   * @returns 导入报告，用户未选择文件时为null
+   */
+  async function importAccountsFromFile(options: ImportOptions = {}): Promise<ImportReport | null> {
+    console.log('[AccountStore] importAccountsFromFile 开始')
+    try {
+      // @ts-ignore
+      const report: ImportReport | null = await window.go.main.App.ImportAccountsFromFile({ policy: '', format: '', template: '', ...options })
+      if (!report) return null
+      console.log('[AccountStore] importAccountsFromFile 成功 - 新建:', report.created, '更新:', report.updated, '取消:', report.cancelled)
+      await loadAccounts()
+      await loadGroups()
+      return report
+    } catch (e) {
+      console.error('[AccountStore] importAccountsFromFile 失败:', e)
+      throw e
+    }
+  }
+
+  /**
+   * 预览导入（只解析，不写入）
+   * @param content - 账号文本内容
+   * @param options - 导入选项（与importAccounts相同）
+   * @returns 逐行预览和预计数量
+   */
+  async function previewImport(content: string, options: ImportOptions = {}): Promise<ImportPreview> {
+    console.log('[AccountStore] previewImport 开始 - 模板:', options.template)
+    // @ts-ignore
+    return await window.go.main.App.PreviewImport(content, { policy: '', format: '', template: '', ...options })
+  }
+
+  /**
+   * 获取导入模板列表（内置模板在前）
+   */
+  async function loadImportTemplates(): Promise<ImportTemplate[]> {
+    // @ts-ignore
+    return (await window.go.main.App.GetImportTemplates()) || []
+  }
+
   /**
    * 删除账号
    * @param id - 账号ID
@@ -105,6 +217,7 @@ export const useAccountStore = defineStore('account', () => {
       console.log('[AccountStore] deleteAccount 成功')
       await loadAccounts()
       await loadGroups() // 更新分组计数
+      await loadWatchedAccounts() // 删除的账号已关闭新邮件提醒
     } catch (e) {
       console.error('[AccountStore] deleteAccount 失败:', e)
       throw e
@@ -196,15 +309,35 @@ export const useAccountStore = defineStore('account', () => {
       console.log('[AccountStore] clearGroup 成功')
       await loadAccounts()
       await loadGroups()
+      await loadWatchedAccounts() // 删除的账号已关闭新邮件提醒
     } catch (e) {
       console.error('[AccountStore] clearGroup 失败:', e)
       throw e
     }
   }
 
+  /** 加载开启新邮件提醒的账号ID列表 */
+  async function loadWatchedAccounts() {
+    // @ts-ignore
+    watchedAccountIds.value = (await window.go.main.App.GetWatchedAccounts()) || []
+  }
+
+  /**
+   * 开启或关闭账号的新邮件提醒
+   * @param accountId - 账号ID
+   * @param watch - true开启，false关闭
+   */
+  async function setAccountWatch(accountId: number, watch: boolean) {
+    console.log('[AccountStore] setAccountWatch - accountId:', accountId, 'watch:', watch)
+    // @ts-ignore
+    await window.go.main.App.SetAccountWatch(accountId, watch)
+    await loadWatchedAccounts()
+  }
+
   return {
-    accounts, groups, selectedAccountId, selectedGroupId, loading,
+    accounts, groups, selectedAccountId, selectedGroupId, loading, watchedAccountIds,
     filteredAccounts,
-    loadAccounts, loadGroups, importAccounts, deleteAccount, createGroup, deleteGroup, moveToGroup, clearGroup
+    loadAccounts, loadGroups, importAccounts, importAccountsFromFile, previewImport, loadImportTemplates, deleteAccount, createGroup, deleteGroup, moveToGroup, clearGroup,
+    loadWatchedAccounts, setAccountWatch
   }
 })
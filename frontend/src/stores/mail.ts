@@ -9,8 +9,22 @@ import { ref } from 'vue'
 interface MailFolder {
   id: string
   displayName: string
+  parentFolderId?: string
   totalItemCount: number
   unreadItemCount: number
+  childFolderCount?: number
+  childFolders?: MailFolder[]  // 子文件夹（后端返回树形结构）
+  noSelect?: boolean           // IMAP中只用于容纳子文件夹的层级节点
+}
+
+/** 将文件夹树展开为列表（父文件夹在前） */
+function flattenFolders(tree: MailFolder[]): MailFolder[] {
+  return tree.flatMap(f => [f, ...flattenFolders(f.childFolders || [])])
+}
+
+/** 邮件地址接口（后端 models.EmailAddr） */
+interface EmailAddr {
+  emailAddress: { name: string; address: string }
 }
 
 /** 邮件消息接口 */
@@ -19,10 +33,25 @@ interface Message {
   subject: string
   bodyPreview: string
   body?: { contentType: string; content: string }
-  from?: { emailAddress: { name: string; address: string } }
+  from?: EmailAddr
+  sender?: EmailAddr           // 实际发送者（代发时与发件人不同）
+  toRecipients?: EmailAddr[]
+  ccRecipients?: EmailAddr[]
+  bccRecipients?: EmailAddr[]
+  replyTo?: EmailAddr[]        // 回复地址（未设置时回复发件人）
   receivedDateTime: string
   hasAttachments: boolean
   isRead: boolean
+  flag?: { flagStatus: string }  // 旗标状态：NotFlagged、Flagged或Complete
+  attachments?: Attachment[]  // IMAP邮件详情包含的附件
+}
+
+/** 一页邮件列表接口（后端 models.MessagePage） */
+interface MessagePage {
+  messages: Message[]
+  nextCursor: string   // 下一页游标，没有更多邮件时为空
+  hasMore: boolean
+  uidValidity?: number
 }
 
 /** 附件接口 */
@@ -61,16 +90,46 @@ export const useMailStore = defineStore('mail', () => {
   const messages = ref<Message[]>([])
   const currentMessage = ref<Message | null>(null)
   const attachments = ref<Attachment[]>([])
+  const attachmentError = ref<string | null>(null)  // 附件获取失败的原因（邮件正文仍然显示）
   const selectedFolderId = ref<string | null>(null)
   const loading = ref(false)
   const detailLoading = ref(false)
-  const currentPage = ref(0)
+  const nextCursor = ref('')    // 下一页游标（由后端生成，原样传回）
+  const hasMore = ref(false)     // 是否还有更早的邮件
   const error = ref<string | null>(null)
 
   // 请求版本号，用于中断旧请求
   let requestId = 0
   // 当前正在加载的账号ID
   let currentAccountId: number | null = null
+  // 进行中的后端请求ID，被新请求取代时通过 CancelRequest 中止（避免切换账号后旧请求继续占用连接）
+  const pendingCalls = new Set<string>()
+  let callSeq = 0
+
+  /**
+   * 取消所有进行中的后端请求
+   */
+  function cancelPending() {
+    for (const id of pendingCalls) {
+      // @ts-ignore
+      window.go.main.App.CancelRequest(id)
+    }
+    pendingCalls.clear()
+  }
+
+  /**
+   * 调用后端邮件API，生成请求ID并在请求结束前记录
+   * @param call - 以请求ID为参数调用后端
+   */
+  async function tracked<T>(call: (callId: string) => Promise<T>): Promise<T> {
+    const callId = `mail-${Date.now()}-${++callSeq}`
+    pendingCalls.add(callId)
+    try {
+      return await call(callId)
+    } finally {
+      pendingCalls.delete(callId)
+    }
+  }
 
   // 邮件详情缓存，避免重复请求
   const messageCache = new Map<string, { message: Message; attachments: Attachment[] }>()
@@ -78,7 +137,7 @@ export const useMailStore = defineStore('mail', () => {
   // 账号级别缓存（一次会话内有效）
   const accountCache = new Map<number, {
     folders: MailFolder[]
-    messages: Map<string, Message[]>  // folderId -> messages
+    messages: Map<string, MessagePage>  // folderId -> 首页邮件
   }>()
 
   // ============================================================================
@@ -94,6 +153,7 @@ export const useMailStore = defineStore('mail', () => {
     console.log('[MailStore] loadFolders 开始 - accountId:', accountId, 'forceRefresh:', forceRefresh)
 
     const myRequestId = ++requestId  // 总是递增，中断之前的请求
+    cancelPending()
     currentAccountId = accountId     // 记录当前账号
 
     // 检查缓存
@@ -116,8 +176,9 @@ export const useMailStore = defineStore('mail', () => {
     error.value = null
     try {
       console.log('[MailStore] loadFolders 调用后端 GetMailFolders...')
+      // 后端返回文件夹树，展开后匹配（部分IMAP服务器的垃圾邮件等文件夹位于收件箱之下）
       // @ts-ignore
-      const apiFolders = await window.go.main.App.GetMailFolders(accountId) || []
+      const apiFolders = flattenFolders(await tracked(id => window.go.main.App.GetMailFolders(accountId, id)) || [])
 
       // 检查是否已被新请求取代或账号已切换
       if (myRequestId !== requestId || currentAccountId !== accountId) {
@@ -153,6 +214,8 @@ export const useMailStore = defineStore('mail', () => {
       }
       return true
     } catch (e: any) {
+      // 被新请求取代的请求已取消，不显示错误
+      if (myRequestId !== requestId) return false
       console.error('[MailStore] loadFolders 失败:', e)
       error.value = String(e)
       return false
@@ -167,36 +230,40 @@ export const useMailStore = defineStore('mail', () => {
 
   /**
    * 加载指定文件夹的邮件列表（支持分页）
+   * 分页使用后端返回的游标，新邮件到达时不会导致下一页重复或遗漏
    * @param accountId - 账号ID
    * @param folderId - 文件夹ID
-   * @param page - 页码，0为首页
+   * @param more - true加载下一页并追加，false加载首页
    * @param forceRefresh - 是否强制刷新
    */
-  async function loadMessages(accountId: number, folderId: string, page = 0, forceRefresh = false) {
-    console.log('[MailStore] loadMessages 开始 - accountId:', accountId, 'folderId:', folderId, 'page:', page, 'forceRefresh:', forceRefresh)
+  async function loadMessages(accountId: number, folderId: string, more = false, forceRefresh = false) {
+    console.log('[MailStore] loadMessages 开始 - accountId:', accountId, 'folderId:', folderId, 'more:', more, 'forceRefresh:', forceRefresh)
 
     const myRequestId = ++requestId  // 总是递增，中断之前的请求
+    cancelPending()
 
     // 检查账号是否已切换
     if (currentAccountId !== accountId) {
       console.log('[MailStore] loadMessages 账号已切换，取消加载')
       return
     }
+    if (more && !nextCursor.value) return
 
     // 检查缓存（仅首页）
-    if (!forceRefresh && page === 0 && accountCache.has(accountId)) {
+    if (!forceRefresh && !more && accountCache.has(accountId)) {
       const cached = accountCache.get(accountId)!
       if (cached.messages.has(folderId)) {
-        const cachedMsgs = cached.messages.get(folderId)!
-        console.log('[MailStore] loadMessages 使用缓存 - 邮件数量:', cachedMsgs.length)
-        messages.value = cachedMsgs
-        currentPage.value = 0
+        const cachedPage = cached.messages.get(folderId)!
+        console.log('[MailStore] loadMessages 使用缓存 - 邮件数量:', cachedPage.messages.length)
+        messages.value = cachedPage.messages
+        nextCursor.value = cachedPage.nextCursor
+        hasMore.value = cachedPage.hasMore
         return
       }
     }
 
     // 首页请求时立即清空旧数据
-    if (page === 0) {
+    if (!more) {
       messages.value = []
       currentMessage.value = null
       attachments.value = []
@@ -205,7 +272,8 @@ export const useMailStore = defineStore('mail', () => {
     try {
       console.log('[MailStore] loadMessages 调用后端 GetMessages...')
       // @ts-ignore
-      const msgs = await window.go.main.App.GetMessages(accountId, folderId, page) || []
+      const page: MessagePage = await tracked(id => window.go.main.App.GetMessages(accountId, folderId, more ? nextCursor.value : '', id))
+      const msgs = page?.messages || []
 
       // 检查是否已被新请求取代或账号已切换
       if (myRequestId !== requestId || currentAccountId !== accountId) {
@@ -213,25 +281,32 @@ export const useMailStore = defineStore('mail', () => {
         return
       }
 
-      console.log('[MailStore] loadMessages 后端返回 - 邮件数量:', msgs.length)
+      console.log('[MailStore] loadMessages 后端返回 - 邮件数量:', msgs.length, 'hasMore:', page?.hasMore)
       msgs.forEach((m: Message, i: number) => {
         console.log(`[MailStore] 邮件[${i}]: id=${m.id}, subject=${m.subject}, isRead=${m.isRead}`)
       })
 
       // 首页替换，后续页追加
-      if (page === 0) {
+      if (!more) {
         messages.value = msgs
         // 缓存首页数据
         if (accountCache.has(accountId)) {
-          accountCache.get(accountId)!.messages.set(folderId, msgs)
+          accountCache.get(accountId)!.messages.set(folderId, { ...page, messages: msgs })
         }
       } else {
         messages.value = [...messages.value, ...msgs]
         console.log('[MailStore] loadMessages 追加后总数:', messages.value.length)
       }
-      currentPage.value = page
+      nextCursor.value = page?.nextCursor || ''
+      hasMore.value = !!page?.hasMore
     } catch (e: any) {
+      if (myRequestId !== requestId) return
       console.error('[MailStore] loadMessages 失败:', e)
+      // 游标失效（文件夹被重建或账号切换了协议），从首页重新加载
+      if (more && String(e).includes('cursor expired')) {
+        loading.value = false
+        await loadMessages(accountId, folderId, false, true)
+      }
     } finally {
       loading.value = false
     }
@@ -251,6 +326,7 @@ export const useMailStore = defineStore('mail', () => {
     console.log('[MailStore] loadMessageDetail 开始 - accountId:', accountId, 'messageId:', messageId, 'folderId:', folderId)
 
     const myRequestId = ++requestId  // 总是递增，中断之前的请求
+    cancelPending()
 
     const cacheKey = `${accountId}-${messageId}`
     const cached = messageCache.get(cacheKey)
@@ -258,18 +334,29 @@ export const useMailStore = defineStore('mail', () => {
       console.log('[MailStore] loadMessageDetail 使用缓存')
       currentMessage.value = cached.message
       attachments.value = cached.attachments
+      attachmentError.value = null
       return
     }
 
     detailLoading.value = true
     try {
-      console.log('[MailStore] loadMessageDetail 调用后端 GetMessageDetail 和 GetAttachments...')
-      // 并行加载邮件详情和附件
+      console.log('[MailStore] loadMessageDetail 调用后端 GetMessageDetail...')
+      const folder = folderId || selectedFolderId.value || 'inbox'
       // @ts-ignore
-      const [msg, atts] = await Promise.all([
-        window.go.main.App.GetMessageDetail(accountId, messageId, folderId || selectedFolderId.value || 'inbox'),
-        window.go.main.App.GetAttachments(accountId, messageId)
-      ])
+      const msg = await tracked(id => window.go.main.App.GetMessageDetail(accountId, messageId, folder, id))
+      // IMAP邮件详情已包含附件；REST API邮件有附件时再单独获取
+      let atts = msg?.attachments
+      let attError: string | null = null
+      if (!atts && msg?.hasAttachments) {
+        try {
+          // @ts-ignore
+          atts = await tracked(id => window.go.main.App.GetAttachments(accountId, messageId, folder, id))
+        } catch (e: any) {
+          if (myRequestId !== requestId) return
+          console.error('[MailStore] GetAttachments 失败:', e)
+          attError = String(e)
+        }
+      }
 
       // 检查是否已被新请求取代或账号已切换
       if (myRequestId !== requestId || currentAccountId !== accountId) {
@@ -282,26 +369,130 @@ export const useMailStore = defineStore('mail', () => {
 
       currentMessage.value = msg
       attachments.value = atts || []
-      messageCache.set(cacheKey, { message: msg, attachments: atts || [] })
+      attachmentError.value = attError
+      // 开启自动标记已读时后端返回已读状态，同步到邮件列表
+      if (msg?.isRead) applyReadState(accountId, [messageId], true)
+      // 附件获取失败时不缓存，下次打开时重试
+      if (!attError) messageCache.set(cacheKey, { message: msg, attachments: atts || [] })
       console.log('[MailStore] loadMessageDetail 完成并缓存')
     } catch (e: any) {
+      if (myRequestId !== requestId) return
       console.error('[MailStore] loadMessageDetail 失败:', e)
     } finally {
       detailLoading.value = false
     }
   }
 
+  // ============================================================================
+  // 邮件状态操作
+  // ============================================================================
+
+  /**
+   * 标记邮件为已读或未读，成功后同步邮件列表、详情和缓存
+   * @param accountId - 账号ID
+   * @param messageIds - 邮件ID列表
+   * @param isRead - true标记为已读，false标记为未读
+   * @param folderId - 文件夹ID（默认为当前文件夹）
+   */
+  async function markMessages(accountId: number, messageIds: string[], isRead: boolean, folderId?: string) {
+    console.log('[MailStore] markMessages - accountId:', accountId, 'count:', messageIds.length, 'isRead:', isRead)
+    // @ts-ignore
+    await tracked(id => window.go.main.App.MarkMessages(accountId, messageIds, folderId || selectedFolderId.value || 'inbox', isRead, id))
+    applyReadState(accountId, messageIds, isRead)
+  }
+
+  /**
+   * 为邮件加旗标或清除旗标，成功后同步邮件列表、详情和缓存
+   * @param accountId - 账号ID
+   * @param messageIds - 邮件ID列表
+   * @param flagged - true加旗标，false清除旗标
+   * @param folderId - 文件夹ID（默认为当前文件夹）
+   */
+  async function flagMessages(accountId: number, messageIds: string[], flagged: boolean, folderId?: string) {
+    console.log('[MailStore] flagMessages - accountId:', accountId, 'count:', messageIds.length, 'flagged:', flagged)
+    // @ts-ignore
+    await tracked(id => window.go.main.App.FlagMessages(accountId, messageIds, folderId || selectedFolderId.value || 'inbox', flagged, id))
+    const ids = new Set(messageIds)
+    const flag = { flagStatus: flagged ? 'Flagged' : 'NotFlagged' }
+    messages.value.forEach(m => { if (ids.has(m.id)) m.flag = { ...flag } })
+    if (currentMessage.value && ids.has(currentMessage.value.id)) currentMessage.value.flag = { ...flag }
+    for (const id of ids) {
+      const cached = messageCache.get(`${accountId}-${id}`)
+      if (cached) cached.message.flag = { ...flag }
+    }
+  }
+
+  /**
+   * 移动邮件到其他文件夹（如"不是垃圾邮件"移到收件箱），成功后从当前列表移除
+   * @param accountId - 账号ID
+   * @param messageIds - 邮件ID列表
+   * @param destinationId - 目标文件夹ID
+   */
+  async function moveMessages(accountId: number, messageIds: string[], destinationId: string) {
+    console.log('[MailStore] moveMessages - accountId:', accountId, 'count:', messageIds.length, 'destination:', destinationId)
+    // @ts-ignore
+    await tracked(id => window.go.main.App.MoveMessages(accountId, messageIds, selectedFolderId.value || 'inbox', destinationId, id))
+    removeMessages(accountId, messageIds)
+  }
+
+  /**
+   * 删除邮件（移到"已删除"文件夹），成功后从当前列表移除
+   * @param accountId - 账号ID
+   * @param messageIds - 邮件ID列表
+   */
+  async function deleteMessages(accountId: number, messageIds: string[]) {
+    console.log('[MailStore] deleteMessages - accountId:', accountId, 'count:', messageIds.length)
+    // @ts-ignore
+    await tracked(id => window.go.main.App.DeleteMessages(accountId, messageIds, selectedFolderId.value || 'inbox', id))
+    removeMessages(accountId, messageIds)
+  }
+
+  /**
+   * 从当前列表移除已移动或删除的邮件，并清除该账号的邮件列表缓存（其他文件夹的内容和数量已变化）
+   * @param accountId - 账号ID
+   * @param messageIds - 邮件ID列表
+   */
+  function removeMessages(accountId: number, messageIds: string[]) {
+    const ids = new Set(messageIds)
+    messages.value = messages.value.filter(m => !ids.has(m.id))
+    if (currentMessage.value && ids.has(currentMessage.value.id)) {
+      currentMessage.value = null
+      attachments.value = []
+    }
+    for (const id of ids) messageCache.delete(`${accountId}-${id}`)
+    accountCache.get(accountId)?.messages.clear()
+  }
+
+  /**
+   * 在本地更新邮件的已读状态（列表中的邮件对象与首页缓存共享）
+   * @param accountId - 账号ID
+   * @param messageIds - 邮件ID列表
+   * @param isRead - 已读状态
+   */
+  function applyReadState(accountId: number, messageIds: string[], isRead: boolean) {
+    const ids = new Set(messageIds)
+    messages.value.forEach(m => { if (ids.has(m.id)) m.isRead = isRead })
+    if (currentMessage.value && ids.has(currentMessage.value.id)) currentMessage.value.isRead = isRead
+    for (const id of ids) {
+      const cached = messageCache.get(`${accountId}-${id}`)
+      if (cached) cached.message.isRead = isRead
+    }
+  }
+
   /**
    * 重置所有邮件状态
    */
   function reset() {
     console.log('[MailStore] reset 重置所有状态')
+    requestId++
+    cancelPending()
     folders.value = [...defaultFolders]
     messages.value = []
     currentMessage.value = null
     attachments.value = []
     selectedFolderId.value = null
-    currentPage.value = 0
+    nextCursor.value = ''
+    hasMore.value = false
     error.value = null
   }
 
@@ -324,8 +515,9 @@ export const useMailStore = defineStore('mail', () => {
   }
 
   return {
-    folders, messages, currentMessage, attachments, selectedFolderId,
-    loading, detailLoading, currentPage, error,
-    loadFolders, loadMessages, loadMessageDetail, reset, clearAccountCache
+    folders, messages, currentMessage, attachments, attachmentError, selectedFolderId,
+    loading, detailLoading, nextCursor, hasMore, error,
+    loadFolders, loadMessages, loadMessageDetail, markMessages, flagMessages, moveMessages, deleteMessages,
+    reset, clearAccountCache
   }
 })
@@ -10,8 +10,17 @@ interface Window {
   go: {
     main: {
       App: {
-        ImportAccounts(content: string): Promise<number>
+        ImportAccounts(content: string, opts: { policy: string; format: string; template: string; mapping?: Record<string, string>; passphrase?: string; group?: string; verify?: boolean }): Promise<any>
+        ImportAccountsFromFile(opts: { policy: string; format: string; template: string; mapping?: Record<string, string>; passphrase?: string; group?: string; verify?: boolean }): Promise<any>
+        CancelImport(): Promise<void>
+        PreviewImport(content: string, opts: { policy: string; format: string; template: string; mapping?: Record<string, string>; passphrase?: string; group?: string; verify?: boolean }): Promise<any>
+        GetImportTemplates(): Promise<any[]>
+        CreateImportTemplate(tpl: { name: string; separator: string; fields: string[] }): Promise<any>
+        UpdateImportTemplate(tpl: { id: number; name: string; separator: string; fields: string[] }): Promise<void>
+        DeleteImportTemplate(id: number): Promise<void>
         GetAccounts(groupId: number | null): Promise<any[]>
+        FilterAccounts(filter: { groupId?: number; tagIds?: number[]; tagMode?: 'any' | 'all'; keyword?: string; status?: string; protocol?: string }): Promise<any[]>
+        ExportAccounts(filter: { groupId?: number; tagIds?: number[]; tagMode?: 'any' | 'all'; keyword?: string; status?: string; protocol?: string }, opts: { format?: 'line' | 'tab' | 'csv' | 'json' | 'bundle'; fields?: string[]; passphrase?: string }): Promise<number>
         DeleteAccount(id: number): Promise<void>
         DeleteAccounts(ids: number[]): Promise<void>
         GetAccountCount(): Promise<number>
@@ -23,13 +32,42 @@ interface Window {
         UpdateGroup(id: number, name: string): Promise<void>
         DeleteGroup(id: number): Promise<void>
         ClearGroup(groupId: number): Promise<void>
-        GetMailFolders(accountId: number): Promise<any[]>
-        GetMessages(accountId: number, folderId: string, page: number): Promise<any[]>
+        GetDeletedAccounts(): Promise<any[]>
+        RestoreAccounts(ids: number[]): Promise<number>
+        PurgeAccounts(ids: number[]): Promise<number>
+        EmptyRecycleBin(): Promise<number>
+        GetRecycleRetentionDays(): Promise<number>
+        SetRecycleRetentionDays(days: number): Promise<void>
+        GetWatchedAccounts(): Promise<number[]>
+        SetAccountWatch(accountId: number, watch: boolean): Promise<void>
+        GetTags(): Promise<any[]>
+        CreateTag(name: string, color: string): Promise<any>
+        UpdateTag(id: number, name: string, color: string): Promise<void>
+        DeleteTag(id: number): Promise<void>
+        TagAccounts(ids: number[], tagId: number): Promise<void>
+        UntagAccounts(ids: number[], tagId: number): Promise<void>
+        GetCustomFields(): Promise<any[]>
+        CreateCustomField(name: string, fieldType: string): Promise<any>
+        UpdateCustomField(id: number, name: string, fieldType: string): Promise<void>
+        DeleteCustomField(id: number): Promise<void>
+        UpdateAccountFields(accountId: number, values: Record<string, string>): Promise<void>
+        UpdateAccountNotes(accountId: number, notes: string): Promise<void>
+        GetAuditLogs(filter: { action?: string; targetType?: string; targetId?: number; keyword?: string; since?: string; until?: string; limit?: number; offset?: number }): Promise<any[]>
+        ExportAuditLogs(filter: { action?: string; targetType?: string; targetId?: number; keyword?: string; since?: string; until?: string }, format: 'csv' | 'json'): Promise<boolean>
+        GetMailFolders(accountId: number, requestId: string): Promise<any[]>
+        GetMessages(accountId: number, folderId: string, cursor: string, requestId: string): Promise<{ messages: any[]; nextCursor: string; hasMore: boolean; uidValidity?: number }>
         SearchMessages(accountId: number, folderId: string, keyword: string): Promise<any[]>
-        GetMessageDetail(accountId: number, messageId: string): Promise<any>
-        GetAttachments(accountId: number, messageId: string): Promise<any[]>
-        DeleteMessage(accountId: number, messageId: string): Promise<void>
-        MarkMessageRead(accountId: number, messageId: string, isRead: boolean): Promise<void>
+        GetMessageDetail(accountId: number, messageId: string, folderId: string, requestId: string): Promise<any>
+        GetAttachments(accountId: number, messageId: string, folderId: string, requestId: string): Promise<any[]>
+        CancelRequest(requestId: string): Promise<void>
+        MarkMessages(accountId: number, messageIds: string[], folderId: string, isRead: boolean, requestId: string): Promise<void>
+        FlagMessages(accountId: number, messageIds: string[], folderId: string, flagged: boolean, requestId: string): Promise<void>
+        MoveMessages(accountId: number, messageIds: string[], folderId: string, destinationId: string, requestId: string): Promise<void>
+        DeleteMessages(accountId: number, messageIds: string[], folderId: string, requestId: string): Promise<void>
+        MarkRead(accountId: number, messageId: string, folderId: string, requestId: string): Promise<void>
+        MarkUnread(accountId: number, messageId: string, folderId: string, requestId: string): Promise<void>
+        GetAutoMarkRead(): Promise<boolean>
+        SetAutoMarkRead(enabled: boolean): Promise<void>
         SaveFile(content: string): Promise<boolean>
       }
     }
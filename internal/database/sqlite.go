@@ -53,7 +53,8 @@ func Init() error {
 
 	// 打开SQLite数据库连接
 	// 如果文件不存在会自动创建
-	DB, err = sql.Open("sqlite3", dbPath)
+	// _busy_timeout：并发写入（如导入后并发检测Token更新状态）时等待锁释放，而不是立即返回database is locked
+	DB, err = sql.Open("sqlite3", dbPath+"?_busy_timeout=5000")
 	if err != nil {
 		return err
 	}
@@ -89,6 +90,55 @@ func Init() error {
 //   - last_error: 最后一次错误信息
 //   - created_at: 创建时间
 //   - updated_at: 更新时间
+//   - protocol: 协议类型（o2/imap，迁移添加）
+//   - notes: 备注（迁移添加）
+//   - deleted_at: 软删除时间，非NULL表示在回收站中（迁移添加）
+//
+// tags 标签表：
+//   - id: 主键，自增
+//   - name: 标签名称，唯一约束
+//   - color: 标签颜色
+//   - created_at: 创建时间
+//
+// account_tags 账号标签关联表（多对多）：
+//   - account_id: 账号ID
+//   - tag_id: 标签ID
+//   - 联合主键(account_id, tag_id)
+//
+// custom_fields 自定义字段定义表：
+//   - id: 主键，自增
+//   - name: 字段名称，唯一约束（如"辅助邮箱"、"手机号"）
+//   - type: 字段类型（text/number/date/email/phone）
+//   - sort_order: 排序顺序
+//   - created_at: 创建时间
+//
+// account_field_values 账号自定义字段值表：
+//   - account_id: 账号ID
+//   - field_id: 字段ID
+//   - value: 字段值（统一以文本存储）
+//   - 联合主键(account_id, field_id)
+//
+// audit_logs 操作审计日志表（只追加）：
+//   - id: 主键，自增
+//   - action: 操作类型（如account.import）
+//   - target_type: 操作对象类型
+//   - target_id: 操作对象ID
+//   - target: 操作对象描述
+//   - summary: 操作摘要
+//   - before/after: 操作前后状态摘要（JSON）
+//   - actor: 操作者
+//   - created_at: 操作时间（UTC）
+//
+// settings 应用设置表（键值对）：
+//   - key: 设置项键名，主键
+//   - value: 设置值（文本）
+//
+// import_templates 导入行模板表：
+//   - id: 主键，自增
+//   - name: 模板名称，唯一
+//   - separator: 分隔符（空表示自动识别）
+//   - fields: 各列字段名（JSON数组）
+//   - created_at: 创建时间
 //
 // 返回值：
 //   - error: SQL执行错误
@@ -126,6 +176,78 @@ func migrate() error {
 	-- 索引：加速按分组筛选
 	CREATE INDEX IF NOT EXISTS idx_accounts_group ON accounts(group_id);
 
+	-- 标签表：账号的多维度分类（一个账号可有多个标签）
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		color TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- 账号标签关联表
+	CREATE TABLE IF NOT EXISTS account_tags (
+		account_id INTEGER NOT NULL,
+		tag_id INTEGER NOT NULL,
+		PRIMARY KEY (account_id, tag_id),
+		FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+		FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+	);
+
+	-- 索引：加速按标签筛选账号
+	CREATE INDEX IF NOT EXISTS idx_account_tags_tag ON account_tags(tag_id);
+
+	-- 自定义字段定义表：用户自定义的账号附加信息
+	CREATE TABLE IF NOT EXISTS custom_fields (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		type TEXT NOT NULL DEFAULT 'text',
+		sort_order INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- 账号自定义字段值表
+	CREATE TABLE IF NOT EXISTS account_field_values (
+		account_id INTEGER NOT NULL,
+		field_id INTEGER NOT NULL,
+		value TEXT,
+		PRIMARY KEY (account_id, field_id),
+		FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+		FOREIGN KEY (field_id) REFERENCES custom_fields(id) ON DELETE CASCADE
+	);
+
+	-- 操作审计日志表：记录所有修改数据的操作，只追加不修改
+	CREATE TABLE IF NOT EXISTS audit_logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		action TEXT NOT NULL,
+		target_type TEXT NOT NULL,
+		target_id INTEGER,
+		target TEXT,
+		summary TEXT,
+		before TEXT,
+		after TEXT,
+		actor TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- 索引：加速按时间和操作类型筛选审计日志
+	CREATE INDEX IF NOT EXISTS idx_audit_logs_created ON audit_logs(created_at);
+	CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action);
+
+	-- 应用设置表：键值对形式存储用户设置
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT
+	);
+
+	-- 导入模板表：描述不同来源账号文本的分隔符和字段顺序
+	CREATE TABLE IF NOT EXISTS import_templates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		separator TEXT,
+		fields TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
 	-- 初始化默认分组（ID=1）
 	INSERT OR IGNORE INTO groups (id, name) VALUES (1, '默认分组');
 	`
@@ -137,6 +259,11 @@ func migrate() error {
 
 	// 添加 protocol 列（如果不存在）
 	DB.Exec("ALTER TABLE accounts ADD COLUMN protocol TEXT DEFAULT 'o2'")
+	// 添加 notes 备注列（如果不存在）
+	DB.Exec("ALTER TABLE accounts ADD COLUMN notes TEXT")
+	// 添加 deleted_at 软删除列（如果不存在），非NULL表示账号在回收站中
+	DB.Exec("ALTER TABLE accounts ADD COLUMN deleted_at DATETIME")
+	DB.Exec("CREATE INDEX IF NOT EXISTS idx_accounts_deleted ON accounts(deleted_at)")
 
 	return nil
 }
@@ -5,6 +5,9 @@
 // 本文件定义了应用的核心数据结构：
 // - Account: 邮箱账号信息
 // - Group: 账号分组信息
+// - Tag: 账号标签信息
+// - CustomField: 账号自定义字段定义
+// - AccountFilter: 账号列表筛选条件
 //
 // 这些结构体用于：
 // - 数据库记录映射
@@ -23,31 +26,86 @@ import "time"
 // - omitempty: 空值时不序列化（减少传输数据量）
 // - "-": 不序列化到JSON（敏感数据保护）
 type Account struct {
-	ID             int64      `json:"id"`                       // 账号ID，主键
-	Email          string     `json:"email"`                    // 邮箱地址，唯一标识
-	Password       string     `json:"password,omitempty"`       // 邮箱密码（可选，用于显示）
-	ClientID       string     `json:"clientId"`                 // OAuth2客户端ID（Azure应用注册）
-	RefreshToken   string     `json:"refreshToken,omitempty"`   // OAuth2刷新令牌（长期有效）
-	AccessToken    string     `json:"-"`                        // OAuth2访问令牌（不传给前端，安全考虑）
-	TokenExpiresAt *time.Time `json:"tokenExpiresAt,omitempty"` // 访问令牌过期时间
-	GroupID        *int64     `json:"groupId,omitempty"`        // 所属分组ID（可为空）
-	GroupName      string     `json:"groupName,omitempty"`      // 分组名称（JOIN查询填充）
-	DisplayName    string     `json:"displayName,omitempty"`    // 显示名称
-	Status         string     `json:"status"`                   // 状态：active=正常, error=异常
-	Protocol       string     `json:"protocol"`                 // 协议类型：o2=REST API, imap=IMAP协议
-	LastError      string     `json:"lastError,omitempty"`      // 最后一次错误信息
-	CreatedAt      time.Time  `json:"createdAt"`                // 创建时间
-	UpdatedAt      time.Time  `json:"updatedAt"`                // 更新时间
+	ID             int64             `json:"id"`                       // 账号ID，主键
+	Email          string            `json:"email"`                    // 邮箱地址，唯一标识
+	Password       string            `json:"password,omitempty"`       // 邮箱密码（可选，用于显示）
+	ClientID       string            `json:"clientId"`                 // OAuth2客户端ID（Azure应用注册）
+	RefreshToken   string            `json:"refreshToken,omitempty"`   // OAuth2刷新令牌（长期有效）
+	AccessToken    string            `json:"-"`                        // OAuth2访问令牌（不传给前端，安全考虑）
+	TokenExpiresAt *time.Time        `json:"tokenExpiresAt,omitempty"` // 访问令牌过期时间
+	GroupID        *int64            `json:"groupId,omitempty"`        // 所属分组ID（可为空）
+	GroupName      string            `json:"groupName,omitempty"`      // 分组名称（JOIN查询填充）
+	DisplayName    string            `json:"displayName,omitempty"`    // 显示名称
+	Status         string            `json:"status"`                   // 状态：active=正常, error=异常
+	Protocol       string            `json:"protocol"`                 // 协议类型（ProtocolXxx）
+	LastError      string            `json:"lastError,omitempty"`      // 最后一次错误信息
+	Tags           []Tag             `json:"tags,omitempty"`           // 账号标签（多对多关联查询填充）
+	Notes          string            `json:"notes,omitempty"`          // 备注（自由文本）
+	CustomFields   map[string]string `json:"customFields,omitempty"`   // 自定义字段值：字段名 -> 值
+	DeletedAt      *time.Time        `json:"deletedAt,omitempty"`      // 删除时间（仅回收站中的账号有值）
+	CreatedAt      time.Time         `json:"createdAt"`                // 创建时间
+	UpdatedAt      time.Time         `json:"updatedAt"`                // 更新时间
 }
 
+// 账号协议类型
+const (
+	ProtocolO2           = "o2"            // REST API（OAuth2）
+	ProtocolIMAP         = "imap"          // IMAP协议，XOAUTH2认证（REST API不可用时自动切换）
+	ProtocolIMAPPassword = "imap-password" // IMAP协议，密码认证（无ClientID和RefreshToken的账号）
+)
+
 // Group 分组模型
 //
 // 用于组织和管理账号，支持按分组筛选和批量操作
 // 对应数据库groups表
 type Group struct {
-	ID        int64  `json:"id"`                  // 分组ID，主键
-	Name      string `json:"name"`                // 分组名称
-	ParentID  *int64 `json:"parentId,omitempty"`  // 父分组ID（预留，支持嵌套分组）
-	SortOrder int    `json:"sortOrder"`           // 排序顺序
-	Count     int    `json:"count,omitempty"`     // 分组内账号数量（查询时计算）
+	ID        int64  `json:"id"`                 // 分组ID，主键
+	Name      string `json:"name"`               // 分组名称
+	ParentID  *int64 `json:"parentId,omitempty"` // 父分组ID（预留，支持嵌套分组）
+	SortOrder int    `json:"sortOrder"`          // 排序顺序
+	Count     int    `json:"count,omitempty"`    // 分组内账号数量（查询时计算）
+}
+
+// Tag 标签模型
+//
+// 与分组不同，一个账号可以同时拥有多个标签，用于按项目、负责人、地区、用途等多个维度分类
+// 对应数据库tags表，账号与标签的关联存储在account_tags表
+type Tag struct {
+	ID    int64  `json:"id"`              // 标签ID，主键
+	Name  string `json:"name"`            // 标签名称，唯一
+	Color string `json:"color,omitempty"` // 标签颜色（前端展示用，如"#3b82f6"）
+	Count int    `json:"count,omitempty"` // 使用该标签的账号数量（查询时计算）
+}
+
+// AccountFilter 账号列表筛选条件
+//
+// 各条件之间为AND关系，零值表示不筛选
+type AccountFilter struct {
+	GroupID  *int64  `json:"groupId,omitempty"`  // 分组ID，nil表示不限分组
+	TagIDs   []int64 `json:"tagIds,omitempty"`   // 标签ID列表
+	TagMode  string  `json:"tagMode,omitempty"`  // 标签匹配方式：any=任一标签（默认）, all=全部标签
+	Keyword  string  `json:"keyword,omitempty"`  // 关键字，模糊匹配邮箱、显示名称、备注和自定义字段值
+	Status   string  `json:"status,omitempty"`   // 账号状态（active/error等），空表示不限
+	Protocol string  `json:"protocol,omitempty"` // 协议类型（ProtocolXxx），空表示不限
+	Deleted  bool    `json:"deleted,omitempty"`  // true=只查询回收站中的账号，false=只查询正常账号
+}
+
+// 自定义字段类型
+const (
+	FieldTypeText   = "text"   // 文本
+	FieldTypeNumber = "number" // 数字
+	FieldTypeDate   = "date"   // 日期（YYYY-MM-DD）
+	FieldTypeEmail  = "email"  // 邮箱地址
+	FieldTypePhone  = "phone"  // 电话号码
+)
+
+// CustomField 自定义字段定义模型
+//
+// 用户可自行定义账号的附加信息（如辅助邮箱、手机号、生日、购买渠道）
+// 字段值按账号存储在account_field_values表，以字段名为键合并到Account.CustomFields
+type CustomField struct {
+	ID        int64  `json:"id"`        // 字段ID，主键
+	Name      string `json:"name"`      // 字段名称，唯一
+	Type      string `json:"type"`      // 字段类型：text/number/date/email/phone
+	SortOrder int    `json:"sortOrder"` // 排序顺序
 }
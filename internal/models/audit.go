@@ -0,0 +1,41 @@
+// Package models 数据模型层
+//
+// audit.go 操作审计日志数据模型定义
+//
+// 本文件定义了审计日志相关的数据结构：
+// - AuditLog: 单条审计记录
+// - AuditFilter: 审计日志查询条件
+package models
+
+import "time"
+
+// AuditLog 操作审计日志模型
+//
+// 记录每一次修改数据的操作（导入、删除、移动、分组变更、协议切换、Token刷新导致的状态变化等）
+// 对应数据库audit_logs表，只追加不修改
+type AuditLog struct {
+	ID         int64     `json:"id"`               // 日志ID，主键
+	Action     string    `json:"action"`           // 操作类型，如"account.import"、"group.delete"
+	TargetType string    `json:"targetType"`       // 操作对象类型：account/group/tag/field/setting
+	TargetID   int64     `json:"targetId"`         // 操作对象ID（批量操作时为0）
+	Target     string    `json:"target,omitempty"` // 操作对象描述（如邮箱地址、分组名）
+	Summary    string    `json:"summary"`          // 操作摘要（人类可读）
+	Before     string    `json:"before,omitempty"` // 操作前状态摘要（JSON）
+	After      string    `json:"after,omitempty"`  // 操作后状态摘要（JSON）
+	Actor      string    `json:"actor"`            // 操作者（系统用户名@主机名，后台任务为"system"）
+	CreatedAt  time.Time `json:"createdAt"`        // 操作时间
+}
+
+// AuditFilter 审计日志查询条件
+//
+// 各条件之间为AND关系，零值表示不筛选
+type AuditFilter struct {
+	Action     string `json:"action,omitempty"`     // 操作类型，支持前缀匹配（如"account."匹配所有账号操作）
+	TargetType string `json:"targetType,omitempty"` // 操作对象类型
+	TargetID   int64  `json:"targetId,omitempty"`   // 操作对象ID
+	Keyword    string `json:"keyword,omitempty"`    // 关键字，模糊匹配操作对象描述和摘要
+	Since      string `json:"since,omitempty"`      // 起始时间（含，UTC），格式YYYY-MM-DD或YYYY-MM-DD HH:MM:SS
+	Until      string `json:"until,omitempty"`      // 截止时间（不含，UTC），格式同上
+	Limit      int    `json:"limit,omitempty"`      // 返回条数上限，<=0表示不限
+	Offset     int    `json:"offset,omitempty"`     // 分页偏移量
+}
@@ -0,0 +1,30 @@
+// Package models 数据模型层
+//
+// export.go 账号导出相关数据模型定义
+//
+// 本文件定义了导出选项：
+// - ExportOptions: 导出格式和字段选择
+package models
+
+// 账号导出格式
+const (
+	ExportFormatLine   = "line"   // 四横线分隔的文本行，可直接重新导入
+	ExportFormatTab    = "tab"    // Tab分隔的文本行（可粘贴到表格）
+	ExportFormatCSV    = "csv"    // 带表头行的CSV
+	ExportFormatJSON   = "json"   // JSON对象数组
+	ExportFormatBundle = "bundle" // 口令加密的账号包（内容为JSON对象数组）
+)
+
+// 导出专用字段名（其余字段名与导入模板相同，见TemplateFieldXxx）
+const (
+	ExportFieldStatus   = "status"   // 账号状态
+	ExportFieldProtocol = "protocol" // 协议类型
+	ExportFieldTags     = "tags"     // 标签名（逗号分隔）
+)
+
+// ExportOptions 账号导出选项
+type ExportOptions struct {
+	Format     string   `json:"format"`               // 导出格式（ExportFormatXxx），为空时为line
+	Fields     []string `json:"fields,omitempty"`     // 导出的字段及顺序，为空时导出全部可重新导入的字段
+	Passphrase string   `json:"passphrase,omitempty"` // 加密包口令（bundle格式必填）
+}
@@ -0,0 +1,161 @@
+// Package models 数据模型层
+//
+// import.go 账号导入相关数据模型定义
+//
+// 本文件定义了导入选项、导入模板和导入结果报告的数据结构：
+// - ImportOptions: 导入选项（重复账号处理策略、数据格式、行模板、表头映射、加密包口令、目标分组）
+// - ImportTemplate: 导入行模板（分隔符、字段顺序）
+// - ImportReport: 一次导入的汇总报告
+// - ImportLineResult: 单行（单条记录）的处理结果
+// - ImportPreview: 导入预览（解析结果和预计处理方式，不写入数据库）
+package models
+
+// 重复账号处理策略（导入的邮箱已存在时如何处理）
+//
+// 所有策略都保留已有账号的ID、标签、协议和缓存Token（凭据未变化时）
+const (
+	ImportPolicySkip             = "skip"              // 跳过已存在的账号
+	ImportPolicyCredentials      = "credentials"       // 只更新凭据（密码、ClientID、RefreshToken）
+	ImportPolicyCredentialsGroup = "credentials-group" // 更新凭据并移动到导入指定的分组
+	ImportPolicyReplace          = "replace"           // 完全替换：凭据、分组、备注和自定义字段
+)
+
+// 导入数据格式
+const (
+	ImportFormatText   = "text"   // 按行的文本格式（由行模板描述）
+	ImportFormatCSV    = "csv"    // 带表头行的CSV
+	ImportFormatJSON   = "json"   // JSON对象数组
+	ImportFormatBundle = "bundle" // 口令加密的账号包（见ExportFormatBundle）
+)
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Policy     string            `json:"policy"`               // 重复账号处理策略（ImportPolicyXxx），为空时为replace
+	Format     string            `json:"format"`               // 数据格式（ImportFormatXxx），为空时为text（加密包自动识别）
+	Template   string            `json:"template"`             // 行模板名称（text格式），为空时为standard
+	Mapping    map[string]string `json:"mapping,omitempty"`    // 表头映射（csv/json格式）：表头名 -> 模板字段名
+	Passphrase string            `json:"passphrase,omitempty"` // 加密包口令（bundle格式必填）
+	Group      string            `json:"group,omitempty"`      // 目标分组，非空时所有账号导入到该分组，忽略数据中的分组
+	Verify     bool              `json:"verify,omitempty"`     // 导入后立即检测新建和更新账号的Token
+}
+
+// 内置导入模板名称
+const (
+	ImportTemplateStandard = "standard" // 标准格式：邮箱、密码、ClientID、RefreshToken、分组
+	ImportTemplateAuto     = "auto"     // 自动识别：按内容特征识别各字段
+	ImportTemplatePassword = "password" // 密码登录的IMAP账号：邮箱、密码、分组（无ClientID和RefreshToken）
+)
+
+// 导入模板字段名
+//
+// 模板字段列表中除以下名称外的其他名称视为同名自定义字段
+const (
+	TemplateFieldEmail        = "email"         // 邮箱（必需）
+	TemplateFieldPassword     = "password"      // 密码
+	TemplateFieldClientID     = "client_id"     // OAuth2客户端ID（必需，密码登录账号除外）
+	TemplateFieldRefreshToken = "refresh_token" // OAuth2刷新令牌（必需，密码登录账号除外）
+	TemplateFieldGroup        = "group"         // 分组名
+	TemplateFieldNotes        = "notes"         // 备注
+	TemplateFieldIgnore       = "-"             // 忽略该列（如辅助邮箱）
+)
+
+// ImportTemplate 导入行模板
+//
+// 描述一行账号文本的分隔符和各列含义，用于兼容不同来源的账号格式
+// 模板字段之后多出的列仍按"字段名=值"解析为备注和自定义字段
+type ImportTemplate struct {
+	ID        int64    `json:"id"`        // 模板ID，内置模板为0
+	Name      string   `json:"name"`      // 模板名称，唯一
+	Separator string   `json:"separator"` // 分隔符，为空时自动识别"----"或Tab
+	Fields    []string `json:"fields"`    // 各列字段名（TemplateFieldXxx或自定义字段名）
+	Builtin   bool     `json:"builtin"`   // 是否为内置模板（不可修改和删除）
+}
+
+// 单行导入结果状态
+const (
+	ImportCreated   = "created"           // 新建账号
+	ImportUpdated   = "updated"           // 更新已存在的账号
+	ImportDuplicate = "skipped-duplicate" // 跳过：与本次导入中前面的行邮箱重复
+	ImportExisting  = "skipped-existing"  // 跳过：账号已存在（skip策略）
+	ImportInvalid   = "invalid"           // 无效：格式错误、字段缺失或字段格式不合法
+	ImportFailed    = "failed"            // 失败：写入数据库出错
+)
+
+// ImportLineResult 单行导入结果
+//
+// 每个非空输入行对应一条结果，便于用户定位问题行
+type ImportLineResult struct {
+	Line      int    `json:"line"`                // 输入中的行号（从1开始）
+	Email     string `json:"email,omitempty"`     // 邮箱地址（解析失败时可能为空）
+	Status    string `json:"status"`              // 处理结果：created/updated/skipped-duplicate/skipped-existing/invalid/failed
+	Reason    string `json:"reason,omitempty"`    // 无效或失败的原因
+	Group     string `json:"group,omitempty"`     // 导入到的分组名
+	AccountID int64  `json:"accountId,omitempty"` // 写入后的账号ID
+	Live      *bool  `json:"live,omitempty"`      // 导入后检测结果：Token是否可用（未检测时为空）
+	LiveError string `json:"liveError,omitempty"` // 检测失败的原因
+}
+
+// ImportReport 导入结果报告
+//
+// 替代单纯的导入数量，完整说明每一行发生了什么
+type ImportReport struct {
+	Total         int                `json:"total"`           // 处理的非空行数
+	Created       int                `json:"created"`         // 新建账号数
+	Updated       int                `json:"updated"`         // 更新账号数
+	Skipped       int                `json:"skipped"`         // 跳过的行数（本次重复或已存在）
+	Invalid       int                `json:"invalid"`         // 无效行数
+	Failed        int                `json:"failed"`          // 写入失败行数
+	Verified      int                `json:"verified"`        // 导入后检测的账号数（未开启检测时为0）
+	Dead          int                `json:"dead"`            // 检测为不可用的账号数
+	GroupsCreated []string           `json:"groupsCreated"`   // 本次导入新建的分组名
+	Lines         []ImportLineResult `json:"lines"`           // 逐行结果（按行号顺序）
+	Cancelled     bool               `json:"cancelled"`       // 文件导入被用户取消（已提交的批次保留）
+	Error         string             `json:"error,omitempty"` // 文件导入中途无法继续读取时的错误（已提交的批次保留）
+}
+
+// Imported 返回成功写入（新建+更新）的账号数量
+func (r *ImportReport) Imported() int {
+	return r.Created + r.Updated
+}
+
+// Add 追加单行结果并更新汇总计数
+func (r *ImportReport) Add(line ImportLineResult) {
+	r.Total++
+	switch line.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportUpdated:
+		r.Updated++
+	case ImportDuplicate, ImportExisting:
+		r.Skipped++
+	case ImportInvalid:
+		r.Invalid++
+	case ImportFailed:
+		r.Failed++
+	}
+	r.Lines = append(r.Lines, line)
+}
+
+// ImportPreviewLine 导入预览的单行结果
+//
+// Status为按当前策略预计的处理结果，此时尚未写入数据库
+type ImportPreviewLine struct {
+	ImportLineResult
+	Password     string            `json:"password,omitempty"`     // 解析出的密码
+	ClientID     string            `json:"clientId,omitempty"`     // 解析出的ClientID
+	RefreshToken string            `json:"refreshToken,omitempty"` // 解析出的RefreshToken
+	Notes        string            `json:"notes,omitempty"`        // 解析出的备注
+	CustomFields map[string]string `json:"customFields,omitempty"` // 解析出的自定义字段
+}
+
+// ImportPreview 导入预览
+//
+// 在确认导入前展示每一行被解析成的字段，以及预计新建、更新、跳过的数量
+type ImportPreview struct {
+	Template string              `json:"template"` // 使用的模板名称（csv/json格式时为格式名）
+	Created  int                 `json:"created"`  // 预计新建数
+	Updated  int                 `json:"updated"`  // 预计更新数
+	Skipped  int                 `json:"skipped"`  // 预计跳过数
+	Invalid  int                 `json:"invalid"`  // 无效行数
+	Lines    []ImportPreviewLine `json:"lines"`    // 逐行预览
+}
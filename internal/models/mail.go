@@ -5,7 +5,9 @@
 // 本文件定义了与Microsoft Outlook API交互的数据结构：
 // - MailFolder: 邮件文件夹
 // - Message: 邮件消息
+// - MessagePage: 一页邮件列表及下一页的游标
 // - MessageBody: 邮件正文
+// - MessageFlag: 邮件旗标
 // - EmailAddr: 邮件地址
 // - Attachment: 邮件附件
 //
@@ -18,11 +20,16 @@ package models
 // 对应Outlook API的MailFolder资源
 // 常见文件夹：Inbox(收件箱)、SentItems(已发送)、Drafts(草稿)、
 // DeletedItems(已删除)、JunkEmail(垃圾邮件)
+// 文件夹列表是树形结构：顶层文件夹的ChildFolders包含其子文件夹
 type MailFolder struct {
-	ID              string `json:"id"`              // 文件夹唯一标识（可能是GUID或预定义名称如"inbox"）
-	DisplayName     string `json:"displayName"`     // 显示名称（如"Inbox"、"Sent Items"）
-	TotalItemCount  int    `json:"totalItemCount"`  // 文件夹内邮件总数
-	UnreadItemCount int    `json:"unreadItemCount"` // 未读邮件数量
+	ID               string       `json:"id"`                       // 文件夹唯一标识（可能是GUID或预定义名称如"inbox"）
+	DisplayName      string       `json:"displayName"`              // 显示名称（如"Inbox"、"Sent Items"）
+	ParentFolderID   string       `json:"parentFolderId,omitempty"` // 父文件夹ID（IMAP顶层文件夹为空）
+	TotalItemCount   int          `json:"totalItemCount"`           // 文件夹内邮件总数
+	UnreadItemCount  int          `json:"unreadItemCount"`          // 未读邮件数量
+	ChildFolderCount int          `json:"childFolderCount"`         // 子文件夹数量
+	ChildFolders     []MailFolder `json:"childFolders,omitempty"`   // 子文件夹
+	NoSelect         bool         `json:"noSelect,omitempty"`       // 是否不能打开（IMAP中只用于容纳子文件夹的层级节点）
 }
 
 // Message 邮件消息模型
@@ -30,15 +37,54 @@ type MailFolder struct {
 // 对应Outlook API的Message资源
 // 包含邮件的基本信息和内容
 type Message struct {
-	ID               string       `json:"id"`               // 邮件唯一标识（GUID）
-	Subject          string       `json:"subject"`          // 邮件主题
-	BodyPreview      string       `json:"bodyPreview"`      // 正文预览（纯文本，约255字符）
-	Body             *MessageBody `json:"body,omitempty"`   // 完整正文（仅在获取详情时返回）
-	From             *EmailAddr   `json:"from,omitempty"`   // 发件人地址
-	ToRecipients     []EmailAddr  `json:"toRecipients,omitempty"` // 收件人列表
-	ReceivedDateTime string       `json:"receivedDateTime"` // 接收时间（ISO 8601格式）
-	HasAttachments   bool         `json:"hasAttachments"`   // 是否有附件
-	IsRead           bool         `json:"isRead"`           // 是否已读
+	ID               string       `json:"id"`                      // 邮件唯一标识（GUID）
+	Subject          string       `json:"subject"`                 // 邮件主题
+	BodyPreview      string       `json:"bodyPreview"`             // 正文预览（纯文本，约255字符）
+	Body             *MessageBody `json:"body,omitempty"`          // 完整正文（仅在获取详情时返回）
+	From             *EmailAddr   `json:"from,omitempty"`          // 发件人地址
+	Sender           *EmailAddr   `json:"sender,omitempty"`        // 实际发送者（代发时与发件人不同）
+	ToRecipients     []EmailAddr  `json:"toRecipients,omitempty"`  // 收件人列表
+	CcRecipients     []EmailAddr  `json:"ccRecipients,omitempty"`  // 抄送人列表
+	BccRecipients    []EmailAddr  `json:"bccRecipients,omitempty"` // 密送人列表（通常只有已发送邮件包含）
+	ReplyTo          []EmailAddr  `json:"replyTo,omitempty"`       // 回复地址列表（未设置时回复发件人）
+	ReceivedDateTime string       `json:"receivedDateTime"`        // 接收时间（ISO 8601格式）
+	HasAttachments   bool         `json:"hasAttachments"`          // 是否有附件
+	IsRead           bool         `json:"isRead"`                  // 是否已读
+	Flag             *MessageFlag `json:"flag,omitempty"`          // 旗标状态
+	Attachments      []Attachment `json:"attachments,omitempty"`   // 附件（仅IMAP邮件详情包含，REST API通过GetAttachments获取）
+}
+
+// MessagePage 一页邮件列表
+//
+// NextCursor是不透明的游标，原样传回即可获取下一页；
+// IMAP账号的游标基于UID，新邮件到达或邮件被删除时不会导致翻页重复或遗漏
+type MessagePage struct {
+	Messages    []Message `json:"messages"`              // 本页邮件（最新的在前）
+	NextCursor  string    `json:"nextCursor"`            // 下一页的游标，没有更多邮件时为空
+	HasMore     bool      `json:"hasMore"`               // 是否还有更早的邮件
+	UIDValidity uint32    `json:"uidValidity,omitempty"` // IMAP文件夹的UIDVALIDITY（REST API账号为0）
+}
+
+// 旗标状态（MessageFlag.FlagStatus）
+const (
+	FlagStatusNotFlagged = "NotFlagged" // 未加旗标
+	FlagStatusFlagged    = "Flagged"    // 已加旗标（IMAP为\Flagged标志）
+	FlagStatusComplete   = "Complete"   // 已完成（仅REST API）
+)
+
+// MessageFlag 邮件旗标模型
+//
+// 对应Outlook API的FollowupFlag，IMAP账号按\Flagged标志填充
+type MessageFlag struct {
+	FlagStatus string `json:"flagStatus"` // 旗标状态：NotFlagged、Flagged或Complete
+}
+
+// NewMessageFlag 按是否加旗标创建旗标
+func NewMessageFlag(flagged bool) *MessageFlag {
+	if flagged {
+		return &MessageFlag{FlagStatus: FlagStatusFlagged}
+	}
+	return &MessageFlag{FlagStatus: FlagStatusNotFlagged}
 }
 
 // MessageBody 邮件正文模型
@@ -60,6 +106,21 @@ type EmailAddr struct {
 	} `json:"emailAddress"`
 }
 
+// NewEmailAddr 创建邮件地址
+//
+// 参数：
+//   - name: 显示名称，可为空
+//   - address: 邮箱地址
+//
+// 返回值：
+//   - EmailAddr: 邮件地址
+func NewEmailAddr(name, address string) EmailAddr {
+	var a EmailAddr
+	a.EmailAddress.Name = name
+	a.EmailAddress.Address = address
+	return a
+}
+
 // Attachment 邮件附件模型
 //
 // 对应Outlook API的Attachment资源
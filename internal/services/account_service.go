@@ -4,19 +4,31 @@
 //
 // 功能说明：
 // - 账号的CRUD操作（增删改查）
-// - 批量导入账号（解析文本格式）
+// - 批量导入账号（解析文本格式，大文件按批次流式导入）
 // - Token和状态更新
 // - 分组关联管理
+// - 按分组、标签组合筛选
+// - 备注和自定义字段（含关键字搜索）
+// - 软删除、回收站恢复和彻底删除
 package services
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"database/sql"
+	"fmt"
+	"io"
 	"outlook-mail-manager/internal/database"
 	"outlook-mail-manager/internal/models"
 	"outlook-mail-manager/internal/utils"
+	"strings"
 	"time"
 )
 
+// importBatchSize 流式导入时每个事务写入的记录数
+const importBatchSize = 500
+
 // AccountService 账号服务
 //
 // 提供账号相关的所有数据库操作
@@ -31,31 +43,86 @@ func NewAccountService() *AccountService {
 	return &AccountService{}
 }
 
+// likeEscaper 转义LIKE模式中的通配符，使关键字中的"%"和"_"按字面匹配（配合 ESCAPE '\' 使用）
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike 转义LIKE关键字中的通配符
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
 // List 获取账号列表
 //
-// 支持按分组筛选，返回账号的完整信息（含分组名称）
+// 支持按分组、标签组合筛选，返回账号的完整信息（含分组名称和标签）
 // 使用LEFT JOIN关联groups表获取分组名称
 //
 // 参数：
-//   - groupID: 分组ID指针，nil表示查询所有账号
+//   - filter: 筛选条件，零值表示查询所有账号
+//     - GroupID: 分组ID指针，nil表示不限分组
+//     - TagIDs: 标签ID列表，空表示不限标签
+//     - TagMode: "all"表示必须包含全部标签，否则包含任一标签即可
+//     - Keyword: 关键字，模糊匹配邮箱、显示名称、备注和自定义字段值
+//     - Status/Protocol: 账号状态和协议类型，空表示不限
+//     - Deleted: true时查询回收站中的账号（回收站视图）
 //
 // 返回值：
 //   - []models.Account: 账号列表，按ID倒序排列（最新的在前）
 //   - error: 数据库查询错误
-func (s *AccountService) List(groupID *int64) ([]models.Account, error) {
+func (s *AccountService) List(filter models.AccountFilter) ([]models.Account, error) {
 	// SQL查询：关联accounts和groups表
 	// COALESCE处理NULL值，提供默认值
 	query := `SELECT a.id, a.email, COALESCE(a.password,''), a.client_id, COALESCE(a.refresh_token,''), COALESCE(a.access_token,''),
 		a.token_expires_at, a.group_id, COALESCE(g.name, '默认分组'), COALESCE(a.display_name,''), COALESCE(a.status,'active'),
-		COALESCE(a.protocol,'o2'), COALESCE(a.last_error,''), a.created_at, a.updated_at
+		COALESCE(a.protocol,'o2'), COALESCE(a.last_error,''), COALESCE(a.notes,''), a.deleted_at, a.created_at, a.updated_at
 		FROM accounts a LEFT JOIN groups g ON a.group_id = g.id`
+	var conds []string
 	args := []interface{}{}
+	// 回收站视图与正常视图互斥
+	if filter.Deleted {
+		conds = append(conds, "a.deleted_at IS NOT NULL")
+	} else {
+		conds = append(conds, "a.deleted_at IS NULL")
+	}
 	// 可选的分组筛选条件
-	if groupID != nil {
-		query += " WHERE a.group_id = ?"
-		args = append(args, *groupID)
+	if filter.GroupID != nil {
+		conds = append(conds, "a.group_id = ?")
+		args = append(args, *filter.GroupID)
+	}
+	// 可选的标签筛选条件（与分组条件为AND关系）
+	if tagIDs := uniqueIDs(filter.TagIDs); len(tagIDs) > 0 {
+		cond := "a.id IN (SELECT account_id FROM account_tags WHERE tag_id IN (" + placeholders(len(tagIDs)) + ")"
+		for _, id := range tagIDs {
+			args = append(args, id)
+		}
+		if filter.TagMode == "all" {
+			// 全部匹配：命中的不同标签数必须等于筛选标签数（已去重，重复的ID不会导致无法匹配）
+			cond += " GROUP BY account_id HAVING COUNT(DISTINCT tag_id) = ?"
+			args = append(args, len(tagIDs))
+		}
+		conds = append(conds, cond+")")
+	}
+	// 可选的关键字搜索：邮箱、显示名称、备注、自定义字段值
+	if kw := strings.TrimSpace(filter.Keyword); kw != "" {
+		like := "%" + escapeLike(kw) + "%"
+		conds = append(conds, `(a.email LIKE ? ESCAPE '\' OR a.display_name LIKE ? ESCAPE '\' OR a.notes LIKE ? ESCAPE '\'
+			OR a.id IN (SELECT account_id FROM account_field_values WHERE value LIKE ? ESCAPE '\'))`)
+		args = append(args, like, like, like, like)
+	}
+	// 可选的状态和协议筛选条件
+	if filter.Status != "" {
+		conds = append(conds, "COALESCE(a.status,'active') = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Protocol != "" {
+		conds = append(conds, "COALESCE(a.protocol,'o2') = ?")
+		args = append(args, filter.Protocol)
+	}
+	query += " WHERE " + strings.Join(conds, " AND ")
+	if filter.Deleted {
+		query += " ORDER BY a.deleted_at DESC, a.id DESC" // 最近删除的排在前面
+	} else {
+		query += " ORDER BY a.id DESC" // 最新账号排在前面
 	}
-	query += " ORDER BY a.id DESC" // 最新账号排在前面
 
 	rows, err := database.DB.Query(query, args...)
 	if err != nil {
@@ -63,15 +130,26 @@ func (s *AccountService) List(groupID *int64) ([]models.Account, error) {
 	}
 	defer rows.Close()
 
+	// 批量加载账号标签和自定义字段，避免逐个账号查询
+	tags, err := tagsByAccount()
+	if err != nil {
+		return nil, err
+	}
+	fieldValues, err := fieldValuesByAccount()
+	if err != nil {
+		return nil, err
+	}
+
 	// 遍历结果集，构建账号列表
 	var accounts []models.Account
 	for rows.Next() {
 		var a models.Account
-		var tokenExp sql.NullString   // Token过期时间可能为NULL
-		var grpID sql.NullInt64       // 分组ID可能为NULL
+		var tokenExp sql.NullString // Token过期时间可能为NULL
+		var grpID sql.NullInt64     // 分组ID可能为NULL
+		var deletedAt sql.NullTime  // 删除时间，仅回收站中的账号有值
 		var createdAt, updatedAt sql.NullString
 		err := rows.Scan(&a.ID, &a.Email, &a.Password, &a.ClientID, &a.RefreshToken, &a.AccessToken,
-			&tokenExp, &grpID, &a.GroupName, &a.DisplayName, &a.Status, &a.Protocol, &a.LastError, &createdAt, &updatedAt)
+			&tokenExp, &grpID, &a.GroupName, &a.DisplayName, &a.Status, &a.Protocol, &a.LastError, &a.Notes, &deletedAt, &createdAt, &updatedAt)
 		if err != nil {
 			continue // 跳过解析失败的行
 		}
@@ -79,6 +157,11 @@ func (s *AccountService) List(groupID *int64) ([]models.Account, error) {
 		if grpID.Valid {
 			a.GroupID = &grpID.Int64
 		}
+		if deletedAt.Valid {
+			a.DeletedAt = &deletedAt.Time
+		}
+		a.Tags = tags[a.ID]
+		a.CustomFields = fieldValues[a.ID]
 		accounts = append(accounts, a)
 	}
 	return accounts, nil
@@ -87,7 +170,7 @@ func (s *AccountService) List(groupID *int64) ([]models.Account, error) {
 // GetByID 根据ID获取单个账号详情
 //
 // 用于获取账号的完整信息，包括Token和过期时间
-// 主要用于Token刷新流程
+// 主要用于Token刷新流程，回收站中的账号视为不存在
 //
 // 参数：
 //   - id: 账号ID
@@ -98,12 +181,12 @@ func (s *AccountService) List(groupID *int64) ([]models.Account, error) {
 func (s *AccountService) GetByID(id int64) (*models.Account, error) {
 	var a models.Account
 	// 使用sql.NullXxx类型处理可空字段
-	var tokenExp, displayName, lastErr, accessToken, protocol sql.NullString
+	var tokenExp, displayName, lastErr, accessToken, protocol, notes sql.NullString
 	var grpID sql.NullInt64
 	err := database.DB.QueryRow(`SELECT id, email, COALESCE(password,''), client_id, COALESCE(refresh_token,''), access_token,
-		token_expires_at, group_id, display_name, COALESCE(status,'active'), protocol, last_error FROM accounts WHERE id = ?`, id).
+		token_expires_at, group_id, display_name, COALESCE(status,'active'), protocol, last_error, notes FROM accounts WHERE id = ? AND deleted_at IS NULL`, id).
 		Scan(&a.ID, &a.Email, &a.Password, &a.ClientID, &a.RefreshToken, &accessToken,
-			&tokenExp, &grpID, &displayName, &a.Status, &protocol, &lastErr)
+			&tokenExp, &grpID, &displayName, &a.Status, &protocol, &lastErr, &notes)
 	if err != nil {
 		return nil, err
 	}
@@ -120,6 +203,9 @@ func (s *AccountService) GetByID(id int64) (*models.Account, error) {
 	if protocol.Valid {
 		a.Protocol = protocol.String
 	}
+	if notes.Valid {
+		a.Notes = notes.String
+	}
 	// 解析Token过期时间（RFC3339格式）
 	if tokenExp.Valid {
 		t, _ := time.Parse(time.RFC3339, tokenExp.String)
@@ -128,38 +214,609 @@ func (s *AccountService) GetByID(id int64) (*models.Account, error) {
 	return &a, nil
 }
 
+// dbExecutor 数据库执行器
+//
+// *sql.DB 和 *sql.Tx 都实现了该接口，使导入等批量写入可以在事务中复用相同的辅助函数
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 // Import 批量导入账号
 //
-// 解析用户输入的文本，批量创建或更新账号
-// 使用INSERT OR REPLACE实现存在则更新、不存在则插入
+// 解析用户输入的文本，批量创建或更新账号，返回逐行的导入报告
+// 邮箱已存在时按opts.Policy处理，更新时保留原账号ID
 //
 // 支持的文本格式：
 // - 邮箱----密码----ClientID----RefreshToken----分组名
 // - 邮箱\t密码\tClientID\tRefreshToken\t分组名
+// - 分组名之后可追加"字段名=值"形式的自定义字段和备注（notes=...）
+// - 其他格式由opts.Template指定的导入模板描述
+// - opts.Format为csv/json时按带表头的CSV或JSON对象数组解析
+// - opts.Format为bundle（或内容为加密包）时用opts.Passphrase解密后按JSON解析
 //
 // 参数：
 //   - text: 包含账号信息的多行文本
+//   - opts: 导入选项
 //
 // 返回值：
-//   - int: 成功导入的账号数量
-//   - error: 始终返回nil（错误在内部处理）
-func (s *AccountService) Import(text string) (int, error) {
-	// 调用解析工具解析文本
-	accounts, groupNames, _ := utils.ParseAccountsText(text)
-	count := 0
-	for i, acc := range accounts {
-		// 确保分组存在（不存在则创建）
-		groupID := s.ensureGroup(groupNames[i])
-		// INSERT OR REPLACE：邮箱唯一，存在则更新
-		_, err := database.DB.Exec(`INSERT OR REPLACE INTO accounts
-			(email, password, client_id, refresh_token, group_id, status, updated_at)
-			VALUES (?, ?, ?, ?, ?, 'active', CURRENT_TIMESTAMP)`,
-			acc.Email, acc.Password, acc.ClientID, acc.RefreshToken, groupID)
-		if err == nil {
-			count++
-		}
-	}
-	return count, nil
+//   - *models.ImportReport: 导入报告（新建、更新、跳过、无效、失败的逐行结果）
+//   - error: 格式或模板无效、策略无效、无法开启或提交事务时返回错误
+func (s *AccountService) Import(text string, opts models.ImportOptions) (*models.ImportReport, error) {
+	lines, _, err := parseImportText(text, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.ImportLines(lines, opts)
+}
+
+// parseImportText 按导入选项中的数据格式解析导入内容
+//
+// 参数：
+//   - text: 导入内容
+//   - opts: 导入选项（Format、Template、Mapping、Passphrase、Group）
+//
+// 返回值：
+//   - []utils.ParsedLine: 逐行（逐条记录）解析结果，字段格式不合法的行Err非空
+//   - string: 使用的模板名称，csv/json/bundle格式时为格式名
+//   - error: 格式未知、模板不存在、加密包口令错误或内容整体无法解析时返回错误
+func parseImportText(text string, opts models.ImportOptions) ([]utils.ParsedLine, string, error) {
+	r, name, err := newImportReader(strings.NewReader(text), opts)
+	if err != nil {
+		return nil, "", err
+	}
+	lines, err := utils.ReadAccountLines(r)
+	if err != nil {
+		return nil, "", err
+	}
+	group := strings.TrimSpace(opts.Group)
+	for i := range lines {
+		prepareImportLine(&lines[i], group)
+	}
+	return lines, name, nil
+}
+
+// newImportReader 按导入选项中的数据格式创建账号记录读取器
+//
+// 格式为空时，内容以加密包标记开头则按bundle处理，否则按文本行处理
+// 加密包需要完整内容才能校验，会先全部读入内存再解密
+//
+// 参数：
+//   - r: 导入内容
+//   - opts: 导入选项（Format、Template、Mapping、Passphrase）
+//
+// 返回值：
+//   - utils.AccountReader: 账号记录读取器
+//   - string: 使用的模板名称，csv/json/bundle格式时为格式名
+//   - error: 格式未知、模板不存在、加密包口令错误或表头无效时返回错误
+func newImportReader(r io.Reader, opts models.ImportOptions) (utils.AccountReader, string, error) {
+	format := opts.Format
+	if format == "" {
+		br := bufio.NewReader(r)
+		head, _ := br.Peek(256)
+		if utils.IsBundle(string(head)) {
+			format = models.ImportFormatBundle
+		}
+		r = br
+	}
+
+	switch format {
+	case models.ImportFormatCSV:
+		ar, err := utils.NewCSVAccountReader(r, opts.Mapping)
+		return ar, format, err
+	case models.ImportFormatJSON:
+		ar, err := utils.NewJSONAccountReader(r, opts.Mapping)
+		return ar, format, err
+	case models.ImportFormatBundle:
+		// 加密包内容为JSON对象数组（见ExportService）
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, "", err
+		}
+		plain, err := utils.OpenBundle(string(data), opts.Passphrase)
+		if err != nil {
+			return nil, "", err
+		}
+		ar, err := utils.NewJSONAccountReader(bytes.NewReader(plain), opts.Mapping)
+		return ar, format, err
+	case "", models.ImportFormatText:
+		tpl, err := loadImportTemplate(opts.Template)
+		if err != nil {
+			return nil, "", err
+		}
+		return utils.NewLineAccountReader(r, tpl), tpl.Name, nil
+	}
+	return nil, "", fmt.Errorf("invalid import format: %s", opts.Format)
+}
+
+// prepareImportLine 校验解析成功的行并应用目标分组
+//
+// 参数：
+//   - p: 解析结果，校验失败时写入Err
+//   - group: 目标分组，非空时覆盖数据中的分组
+func prepareImportLine(p *utils.ParsedLine, group string) {
+	if p.Err != nil {
+		return
+	}
+	// 校验字段格式（邮箱、ClientID、RefreshToken）
+	p.Err = utils.ValidateAccount(p.Account)
+	// 指定了目标分组时覆盖数据中的分组
+	if group != "" {
+		p.Group = group
+	}
+}
+
+// PreviewImport 预览导入结果
+//
+// 按数据格式和模板解析文本，并按策略判断每一行预计的处理结果，不写入数据库
+//
+// 参数：
+//   - text: 包含账号信息的多行文本
+//   - opts: 导入选项（与实际导入时相同）
+//
+// 返回值：
+//   - *models.ImportPreview: 逐行预览和预计数量
+//   - error: 格式或模板无效、策略无效或数据库查询错误
+func (s *AccountService) PreviewImport(text string, opts models.ImportOptions) (*models.ImportPreview, error) {
+	policy, err := normalizeImportPolicy(opts.Policy)
+	if err != nil {
+		return nil, err
+	}
+	lines, template, err := parseImportText(text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &models.ImportPreview{Template: template, Lines: []models.ImportPreviewLine{}}
+	seen := make(map[string]int) // 小写邮箱 -> 首次出现的行号
+	for _, p := range lines {
+		line := models.ImportPreviewLine{ImportLineResult: models.ImportLineResult{Line: p.Line}}
+		if p.Err != nil {
+			line.Status = models.ImportInvalid
+			line.Reason = p.Err.Error()
+			if p.Account != nil {
+				line.Email = p.Account.Email
+			}
+			preview.Invalid++
+			preview.Lines = append(preview.Lines, line)
+			continue
+		}
+		acc := p.Account
+		line.Email = acc.Email
+		line.Group = p.Group
+		line.Password = acc.Password
+		line.ClientID = acc.ClientID
+		line.RefreshToken = acc.RefreshToken
+		line.Notes = acc.Notes
+		line.CustomFields = acc.CustomFields
+
+		if err := validateImportFields(database.DB, acc.CustomFields); err != nil {
+			line.Status = models.ImportInvalid
+			line.Reason = "custom fields: " + err.Error()
+			preview.Invalid++
+			preview.Lines = append(preview.Lines, line)
+			continue
+		}
+
+		key := strings.ToLower(acc.Email)
+		if first, ok := seen[key]; ok {
+			line.Status = models.ImportDuplicate
+			line.Reason = fmt.Sprintf("duplicate of line %d", first)
+			preview.Skipped++
+			preview.Lines = append(preview.Lines, line)
+			continue
+		}
+		seen[key] = p.Line
+
+		existingID, _, err := findImportTarget(database.DB, acc.Email)
+		if err != nil {
+			return nil, err
+		}
+		line.AccountID = existingID
+		switch {
+		case existingID == 0:
+			line.Status = models.ImportCreated
+			preview.Created++
+		case policy == models.ImportPolicySkip:
+			line.Status = models.ImportExisting
+			preview.Skipped++
+		default:
+			line.Status = models.ImportUpdated
+			if policy == models.ImportPolicyCredentials {
+				line.Group = "" // 只更新凭据，不移动分组
+			}
+			preview.Updated++
+		}
+		preview.Lines = append(preview.Lines, line)
+	}
+	return preview, nil
+}
+
+// findImportTarget 查找导入邮箱对应的已有账号
+//
+// 邮箱不区分大小写，包括回收站中的账号
+//
+// 返回值：
+//   - int64: 已有账号ID，不存在时为0
+//   - bool: 账号是否在回收站中
+//   - error: 数据库查询错误
+func findImportTarget(db dbExecutor, email string) (int64, bool, error) {
+	var id int64
+	var deletedAt sql.NullTime
+	err := db.QueryRow("SELECT id, deleted_at FROM accounts WHERE email = ? COLLATE NOCASE", email).Scan(&id, &deletedAt)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return id, deletedAt.Valid, nil
+}
+
+// ImportLines 导入已解析的账号行
+//
+// 所有写入在一个事务中完成；单行写入失败只记录到报告中，不影响其他行
+// 同一批次中邮箱重复（不区分大小写）的行，只导入第一次出现的行，其余标记为重复跳过
+//
+// 参数：
+//   - lines: 逐行解析结果（含解析失败的行）
+//   - opts: 导入选项
+//
+// 返回值：
+//   - *models.ImportReport: 导入报告
+//   - error: 策略无效、无法开启或提交事务时返回错误
+func (s *AccountService) ImportLines(lines []utils.ParsedLine, opts models.ImportOptions) (*models.ImportReport, error) {
+	im, err := newAccountImporter(opts.Policy)
+	if err != nil {
+		return nil, err
+	}
+	if err := im.importBatch(lines); err != nil {
+		return nil, err
+	}
+	return im.report, nil
+}
+
+// ImportStream 流式导入账号
+//
+// 从r逐条读取记录，每importBatchSize条在一个事务中写入，内存占用与文件大小无关
+// 每提交一个批次调用一次progress；ctx被取消时在当前批次提交后停止，报告Cancelled为true
+// 已提交的批次不会回滚；内容中途无法继续解析（如JSON语法错误）时，
+// 之前读取的记录照常写入，错误记录在报告的Error中
+//
+// 参数：
+//   - ctx: 用于取消导入
+//   - r: 导入内容（文件）
+//   - opts: 导入选项（与Import相同）
+//   - progress: 进度回调，参数为已处理的记录数，可为nil
+//
+// 返回值：
+//   - *models.ImportReport: 导入报告（取消或中断时为已处理部分的报告）
+//   - error: 格式或模板无效、策略无效、加密包口令错误、无法开启或提交事务时返回错误
+func (s *AccountService) ImportStream(ctx context.Context, r io.Reader, opts models.ImportOptions,
+	progress func(records int)) (*models.ImportReport, error) {
+	im, err := newAccountImporter(opts.Policy)
+	if err != nil {
+		return nil, err
+	}
+	ar, _, err := newImportReader(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	group := strings.TrimSpace(opts.Group)
+	batch := make([]utils.ParsedLine, 0, importBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := im.importBatch(batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		if progress != nil {
+			progress(im.report.Total)
+		}
+		return nil
+	}
+
+	for {
+		if ctx.Err() != nil {
+			im.report.Cancelled = true
+			break
+		}
+		p, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			im.report.Error = err.Error()
+			break
+		}
+		prepareImportLine(&p, group)
+		batch = append(batch, p)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// 取消时丢弃尚未提交的记录，中断或读完时写入剩余记录
+	if im.report.Cancelled {
+		return im.report, nil
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return im.report, nil
+}
+
+// accountImporter 账号导入器
+//
+// 在多个批次（事务）之间共享重复检测和导入报告
+type accountImporter struct {
+	policy string               // 重复账号处理策略
+	report *models.ImportReport // 累计的导入报告
+	seen   map[string]int       // 小写邮箱 -> 首次出现的行号
+}
+
+// newAccountImporter 创建账号导入器
+//
+// 参数：
+//   - policy: 重复账号处理策略，为空时为replace
+//
+// 返回值：
+//   - *accountImporter: 导入器
+//   - error: 策略无效时返回错误
+func newAccountImporter(policy string) (*accountImporter, error) {
+	policy, err := normalizeImportPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	return &accountImporter{
+		policy: policy,
+		report: &models.ImportReport{GroupsCreated: []string{}, Lines: []models.ImportLineResult{}},
+		seen:   make(map[string]int),
+	}, nil
+}
+
+// importBatch 在一个事务中导入一批账号行，结果追加到报告
+//
+// 每一行在一个保存点中写入，写入失败时回滚到保存点，
+// 撤销该行已执行的部分（如账号已插入但自定义字段写入失败），不影响同批次的其他行
+//
+// 返回值：
+//   - error: 无法开启或提交事务、无法设置或回滚保存点时返回错误（此时该批次的结果不计入报告）
+func (im *accountImporter) importBatch(lines []utils.ParsedLine) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	results := make([]models.ImportLineResult, 0, len(lines))
+	var groupsCreated []string
+	for _, p := range lines {
+		if _, err := tx.Exec("SAVEPOINT import_line"); err != nil {
+			return err
+		}
+		result, group := im.importLine(tx, p)
+		if result.Status == models.ImportFailed {
+			if _, err := tx.Exec("ROLLBACK TO import_line"); err != nil {
+				return err
+			}
+			group = "" // 新建的分组也已回滚
+		}
+		if _, err := tx.Exec("RELEASE import_line"); err != nil {
+			return err
+		}
+		if group != "" {
+			groupsCreated = append(groupsCreated, group)
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, r := range results {
+		im.report.Add(r)
+	}
+	im.report.GroupsCreated = append(im.report.GroupsCreated, groupsCreated...)
+	return nil
+}
+
+// importLine 导入单行
+//
+// 处理结果为failed时，该行已执行的写入由importBatch回滚
+//
+// 返回值：
+//   - models.ImportLineResult: 该行的处理结果
+//   - string: 为该行新建的分组名，未新建时为空
+func (im *accountImporter) importLine(tx dbExecutor, p utils.ParsedLine) (models.ImportLineResult, string) {
+	result := models.ImportLineResult{Line: p.Line}
+	if p.Err != nil {
+		result.Status = models.ImportInvalid
+		result.Reason = p.Err.Error()
+		if p.Account != nil {
+			result.Email = p.Account.Email
+		}
+		return result, ""
+	}
+	acc := p.Account
+	result.Email = acc.Email
+	result.Group = p.Group
+
+	// 自定义字段值与已有字段的类型不匹配时整行无效，不写入账号
+	if err := validateImportFields(tx, acc.CustomFields); err != nil {
+		result.Status = models.ImportInvalid
+		result.Reason = "custom fields: " + err.Error()
+		return result, ""
+	}
+
+	key := strings.ToLower(acc.Email)
+	if first, ok := im.seen[key]; ok {
+		result.Status = models.ImportDuplicate
+		result.Reason = fmt.Sprintf("duplicate of line %d", first)
+		return result, ""
+	}
+	im.seen[key] = p.Line
+
+	// 查找已存在的账号
+	existingID, deleted, err := findImportTarget(tx, acc.Email)
+	if err != nil {
+		result.Status = models.ImportFailed
+		result.Reason = err.Error()
+		return result, ""
+	}
+	if existingID != 0 && im.policy == models.ImportPolicySkip {
+		result.Status = models.ImportExisting
+		result.AccountID = existingID
+		result.Reason = "account already exists"
+		if deleted {
+			result.Reason = "account exists in recycle bin"
+		}
+		return result, ""
+	}
+
+	// 只更新凭据时不涉及分组，无需创建
+	var groupID int64
+	var createdGroup string
+	if existingID != 0 && im.policy == models.ImportPolicyCredentials {
+		result.Group = ""
+	} else {
+		var created bool
+		groupID, created, err = ensureGroup(tx, p.Group)
+		if err != nil {
+			result.Status = models.ImportFailed
+			result.Reason = "create group: " + err.Error()
+			return result, ""
+		}
+		if created {
+			createdGroup = p.Group
+		}
+	}
+
+	if existingID == 0 {
+		result.AccountID, err = insertImportedAccount(tx, acc, groupID)
+		result.Status = models.ImportCreated
+	} else {
+		result.AccountID = existingID
+		err = updateImportedAccount(tx, existingID, acc, groupID, im.policy)
+		result.Status = models.ImportUpdated
+	}
+	if err != nil {
+		result.Status = models.ImportFailed
+		result.Reason = err.Error()
+		if existingID == 0 {
+			result.AccountID = 0 // 插入的账号会被回滚
+		}
+	}
+	return result, createdGroup
+}
+
+// normalizeImportPolicy 校验重复账号处理策略，为空时返回默认的完全替换
+func normalizeImportPolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return models.ImportPolicyReplace, nil
+	case models.ImportPolicySkip, models.ImportPolicyCredentials,
+		models.ImportPolicyCredentialsGroup, models.ImportPolicyReplace:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid import policy: %s", policy)
+}
+
+// insertImportedAccount 插入新导入的账号
+//
+// 参数：
+//   - db: 数据库执行器
+//   - acc: 要写入的账号
+//   - groupID: 分组ID
+//
+// 返回值：
+//   - int64: 新账号ID
+//   - error: 写入失败时返回错误
+func insertImportedAccount(db dbExecutor, acc *models.Account, groupID int64) (int64, error) {
+	protocol := acc.Protocol
+	if protocol == "" {
+		protocol = models.ProtocolO2
+	}
+	res, err := db.Exec(`INSERT INTO accounts
+		(email, password, client_id, refresh_token, group_id, notes, status, protocol, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'active', ?, CURRENT_TIMESTAMP)`,
+		acc.Email, acc.Password, acc.ClientID, acc.RefreshToken, groupID, acc.Notes, protocol)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	// 写入自定义字段值（不存在的字段自动创建）
+	if len(acc.CustomFields) > 0 {
+		if err := importAccountFields(db, id, acc.CustomFields); err != nil {
+			return id, fmt.Errorf("custom fields: %w", err)
+		}
+	}
+	return id, nil
+}
+
+// updateImportedAccount 按策略原地更新已存在的账号
+//
+// 使用UPDATE而不是INSERT OR REPLACE，账号ID、标签保持不变；协议只在密码登录与OAuth2之间切换时变化
+// ClientID和RefreshToken都未变化时保留数据库中缓存的AccessToken，否则清除
+// 账号在回收站中时同时恢复
+//
+// 参数：
+//   - db: 数据库执行器
+//   - id: 已存在的账号ID
+//   - acc: 导入的账号数据
+//   - groupID: 导入指定的分组ID（credentials策略下不使用）
+//   - policy: 重复账号处理策略（不能为skip）
+//
+// 返回值：
+//   - error: 写入失败时返回错误
+func updateImportedAccount(db dbExecutor, id int64, acc *models.Account, groupID int64, policy string) error {
+	sets := []string{
+		"password = ?", "client_id = ?", "refresh_token = ?",
+		// SET中的右值引用的是更新前的列值
+		"access_token = CASE WHEN client_id = ? AND refresh_token = ? THEN access_token ELSE NULL END",
+		"token_expires_at = CASE WHEN client_id = ? AND refresh_token = ? THEN token_expires_at ELSE NULL END",
+		// 导入密码登录账号时切换为imap-password，已是密码登录的账号导入了OAuth2凭据时恢复为o2
+		"protocol = CASE WHEN ? = ? THEN ? WHEN protocol = ? THEN ? ELSE protocol END",
+		"status = 'active'", "last_error = ''", "deleted_at = NULL", "updated_at = CURRENT_TIMESTAMP",
+	}
+	args := []interface{}{
+		acc.Password, acc.ClientID, acc.RefreshToken,
+		acc.ClientID, acc.RefreshToken, acc.ClientID, acc.RefreshToken,
+		acc.Protocol, models.ProtocolIMAPPassword, models.ProtocolIMAPPassword, models.ProtocolIMAPPassword, models.ProtocolO2,
+	}
+	if policy == models.ImportPolicyCredentialsGroup || policy == models.ImportPolicyReplace {
+		sets = append(sets, "group_id = ?")
+		args = append(args, groupID)
+	}
+	if policy == models.ImportPolicyReplace {
+		sets = append(sets, "notes = ?")
+		args = append(args, acc.Notes)
+	}
+	args = append(args, id)
+	if _, err := db.Exec("UPDATE accounts SET "+strings.Join(sets, ", ")+" WHERE id = ?", args...); err != nil {
+		return err
+	}
+
+	if policy != models.ImportPolicyReplace {
+		return nil
+	}
+	// 完全替换：自定义字段以导入数据为准
+	if _, err := db.Exec("DELETE FROM account_field_values WHERE account_id = ?", id); err != nil {
+		return err
+	}
+	if len(acc.CustomFields) > 0 {
+		if err := importAccountFields(db, id, acc.CustomFields); err != nil {
+			return fmt.Errorf("custom fields: %w", err)
+		}
+	}
+	return nil
 }
 
 // ensureGroup 确保分组存在
@@ -168,24 +825,32 @@ func (s *AccountService) Import(text string) (int, error) {
 // 如果分组已存在则返回其ID，否则创建新分组
 //
 // 参数：
+//   - db: 数据库执行器
 //   - name: 分组名称
 //
 // 返回值：
 //   - int64: 分组ID
-func (s *AccountService) ensureGroup(name string) int64 {
+//   - bool: 是否为本次新建的分组
+//   - error: 创建分组失败时返回错误
+func ensureGroup(db dbExecutor, name string) (int64, bool, error) {
 	var id int64
 	// 先查询是否存在
-	err := database.DB.QueryRow("SELECT id FROM groups WHERE name = ?", name).Scan(&id)
+	err := db.QueryRow("SELECT id FROM groups WHERE name = ?", name).Scan(&id)
 	if err == nil {
-		return id // 已存在，返回ID
+		return id, false, nil // 已存在，返回ID
 	}
 	// 不存在，创建新分组
-	res, _ := database.DB.Exec("INSERT INTO groups (name) VALUES (?)", name)
-	id, _ = res.LastInsertId()
-	return id
+	res, err := db.Exec("INSERT INTO groups (name) VALUES (?)", name)
+	if err != nil {
+		return 0, false, err
+	}
+	id, err = res.LastInsertId()
+	return id, err == nil, err
 }
 
-// Delete 删除账号
+// Delete 删除账号（移入回收站）
+//
+// 软删除：仅设置deleted_at，账号的凭据、标签和自定义字段均保留，可通过Restore恢复
 //
 // 参数：
 //   - id: 要删除的账号ID
@@ -193,10 +858,114 @@ func (s *AccountService) ensureGroup(name string) int64 {
 // 返回值：
 //   - error: 删除失败时返回错误
 func (s *AccountService) Delete(id int64) error {
-	_, err := database.DB.Exec("DELETE FROM accounts WHERE id = ?", id)
+	_, err := database.DB.Exec("UPDATE accounts SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", id)
 	return err
 }
 
+// Restore 从回收站恢复账号
+//
+// 参数：
+//   - ids: 要恢复的账号ID列表
+//
+// 返回值：
+//   - int: 实际恢复的账号数量
+//   - error: 更新失败时返回错误
+func (s *AccountService) Restore(ids []int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	res, err := database.DB.Exec(`UPDATE accounts SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE deleted_at IS NOT NULL AND id IN (`+placeholders(len(ids))+")", args...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// Purge 彻底删除回收站中的账号
+//
+// 只会删除已在回收站中的账号，同时清理标签关联和自定义字段值，不可恢复
+//
+// 参数：
+//   - ids: 要彻底删除的账号ID列表
+//
+// 返回值：
+//   - int: 实际删除的账号数量
+//   - error: 删除失败时返回错误
+func (s *AccountService) Purge(ids []int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return s.purgeWhere("id IN ("+placeholders(len(ids))+")", args...)
+}
+
+// PurgeAll 清空回收站
+//
+// 返回值：
+//   - int: 实际删除的账号数量
+//   - error: 删除失败时返回错误
+func (s *AccountService) PurgeAll() (int, error) {
+	return s.purgeWhere("1 = 1")
+}
+
+// PurgeExpired 彻底删除在回收站中超过指定天数的账号
+//
+// 应用启动时调用，实现回收站自动清理
+//
+// 参数：
+//   - days: 保留天数，<=0表示不清理
+//
+// 返回值：
+//   - int: 实际删除的账号数量
+//   - error: 删除失败时返回错误
+func (s *AccountService) PurgeExpired(days int) (int, error) {
+	if days <= 0 {
+		return 0, nil
+	}
+	return s.purgeWhere("deleted_at <= datetime('now', ?)", fmt.Sprintf("-%d days", days))
+}
+
+// purgeWhere 彻底删除满足条件且已在回收站中的账号
+//
+// 在一个事务中同时清理标签关联和自定义字段值（SQLite默认未开启外键约束）
+//
+// 参数：
+//   - where: 附加的SQL条件（作用于accounts表）
+//   - args: 条件参数
+//
+// 返回值：
+//   - int: 实际删除的账号数量
+//   - error: 删除失败时返回错误
+func (s *AccountService) purgeWhere(where string, args ...interface{}) (int, error) {
+	cond := "deleted_at IS NOT NULL AND " + where
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM account_tags WHERE account_id IN (SELECT id FROM accounts WHERE "+cond+")", args...); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM account_field_values WHERE account_id IN (SELECT id FROM accounts WHERE "+cond+")", args...); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec("DELETE FROM accounts WHERE "+cond, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), tx.Commit()
+}
+
 // UpdateToken 更新账号的Token信息
 //
 // Token刷新成功后调用，同时更新状态为active并清除错误信息
@@ -246,28 +1015,66 @@ func (s *AccountService) UpdateGroup(accountID, groupID int64) error {
 	return err
 }
 
+// UpdateNotes 更新账号备注
+//
+// 参数：
+//   - id: 账号ID
+//   - notes: 备注内容（自由文本）
+//
+// 返回值：
+//   - error: 更新失败时返回错误
+func (s *AccountService) UpdateNotes(id int64, notes string) error {
+	_, err := database.DB.Exec("UPDATE accounts SET notes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", notes, id)
+	return err
+}
+
+// Emails 批量查询账号邮箱地址
+//
+// 包含回收站中的账号，用于审计记录等需要描述操作对象的场景
+//
+// 参数：
+//   - ids: 账号ID列表
+//
+// 返回值：
+//   - []string: 邮箱地址列表（与存在的账号对应，顺序与ids一致）
+func (s *AccountService) Emails(ids []int64) []string {
+	emails := make([]string, 0, len(ids))
+	for _, id := range ids {
+		var email string
+		if err := database.DB.QueryRow("SELECT email FROM accounts WHERE id = ?", id).Scan(&email); err == nil {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
 // Count 获取账号总数
 //
 // 返回值：
-//   - int: 数据库中的账号总数
+//   - int: 数据库中的账号总数（不含回收站中的账号）
 func (s *AccountService) Count() int {
 	var count int
-	database.DB.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&count)
+	database.DB.QueryRow("SELECT COUNT(*) FROM accounts WHERE deleted_at IS NULL").Scan(&count)
 	return count
 }
 
-// DeleteByGroup 删除指定分组下的所有账号
+// DeleteByGroup 删除指定分组下的所有账号（移入回收站）
 //
-// 用于清空分组功能
+// 用于清空分组功能，误操作后可在回收站中恢复
 //
 // 参数：
 //   - groupID: 分组ID
 //
 // 返回值：
+//   - int: 移入回收站的账号数量
 //   - error: 删除失败时返回错误
-func (s *AccountService) DeleteByGroup(groupID int64) error {
-	_, err := database.DB.Exec("DELETE FROM accounts WHERE group_id = ?", groupID)
-	return err
+func (s *AccountService) DeleteByGroup(groupID int64) (int, error) {
+	res, err := database.DB.Exec("UPDATE accounts SET deleted_at = CURRENT_TIMESTAMP WHERE group_id = ? AND deleted_at IS NULL", groupID)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
 }
 
 // UpdateProtocol 更新账号的邮件访问协议类型
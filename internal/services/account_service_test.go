@@ -0,0 +1,63 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"outlook-mail-manager/internal/database"
+	"outlook-mail-manager/internal/models"
+)
+
+// TestImportRollsBackFailedLine 写入失败的行整体回滚，不留下半导入的账号、分组或字段值
+func TestImportRollsBackFailedLine(t *testing.T) {
+	openTestDB(t)
+	// 写入值为boom的自定义字段时失败（模拟账号插入之后的写入错误）
+	if _, err := database.DB.Exec(`CREATE TRIGGER fail_field_value BEFORE INSERT ON account_field_values
+		WHEN NEW.value = 'boom' BEGIN SELECT RAISE(ABORT, 'boom'); END`); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+	s := NewAccountService()
+
+	report, err := s.Import("a@example.com----pw------------新组----备用=boom\n"+
+		"b@example.com----pw------------老组----备用=fine", models.ImportOptions{})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if report.Created != 1 || report.Failed != 1 || !reflect.DeepEqual(report.GroupsCreated, []string{"老组"}) {
+		t.Fatalf("report = %+v", report)
+	}
+	if line := report.Lines[0]; line.Status != models.ImportFailed || line.AccountID != 0 {
+		t.Errorf("failed line = %+v", line)
+	}
+	accounts := listAccounts(t)
+	if len(accounts) != 1 || accounts[0].Email != "b@example.com" {
+		t.Fatalf("accounts = %+v, want only b@example.com", accounts)
+	}
+	var groups int
+	database.DB.QueryRow("SELECT COUNT(*) FROM groups WHERE name = '新组'").Scan(&groups)
+	if groups != 0 {
+		t.Errorf("group of the failed line was kept")
+	}
+
+	// 更新已存在的账号失败时保留原有数据
+	report, err = s.Import("b@example.com----changed------------老组----备用=boom", models.ImportOptions{})
+	if err != nil || report.Failed != 1 {
+		t.Fatalf("update import = %+v, %v", report, err)
+	}
+	b, err := s.GetByID(accounts[0].ID)
+	if err != nil {
+		t.Fatalf("get account: %v", err)
+	}
+	if b.Password != "pw" {
+		t.Errorf("password = %q after failed update, want unchanged", b.Password)
+	}
+	if fields := NewFieldService().AccountFields(b.ID); fields["备用"] != "fine" {
+		t.Errorf("custom fields = %v after failed update, want unchanged", fields)
+	}
+
+	// 失败的行可以重新导入为新账号
+	report, err = s.Import("a@example.com----pw------------新组----备用=ok", models.ImportOptions{})
+	if err != nil || report.Created != 1 {
+		t.Errorf("re-import = %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,248 @@
+// Package services 业务服务层
+//
+// audit_service.go 操作审计日志服务
+//
+// 功能说明：
+// - 追加写入审计记录（只追加，不提供修改和删除）
+// - 按操作类型、对象、时间、关键字筛选查询
+// - 导出审计日志（CSV/JSON）
+package services
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/user"
+	"outlook-mail-manager/internal/database"
+	"outlook-mail-manager/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 审计操作类型
+//
+// 命名规则：对象类型.动作，便于按前缀筛选
+const (
+	AuditAccountImport   = "account.import"   // 导入账号
+	AuditAccountDelete   = "account.delete"   // 删除账号（移入回收站）
+	AuditAccountRestore  = "account.restore"  // 从回收站恢复账号
+	AuditAccountPurge    = "account.purge"    // 彻底删除账号
+	AuditAccountMove     = "account.move"     // 移动账号到其他分组
+	AuditAccountProtocol = "account.protocol" // 账号协议切换（如O2回退到IMAP）
+	AuditAccountStatus   = "account.status"   // 账号状态变化（Token刷新成功/失败）
+	AuditAccountNotes    = "account.notes"    // 修改账号备注
+	AuditAccountFields   = "account.fields"   // 修改账号自定义字段
+	AuditAccountTag      = "account.tag"      // 为账号打标签
+	AuditAccountUntag    = "account.untag"    // 移除账号标签
+	AuditGroupCreate     = "group.create"     // 创建分组
+	AuditGroupDelete     = "group.delete"     // 删除分组
+	AuditGroupClear      = "group.clear"      // 清空分组
+	AuditTagCreate       = "tag.create"       // 创建标签
+	AuditTagUpdate       = "tag.update"       // 修改标签
+	AuditTagDelete       = "tag.delete"       // 删除标签
+	AuditFieldCreate     = "field.create"     // 创建自定义字段
+	AuditFieldUpdate     = "field.update"     // 修改自定义字段
+	AuditFieldDelete     = "field.delete"     // 删除自定义字段
+	AuditSettingUpdate   = "setting.update"   // 修改设置
+	AuditTemplateCreate  = "template.create"  // 创建导入模板
+	AuditTemplateUpdate  = "template.update"  // 修改导入模板
+	AuditTemplateDelete  = "template.delete"  // 删除导入模板
+	AuditMessageDelete   = "message.delete"   // 删除邮件（对象为邮件所属账号）
+	AuditMessageMove     = "message.move"     // 移动邮件到其他文件夹（对象为邮件所属账号）
+)
+
+// 审计对象类型
+const (
+	AuditTargetAccount  = "account"
+	AuditTargetGroup    = "group"
+	AuditTargetTag      = "tag"
+	AuditTargetField    = "field"
+	AuditTargetSetting  = "setting"
+	AuditTargetTemplate = "template"
+)
+
+// AuditActorSystem 后台任务（如启动时自动清理回收站）的操作者名称
+const AuditActorSystem = "system"
+
+// auditTimeLayout 审计时间存储格式（UTC），与SQLite的CURRENT_TIMESTAMP一致，便于字符串比较
+const auditTimeLayout = "2006-01-02 15:04:05"
+
+// AuditService 操作审计日志服务
+type AuditService struct {
+	actor string // 当前操作者，创建服务时确定
+}
+
+// NewAuditService 创建审计日志服务实例
+//
+// 操作者取当前系统用户名和主机名（用户名@主机名），获取失败时为"unknown"
+//
+// 返回值：
+//   - *AuditService: 服务实例
+func NewAuditService() *AuditService {
+	actor := "unknown"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		actor = u.Username
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		actor += "@" + host
+	}
+	return &AuditService{actor: actor}
+}
+
+// Record 追加一条审计记录
+//
+// 写入失败只记录日志，不影响业务操作本身
+//
+// 参数：
+//   - action: 操作类型（AuditXxx常量）
+//   - targetType: 操作对象类型（AuditTargetXxx常量）
+//   - targetID: 操作对象ID，批量操作时为0
+//   - target: 操作对象描述（如邮箱地址）
+//   - summary: 操作摘要
+//   - before: 操作前状态（任意可JSON序列化的值，nil表示无）
+//   - after: 操作后状态（同上）
+func (s *AuditService) Record(action, targetType string, targetID int64, target, summary string, before, after interface{}) {
+	s.record(s.actor, action, targetType, targetID, target, summary, before, after)
+}
+
+// RecordSystem 以系统身份追加一条审计记录
+//
+// 用于非用户直接触发的操作（如启动时自动清理回收站）
+func (s *AuditService) RecordSystem(action, targetType string, targetID int64, target, summary string, before, after interface{}) {
+	s.record(AuditActorSystem, action, targetType, targetID, target, summary, before, after)
+}
+
+// record 写入审计记录
+func (s *AuditService) record(actor, action, targetType string, targetID int64, target, summary string, before, after interface{}) {
+	_, err := database.DB.Exec(`INSERT INTO audit_logs
+		(action, target_type, target_id, target, summary, before, after, actor, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		action, targetType, targetID, target, summary, toAuditJSON(before), toAuditJSON(after), actor,
+		time.Now().UTC().Format(auditTimeLayout))
+	if err != nil {
+		log.Printf("[Audit] 写入审计记录失败: action=%s, error=%v", action, err)
+	}
+}
+
+// List 查询审计日志
+//
+// 按时间倒序返回（最新的在前）
+//
+// 参数：
+//   - filter: 筛选条件
+//
+// 返回值：
+//   - []models.AuditLog: 审计日志列表
+//   - error: 数据库查询错误
+func (s *AuditService) List(filter models.AuditFilter) ([]models.AuditLog, error) {
+	query := `SELECT id, action, target_type, COALESCE(target_id,0), COALESCE(target,''), COALESCE(summary,''),
+		COALESCE(before,''), COALESCE(after,''), COALESCE(actor,''), created_at FROM audit_logs`
+	var conds []string
+	var args []interface{}
+	if filter.Action != "" {
+		// 以"."结尾时按前缀匹配
+		if strings.HasSuffix(filter.Action, ".") {
+			conds = append(conds, `action LIKE ? ESCAPE '\'`)
+			args = append(args, escapeLike(filter.Action)+"%")
+		} else {
+			conds = append(conds, "action = ?")
+			args = append(args, filter.Action)
+		}
+	}
+	if filter.TargetType != "" {
+		conds = append(conds, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		conds = append(conds, "target_id = ?")
+		args = append(args, filter.TargetID)
+	}
+	if kw := strings.TrimSpace(filter.Keyword); kw != "" {
+		like := "%" + escapeLike(kw) + "%"
+		conds = append(conds, `(target LIKE ? ESCAPE '\' OR summary LIKE ? ESCAPE '\')`)
+		args = append(args, like, like)
+	}
+	if filter.Since != "" {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if filter.Until != "" {
+		conds = append(conds, "created_at < ?")
+		args = append(args, filter.Until)
+	}
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []models.AuditLog
+	for rows.Next() {
+		var l models.AuditLog
+		var createdAt sql.NullTime
+		if err := rows.Scan(&l.ID, &l.Action, &l.TargetType, &l.TargetID, &l.Target, &l.Summary,
+			&l.Before, &l.After, &l.Actor, &createdAt); err != nil {
+			continue // 跳过解析失败的行
+		}
+		l.CreatedAt = createdAt.Time
+		logs = append(logs, l)
+	}
+	return logs, nil
+}
+
+// Export 导出审计日志
+//
+// 参数：
+//   - w: 输出目标
+//   - logs: 要导出的审计日志
+//   - format: 导出格式，"json"或"csv"（默认）
+//
+// 返回值：
+//   - error: 写入失败时返回错误
+func (s *AuditService) Export(w io.Writer, logs []models.AuditLog, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if logs == nil {
+			logs = []models.AuditLog{}
+		}
+		return enc.Encode(logs)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "time", "actor", "action", "targetType", "targetId", "target", "summary", "before", "after"})
+	for _, l := range logs {
+		cw.Write([]string{
+			strconv.FormatInt(l.ID, 10), l.CreatedAt.Format(auditTimeLayout), l.Actor, l.Action,
+			l.TargetType, strconv.FormatInt(l.TargetID, 10), l.Target, l.Summary, l.Before, l.After,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// toAuditJSON 将审计状态序列化为JSON字符串，nil返回空字符串
+func toAuditJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+
+	"outlook-mail-manager/internal/models"
+)
+
+func TestAuditListKeywordIsLiteral(t *testing.T) {
+	openTestDB(t)
+	s := NewAuditService()
+	s.Record(AuditAccountDelete, AuditTargetAccount, 1, "a_b@example.com", "删除账号", nil, nil)
+	s.Record(AuditAccountDelete, AuditTargetAccount, 2, "axb@example.com", "删除账号", nil, nil)
+	s.Record(AuditSettingUpdate, AuditTargetSetting, 0, "quota", "上限改为100%", nil, nil)
+	s.Record(AuditSettingUpdate, AuditTargetSetting, 0, "quota", "上限改为1000", nil, nil)
+
+	tests := []struct {
+		filter models.AuditFilter
+		want   []string // 按时间倒序的target或summary
+	}{
+		{models.AuditFilter{Keyword: "a_b"}, []string{"a_b@example.com"}},
+		{models.AuditFilter{Keyword: "_"}, []string{"a_b@example.com"}},
+		{models.AuditFilter{Keyword: "100%"}, []string{"上限改为100%"}},
+		{models.AuditFilter{Keyword: "%"}, []string{"上限改为100%"}},
+		{models.AuditFilter{Keyword: `\`}, nil},
+		{models.AuditFilter{Action: "account."}, []string{"axb@example.com", "a_b@example.com"}},
+		{models.AuditFilter{Action: "account_"}, nil},
+	}
+	for _, tt := range tests {
+		logs, err := s.List(tt.filter)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", tt.filter, err)
+		}
+		var got []string
+		for _, l := range logs {
+			if l.TargetType == AuditTargetSetting {
+				got = append(got, l.Summary)
+			} else {
+				got = append(got, l.Target)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("List(%+v) = %q, want %q", tt.filter, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("List(%+v) = %q, want %q", tt.filter, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package services 业务服务层
+//
+// charset.go 邮件字符集转换
+//
+// 功能说明：
+// - 按声明的charset将正文和RFC 2047编码的邮件头转换为UTF-8（GB2312/GBK/GB18030、Big5、Shift_JIS、ISO-8859-x、Windows-125x等）
+// - 字符集名称按WHATWG编码标准解析（如gb2312按GBK解码，iso-8859-1按windows-1252解码），兼容实际邮件中的常见误标
+// - 未声明字符集或声明与内容不符时，在常见的中日文字符集中检测最合适的一个，最后按windows-1252解码（不会失败）
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// htmlMetaCharsetRe 匹配HTML正文中 <meta charset="..."> 或 <meta ... content="text/html; charset=..."> 声明的字符集
+var htmlMetaCharsetRe = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_\-:.]+)`)
+
+// charsetCandidate 未声明字符集时参与检测的编码
+type charsetCandidate struct {
+	enc    encoding.Encoding
+	common [2]byte // 常用汉字（一级字）在该编码中的首字节范围
+	kana   bool    // 是否为日文编码（假名是正常文本）
+}
+
+// detectCandidates 未声明字符集时依次尝试的编码（按中文邮件的常见程度排序，得分相同时靠前的优先）
+var detectCandidates = []charsetCandidate{
+	{enc: simplifiedchinese.GB18030, common: [2]byte{0xB0, 0xD7}},     // GB2312一级汉字；GB18030是GB2312和GBK的超集
+	{enc: traditionalchinese.Big5, common: [2]byte{0xA4, 0xC6}},       // Big5常用字
+	{enc: japanese.ShiftJIS, common: [2]byte{0x88, 0x9F}, kana: true}, // JIS第一水准汉字
+}
+
+// lookupCharset 按名称查找字符集
+//
+// 返回值：
+//   - encoding.Encoding: 字符集编码，UTF-8和US-ASCII返回nil（无需转换）
+//   - error: 无法识别的字符集名称
+func lookupCharset(charset string) (encoding.Encoding, error) {
+	name := strings.ToLower(strings.Trim(strings.TrimSpace(charset), `"'`))
+	switch name {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return nil, nil
+	}
+	if enc, err := htmlindex.Get(name); err == nil {
+		if enc == encoding.Nop || enc == encoding.Replacement {
+			return nil, fmt.Errorf("unsupported charset: %s", charset)
+		}
+		return enc, nil
+	}
+	// WHATWG未收录的名称（如ISO-8859-11、部分IANA别名）
+	if enc, err := ianaindex.MIME.Encoding(name); err == nil && enc != nil {
+		return enc, nil
+	}
+	return nil, fmt.Errorf("unsupported charset: %s", charset)
+}
+
+// charsetReader 供mime.WordDecoder使用的字符集转换函数
+//
+// 参数：
+//   - charset: 声明的字符集名称
+//   - input: 该字符集编码的内容
+//
+// 返回值：
+//   - io.Reader: UTF-8内容
+//   - error: 无法识别的字符集名称
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := lookupCharset(charset)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return input, nil
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// decodeCharset 将文本内容从声明的字符集转换为UTF-8
+//
+// 参数：
+//   - data: 原始字节（已解码传输编码）
+//   - charset: 声明的字符集，为空表示未声明
+//
+// 返回值：
+//   - string: UTF-8文本
+func decodeCharset(data []byte, charset string) string {
+	enc, err := lookupCharset(charset)
+	if err == nil && enc != nil {
+		if out, err := enc.NewDecoder().Bytes(data); err == nil {
+			return string(out)
+		}
+	}
+	// 声明为UTF-8（或未声明、无法识别）且内容确实是UTF-8
+	if utf8.Valid(data) {
+		return string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	}
+	return detectAndDecode(data)
+}
+
+// detectAndDecode 在常见字符集中检测最合适的一个并解码
+//
+// 逐个尝试detectCandidates，按解码结果的charsetScore选择得分最高的；
+// 都不合适时按windows-1252解码
+func detectAndDecode(data []byte) string {
+	best, bestScore := "", 0
+	for _, c := range detectCandidates {
+		out, err := c.enc.NewDecoder().Bytes(data)
+		if err != nil {
+			continue
+		}
+		if score := charsetScore(string(out), c); score > bestScore {
+			best, bestScore = string(out), score
+		}
+	}
+	if bestScore > 0 {
+		return best
+	}
+	out, _ := charmap.Windows1252.NewDecoder().Bytes(data)
+	return string(out)
+}
+
+// charsetScore 评估按候选编码解码的结果是否像正常文本
+//
+// 常用汉字加分最多，其他汉字少量加分；日文编码中的假名加分，中文编码中的假名扣分；
+// 替换字符（U+FFFD）、私用区字符、半角片假名和控制字符扣分（按错误编码解码时常见）
+func charsetScore(s string, c charsetCandidate) int {
+	score := 0
+	encoder := c.enc.NewEncoder()
+	for _, r := range s {
+		switch {
+		case r == utf8.RuneError || unicode.Is(unicode.Co, r) || r >= 0xFF61 && r <= 0xFF9F:
+			score -= 10
+		case r < 0x20 && r != '\r' && r != '\n' && r != '\t':
+			score -= 5
+		case unicode.Is(unicode.Han, r):
+			score++
+			if b, err := encoder.String(string(r)); err == nil && len(b) == 2 && b[0] >= c.common[0] && b[0] <= c.common[1] {
+				score += 2
+			}
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			if c.kana {
+				score += 3
+			} else {
+				score -= 3
+			}
+		}
+	}
+	return score
+}
+
+// htmlMetaCharset 读取HTML正文中meta标签声明的字符集（只检查开头部分）
+func htmlMetaCharset(data []byte) string {
+	if len(data) > 2048 {
+		data = data[:2048]
+	}
+	if m := htmlMetaCharsetRe.FindSubmatch(data); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// mustEncode 将UTF-8文本编码为指定字符集的字节
+func mustEncode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("encode %q: %v", s, err)
+	}
+	return b
+}
+
+func TestDetectAndDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  encoding.Encoding
+		text string
+	}{
+		{"GBK subject", simplifiedchinese.GBK, "您的账户安全验证码"},
+		{"GBK body with ASCII", simplifiedchinese.GBK, "尊敬的用户：\r\n您好！您的订单已发货，请注意查收。\r\nOrder #12345"},
+		{"GB18030 extension", simplifiedchinese.GB18030, "会议通知：明天上午十点在三楼会议室"},
+		{"Big5 subject", traditionalchinese.Big5, "您的帳戶安全驗證碼"},
+		{"Big5 body", traditionalchinese.Big5, "親愛的會員您好：\r\n感謝您的訂購，商品將於三個工作天內寄出。"},
+		{"Shift_JIS", japanese.ShiftJIS, "お問い合わせありがとうございます。担当者より連絡いたします。"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := mustEncode(t, tt.enc, tt.text)
+			if got := detectAndDecode(data); got != tt.text {
+				t.Errorf("detectAndDecode = %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestDetectAndDecodeFallback(t *testing.T) {
+	// 不像中日文的字节按windows-1252解码
+	data := []byte("Caf\xe9 \x80 na\xefve")
+	if got, want := detectAndDecode(data), "Café € naïve"; got != want {
+		t.Errorf("detectAndDecode = %q, want %q", got, want)
+	}
+}
+
+func TestDecodeCharset(t *testing.T) {
+	gbk := mustEncode(t, simplifiedchinese.GBK, "验证码")
+	big5 := mustEncode(t, traditionalchinese.Big5, "驗證碼")
+	// 未声明字符集时需要足够长的文本才能区分GBK和Big5
+	big5Text := "您的帳戶驗證碼已發送，請於十分鐘內使用。"
+	big5Long := mustEncode(t, traditionalchinese.Big5, big5Text)
+	tests := []struct {
+		name    string
+		data    []byte
+		charset string
+		want    string
+	}{
+		{"declared gb2312 decodes as GBK", gbk, "gb2312", "验证码"},
+		{"declared big5", big5, "Big5", "驗證碼"},
+		{"quoted charset name", gbk, `"GBK"`, "验证码"},
+		{"undeclared GBK", gbk, "", "验证码"},
+		{"undeclared Big5", big5Long, "", big5Text},
+		{"mislabeled as UTF-8", gbk, "utf-8", "验证码"},
+		{"unknown charset", big5Long, "x-unknown", big5Text},
+		{"UTF-8 with BOM", []byte("\xef\xbb\xbf验证码"), "", "验证码"},
+		{"iso-8859-1 decodes as windows-1252", []byte("\x93quoted\x94"), "iso-8859-1", "“quoted”"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeCharset(tt.data, tt.charset); got != tt.want {
+				t.Errorf("decodeCharset = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Package services 业务服务层
+//
+// export_service.go 账号导出服务
+//
+// 功能说明：
+// - 将账号按四横线文本、Tab文本、CSV、JSON格式逐条写出（流式，不在内存中拼接整个文件）
+// - 口令加密的账号包（用于把账号交给他人）
+// - 支持字段选择（如不导出RefreshToken）
+// - 默认字段导出的结果可以按相同格式重新导入
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"outlook-mail-manager/internal/models"
+	"outlook-mail-manager/internal/utils"
+	"sort"
+	"strings"
+)
+
+// defaultExportFields 默认导出的基本字段（可重新导入）
+var defaultExportFields = []string{
+	models.TemplateFieldEmail, models.TemplateFieldPassword, models.TemplateFieldClientID,
+	models.TemplateFieldRefreshToken, models.TemplateFieldGroup,
+}
+
+// exportAccountJSON 默认字段导出为JSON时的账号结构
+type exportAccountJSON struct {
+	Email        string            `json:"email"`
+	Password     string            `json:"password"`
+	ClientID     string            `json:"client_id"`
+	RefreshToken string            `json:"refresh_token"`
+	Group        string            `json:"group"`
+	Notes        string            `json:"notes,omitempty"`
+	CustomFields map[string]string `json:"customFields,omitempty"`
+}
+
+// ExportService 账号导出服务
+type ExportService struct{}
+
+// NewExportService 创建账号导出服务实例
+//
+// 返回值：
+//   - *ExportService: 服务实例
+func NewExportService() *ExportService {
+	return &ExportService{}
+}
+
+// Export 导出账号
+//
+// 未指定字段时：
+// - line/tab格式：邮箱、密码、ClientID、RefreshToken、分组，之后追加"notes=备注"和"字段名=值"
+// - csv格式：基本字段、notes列和每个自定义字段一列
+// - json格式：基本字段、notes和customFields对象
+// - bundle格式：与json格式相同的内容，用opts.Passphrase加密（整体加密，需要先在内存中生成）
+//
+// 指定字段时只按顺序输出这些字段，字段名可以是TemplateFieldXxx、ExportFieldXxx或自定义字段名
+//
+// 参数：
+//   - w: 输出目标
+//   - accounts: 要导出的账号
+//   - opts: 导出选项
+//
+// 返回值：
+//   - error: 格式无效或写入失败时返回错误
+func (s *ExportService) Export(w io.Writer, accounts []models.Account, opts models.ExportOptions) error {
+	format, err := normalizeExportFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case models.ExportFormatCSV:
+		return exportCSV(w, accounts, opts.Fields)
+	case models.ExportFormatJSON:
+		return exportJSON(w, accounts, opts.Fields)
+	case models.ExportFormatBundle:
+		return exportBundle(w, accounts, opts)
+	}
+
+	sep := "----"
+	if format == models.ExportFormatTab {
+		sep = "\t"
+	}
+	for _, acc := range accounts {
+		var parts []string
+		if len(opts.Fields) == 0 {
+			parts = exportValues(&acc, defaultExportFields)
+			if acc.Notes != "" {
+				parts = append(parts, "notes="+singleLine(acc.Notes))
+			}
+			for _, name := range sortedKeys(acc.CustomFields) {
+				parts = append(parts, name+"="+singleLine(acc.CustomFields[name]))
+			}
+		} else {
+			parts = exportValues(&acc, opts.Fields)
+			for i := range parts {
+				parts[i] = singleLine(parts[i])
+			}
+		}
+		if _, err := io.WriteString(w, strings.Join(parts, sep)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportExtension 返回导出格式对应的文件扩展名（不含点）
+func ExportExtension(format string) string {
+	switch format {
+	case models.ExportFormatCSV:
+		return "csv"
+	case models.ExportFormatJSON:
+		return "json"
+	case models.ExportFormatBundle:
+		return "ombundle"
+	}
+	return "txt"
+}
+
+// normalizeExportFormat 校验导出格式，为空时返回line
+func normalizeExportFormat(format string) (string, error) {
+	switch format {
+	case "":
+		return models.ExportFormatLine, nil
+	case models.ExportFormatLine, models.ExportFormatTab, models.ExportFormatCSV, models.ExportFormatJSON,
+		models.ExportFormatBundle:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid export format: %s", format)
+}
+
+// exportCSV 以带表头的CSV格式导出
+func exportCSV(w io.Writer, accounts []models.Account, fields []string) error {
+	if len(fields) == 0 {
+		fields = append(append([]string{}, defaultExportFields...), models.TemplateFieldNotes)
+		fields = append(fields, customFieldNames(accounts)...)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fields); err != nil {
+		return err
+	}
+	for _, acc := range accounts {
+		if err := cw.Write(exportValues(&acc, fields)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportJSON 以JSON对象数组格式导出，逐个对象写出
+func exportJSON(w io.Writer, accounts []models.Account, fields []string) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, acc := range accounts {
+		var v interface{}
+		if len(fields) == 0 {
+			v = exportAccountJSON{
+				Email: acc.Email, Password: acc.Password, ClientID: acc.ClientID, RefreshToken: acc.RefreshToken,
+				Group: acc.GroupName, Notes: acc.Notes, CustomFields: acc.CustomFields,
+			}
+		} else {
+			obj := make(map[string]string, len(fields))
+			values := exportValues(&acc, fields)
+			for j, f := range fields {
+				obj[f] = values[j]
+			}
+			v = obj
+		}
+		data, err := json.MarshalIndent(v, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		prefix := ",\n  "
+		if i == 0 {
+			prefix = "\n  "
+		}
+		if _, err := io.WriteString(w, prefix+string(data)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
+
+// exportBundle 以口令加密的账号包格式导出
+func exportBundle(w io.Writer, accounts []models.Account, opts models.ExportOptions) error {
+	if opts.Passphrase == "" {
+		return fmt.Errorf("passphrase is required for bundle export")
+	}
+	var buf bytes.Buffer
+	if err := exportJSON(&buf, accounts, opts.Fields); err != nil {
+		return err
+	}
+	sealed, err := utils.SealBundle(buf.Bytes(), opts.Passphrase)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, sealed)
+	return err
+}
+
+// exportValues 按字段顺序取出账号的字段值
+func exportValues(acc *models.Account, fields []string) []string {
+	values := make([]string, len(fields))
+	for i, f := range fields {
+		switch f {
+		case models.TemplateFieldEmail:
+			values[i] = acc.Email
+		case models.TemplateFieldPassword:
+			values[i] = acc.Password
+		case models.TemplateFieldClientID:
+			values[i] = acc.ClientID
+		case models.TemplateFieldRefreshToken:
+			values[i] = acc.RefreshToken
+		case models.TemplateFieldGroup:
+			values[i] = acc.GroupName
+		case models.TemplateFieldNotes:
+			values[i] = acc.Notes
+		case models.ExportFieldStatus:
+			values[i] = acc.Status
+		case models.ExportFieldProtocol:
+			values[i] = acc.Protocol
+		case models.ExportFieldTags:
+			names := make([]string, len(acc.Tags))
+			for j, t := range acc.Tags {
+				names[j] = t.Name
+			}
+			values[i] = strings.Join(names, ",")
+		default:
+			values[i] = acc.CustomFields[f]
+		}
+	}
+	return values
+}
+
+// customFieldNames 返回账号中出现过的所有自定义字段名（排序）
+func customFieldNames(accounts []models.Account) []string {
+	seen := make(map[string]string)
+	for _, acc := range accounts {
+		for name := range acc.CustomFields {
+			seen[name] = ""
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// sortedKeys 返回map的键（排序），保证导出结果稳定
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// singleLine 将多行文本合并为一行（文本行格式中换行会破坏行结构）
+func singleLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+
+	"outlook-mail-manager/internal/database"
+	"outlook-mail-manager/internal/models"
+)
+
+// openTestDB 在临时目录中初始化数据库，测试结束时关闭
+func openTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	if err := database.Init(); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	t.Cleanup(func() { database.DB.Close() })
+}
+
+// listAccounts 返回数据库中的所有账号
+func listAccounts(t *testing.T) []models.Account {
+	t.Helper()
+	accounts, err := NewAccountService().List(models.AccountFilter{})
+	if err != nil {
+		t.Fatalf("list accounts: %v", err)
+	}
+	return accounts
+}
+
+// TestExportImportPasswordAccount 默认字段导出的密码登录账号可以按相同格式重新导入
+func TestExportImportPasswordAccount(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{models.ExportFormatLine, "p@example.com----secret------------客户\n"},
+		{models.ExportFormatTab, "p@example.com\tsecret\t\t\t客户\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			openTestDB(t)
+			report, err := NewAccountService().Import("p@example.com----secret----客户", models.ImportOptions{})
+			if err != nil || report.Created != 1 {
+				t.Fatalf("import = %+v, %v", report, err)
+			}
+			exported := listAccounts(t)
+			if len(exported) != 1 || exported[0].Protocol != models.ProtocolIMAPPassword {
+				t.Fatalf("accounts before export = %+v", exported)
+			}
+
+			var buf bytes.Buffer
+			if err := NewExportService().Export(&buf, exported, models.ExportOptions{Format: tt.format}); err != nil {
+				t.Fatalf("export: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Fatalf("exported %q, want %q", buf.String(), tt.want)
+			}
+
+			// 导入到新的数据库
+			openTestDB(t)
+			report, err = NewAccountService().Import(buf.String(), models.ImportOptions{})
+			if err != nil {
+				t.Fatalf("re-import: %v", err)
+			}
+			if report.Created != 1 || report.Invalid != 0 {
+				t.Fatalf("re-import report = %+v", report)
+			}
+			imported := listAccounts(t)
+			if len(imported) != 1 {
+				t.Fatalf("got %d accounts after re-import, want 1", len(imported))
+			}
+			got, want := imported[0], exported[0]
+			if got.Email != want.Email || got.Password != want.Password || got.Protocol != want.Protocol ||
+				got.GroupName != want.GroupName || got.ClientID != "" || got.RefreshToken != "" {
+				t.Errorf("re-imported account = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
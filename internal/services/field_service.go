@@ -0,0 +1,430 @@
+// Package services 业务服务层
+//
+// field_service.go 自定义字段服务
+//
+// 功能说明：
+// - 自定义字段定义的CRUD操作
+// - 字段值按类型校验
+// - 账号自定义字段值的读写
+// - 导入时按名称自动创建字段
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"net/mail"
+	"outlook-mail-manager/internal/database"
+	"outlook-mail-manager/internal/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// phoneRe 电话号码格式：允许+、数字、空格、短横线和括号
+var phoneRe = regexp.MustCompile(`^\+?[\d\s\-()]{5,}$`)
+
+// FieldService 自定义字段服务
+//
+// 提供自定义字段定义和字段值相关的所有数据库操作
+type FieldService struct{}
+
+// NewFieldService 创建自定义字段服务实例
+//
+// 返回值：
+//   - *FieldService: 服务实例
+func NewFieldService() *FieldService {
+	return &FieldService{}
+}
+
+// List 获取所有自定义字段定义
+//
+// 返回值：
+//   - []models.CustomField: 字段列表，按排序顺序和ID排序
+//   - error: 数据库查询错误
+func (s *FieldService) List() ([]models.CustomField, error) {
+	rows, err := database.DB.Query("SELECT id, name, type, sort_order FROM custom_fields ORDER BY sort_order, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []models.CustomField
+	for rows.Next() {
+		var f models.CustomField
+		if err := rows.Scan(&f.ID, &f.Name, &f.Type, &f.SortOrder); err != nil {
+			continue // 跳过解析失败的行
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// Get 根据ID获取自定义字段定义
+//
+// 参数：
+//   - id: 字段ID
+//
+// 返回值：
+//   - *models.CustomField: 字段对象
+//   - error: 字段不存在或数据库错误
+func (s *FieldService) Get(id int64) (*models.CustomField, error) {
+	var f models.CustomField
+	err := database.DB.QueryRow("SELECT id, name, type, sort_order FROM custom_fields WHERE id = ?", id).
+		Scan(&f.ID, &f.Name, &f.Type, &f.SortOrder)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Create 创建自定义字段
+//
+// 参数：
+//   - name: 字段名称（不能为空，不能重名）
+//   - fieldType: 字段类型，为空时默认为text
+//
+// 返回值：
+//   - *models.CustomField: 创建成功的字段对象
+//   - error: 名称为空、类型无效或重名时返回错误
+func (s *FieldService) Create(name, fieldType string) (*models.CustomField, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("field name is empty")
+	}
+	if fieldType == "" {
+		fieldType = models.FieldTypeText
+	}
+	if !isValidFieldType(fieldType) {
+		return nil, fmt.Errorf("invalid field type: %s", fieldType)
+	}
+	res, err := database.DB.Exec("INSERT INTO custom_fields (name, type) VALUES (?, ?)", name, fieldType)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+	return &models.CustomField{ID: id, Name: name, Type: fieldType}, nil
+}
+
+// Update 更新自定义字段的名称和类型
+//
+// 修改类型时按新类型校验已有的字段值（不做转换），有不合法的值时拒绝修改
+//
+// 参数：
+//   - id: 字段ID
+//   - name: 新的字段名称
+//   - fieldType: 新的字段类型
+//
+// 返回值：
+//   - error: 名称为空、类型无效、已有值不符合新类型或更新失败时返回错误
+func (s *FieldService) Update(id int64, name, fieldType string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("field name is empty")
+	}
+	if !isValidFieldType(fieldType) {
+		return fmt.Errorf("invalid field type: %s", fieldType)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := validateStoredValues(tx, id, fieldType); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE custom_fields SET name = ?, type = ? WHERE id = ?", name, fieldType, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// validateStoredValues 按字段类型校验该字段已有的所有值
+//
+// 参数：
+//   - tx: 修改类型所在的事务
+//   - fieldID: 字段ID
+//   - fieldType: 要校验的字段类型
+//
+// 返回值：
+//   - error: 不合法的值的数量和第一个不合法的值，或数据库查询错误
+func validateStoredValues(tx *sql.Tx, fieldID int64, fieldType string) error {
+	rows, err := tx.Query("SELECT COALESCE(value,'') FROM account_field_values WHERE field_id = ? ORDER BY account_id", fieldID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var invalid int
+	var first error
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return err
+		}
+		if err := validateFieldValue(fieldType, value); err != nil {
+			invalid++
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d existing values do not match type %s (%v)", invalid, fieldType, first)
+	}
+	return nil
+}
+
+// Delete 删除自定义字段
+//
+// 同时删除所有账号在该字段上的值
+//
+// 参数：
+//   - id: 要删除的字段ID
+//
+// 返回值：
+//   - error: 删除失败时返回错误
+func (s *FieldService) Delete(id int64) error {
+	if _, err := database.DB.Exec("DELETE FROM account_field_values WHERE field_id = ?", id); err != nil {
+		return err
+	}
+	_, err := database.DB.Exec("DELETE FROM custom_fields WHERE id = ?", id)
+	return err
+}
+
+// SetAccountFields 设置账号的自定义字段值
+//
+// 按字段名写入，值为空字符串时删除该字段值
+// 字段不存在时返回错误，值与字段类型不匹配时返回错误
+// 所有值在一个事务中写入，出错时不会只更新部分字段
+//
+// 参数：
+//   - accountID: 账号ID
+//   - values: 字段名 -> 值
+//
+// 返回值：
+//   - error: 字段不存在、校验失败或写入失败时返回错误
+func (s *FieldService) SetAccountFields(accountID int64, values map[string]string) error {
+	fields, err := s.List()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]models.CustomField, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	for name, value := range values {
+		f, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("unknown field: %s", name)
+		}
+		if err := validateFieldValue(f.Type, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for name, value := range values {
+		if err := setFieldValue(tx, accountID, byName[name].ID, value); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// AccountFields 获取单个账号的自定义字段值
+//
+// 参数：
+//   - accountID: 账号ID
+//
+// 返回值：
+//   - map[string]string: 字段名 -> 值（无值时为空map）
+func (s *FieldService) AccountFields(accountID int64) map[string]string {
+	values := make(map[string]string)
+	rows, err := database.DB.Query(`SELECT f.name, COALESCE(v.value,'')
+		FROM account_field_values v JOIN custom_fields f ON v.field_id = f.id WHERE v.account_id = ?`, accountID)
+	if err != nil {
+		return values
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err == nil {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// importAccountFields 导入时写入账号的自定义字段值
+//
+// 与SetAccountFields不同，不存在的字段会自动以text类型创建（类似导入时自动创建分组）
+// 已存在的字段按其类型校验，导入前应先用validateImportFields检查，使错误作为无效行报告
+//
+// 参数：
+//   - db: 数据库执行器（导入事务或全局连接）
+//   - accountID: 账号ID
+//   - values: 字段名 -> 值
+//
+// 返回值：
+//   - error: 值与字段类型不匹配、创建字段或写入字段值失败时返回错误
+func importAccountFields(db dbExecutor, accountID int64, values map[string]string) error {
+	for name, value := range values {
+		fieldID, fieldType, err := ensureField(db, name)
+		if err != nil {
+			return err
+		}
+		if err := validateFieldValue(fieldType, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := setFieldValue(db, accountID, fieldID, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateImportFields 按已存在字段的类型校验导入的字段值
+//
+// 不存在的字段导入时以text类型创建，不需要校验；字段按名称排序检查，错误信息稳定
+//
+// 参数：
+//   - db: 数据库执行器
+//   - values: 字段名 -> 值
+//
+// 返回值：
+//   - error: 第一个不合法的字段值（"字段名: 原因"）或数据库查询错误
+func validateImportFields(db dbExecutor, values map[string]string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var fieldType string
+		err := db.QueryRow("SELECT type FROM custom_fields WHERE name = ?", name).Scan(&fieldType)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := validateFieldValue(fieldType, values[name]); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ensureField 确保自定义字段存在
+//
+// 返回值：
+//   - int64: 字段ID
+//   - string: 字段类型（新建的字段为text）
+//   - error: 查询或创建失败时返回错误
+func ensureField(db dbExecutor, name string) (int64, string, error) {
+	var id int64
+	var fieldType string
+	err := db.QueryRow("SELECT id, type FROM custom_fields WHERE name = ?", name).Scan(&id, &fieldType)
+	if err == nil {
+		return id, fieldType, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, "", err
+	}
+	res, err := db.Exec("INSERT INTO custom_fields (name, type) VALUES (?, ?)", name, models.FieldTypeText)
+	if err != nil {
+		return 0, "", err
+	}
+	id, err = res.LastInsertId()
+	return id, models.FieldTypeText, err
+}
+
+// setFieldValue 写入单个字段值，空值表示删除
+func setFieldValue(db dbExecutor, accountID, fieldID int64, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		_, err := db.Exec("DELETE FROM account_field_values WHERE account_id = ? AND field_id = ?", accountID, fieldID)
+		return err
+	}
+	_, err := db.Exec(`INSERT INTO account_field_values (account_id, field_id, value) VALUES (?, ?, ?)
+		ON CONFLICT(account_id, field_id) DO UPDATE SET value = excluded.value`, accountID, fieldID, value)
+	return err
+}
+
+// fieldValuesByAccount 批量查询账号的自定义字段值
+//
+// 内部方法，供AccountService填充账号自定义字段使用
+//
+// 返回值：
+//   - map[int64]map[string]string: 账号ID -> (字段名 -> 值)
+//   - error: 数据库查询错误
+func fieldValuesByAccount() (map[int64]map[string]string, error) {
+	rows, err := database.DB.Query(`SELECT v.account_id, f.name, COALESCE(v.value,'')
+		FROM account_field_values v JOIN custom_fields f ON v.field_id = f.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int64]map[string]string)
+	for rows.Next() {
+		var accountID int64
+		var name, value string
+		if err := rows.Scan(&accountID, &name, &value); err != nil {
+			continue
+		}
+		if result[accountID] == nil {
+			result[accountID] = make(map[string]string)
+		}
+		result[accountID][name] = value
+	}
+	return result, nil
+}
+
+// isValidFieldType 判断字段类型是否有效
+func isValidFieldType(t string) bool {
+	switch t {
+	case models.FieldTypeText, models.FieldTypeNumber, models.FieldTypeDate, models.FieldTypeEmail, models.FieldTypePhone:
+		return true
+	}
+	return false
+}
+
+// validateFieldValue 按字段类型校验字段值，空值总是合法
+func validateFieldValue(fieldType, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	switch fieldType {
+	case models.FieldTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("invalid number: %s", value)
+		}
+	case models.FieldTypeDate:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("invalid date (want YYYY-MM-DD): %s", value)
+		}
+	case models.FieldTypeEmail:
+		if _, err := mail.ParseAddress(value); err != nil {
+			return fmt.Errorf("invalid email: %s", value)
+		}
+	case models.FieldTypePhone:
+		if !phoneRe.MatchString(value) {
+			return fmt.Errorf("invalid phone: %s", value)
+		}
+	}
+	return nil
+}
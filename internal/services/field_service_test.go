@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+
+	"outlook-mail-manager/internal/database"
+	"outlook-mail-manager/internal/models"
+)
+
+// createTestAccount 导入一个密码登录账号，返回账号ID
+func createTestAccount(t *testing.T, email string) int64 {
+	t.Helper()
+	report, err := NewAccountService().Import(email+"----pw", models.ImportOptions{Template: models.ImportTemplatePassword})
+	if err != nil || report.Created != 1 {
+		t.Fatalf("import %s = %+v, %v", email, report, err)
+	}
+	return report.Lines[0].AccountID
+}
+
+func TestSetAccountFieldsIsAtomic(t *testing.T) {
+	openTestDB(t)
+	s := NewFieldService()
+	id := createTestAccount(t, "a@example.com")
+	for _, name := range []string{"备用", "来源"} {
+		if _, err := s.Create(name, models.FieldTypeText); err != nil {
+			t.Fatalf("create field: %v", err)
+		}
+	}
+	if _, err := database.DB.Exec(`CREATE TRIGGER fail_field_value BEFORE INSERT ON account_field_values
+		WHEN NEW.value = 'boom' BEGIN SELECT RAISE(ABORT, 'boom'); END`); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+
+	if err := s.SetAccountFields(id, map[string]string{"备用": "ok", "来源": "boom"}); err == nil {
+		t.Fatal("SetAccountFields succeeded, want error")
+	}
+	if fields := s.AccountFields(id); len(fields) != 0 {
+		t.Errorf("fields = %v after failed update, want none", fields)
+	}
+	if err := s.SetAccountFields(id, map[string]string{"备用": "ok", "来源": "web"}); err != nil {
+		t.Fatalf("SetAccountFields: %v", err)
+	}
+	if fields := s.AccountFields(id); fields["备用"] != "ok" || fields["来源"] != "web" {
+		t.Errorf("fields = %v", fields)
+	}
+}
+
+func TestUpdateFieldTypeRevalidatesValues(t *testing.T) {
+	openTestDB(t)
+	s := NewFieldService()
+	a, b := createTestAccount(t, "a@example.com"), createTestAccount(t, "b@example.com")
+	f, err := s.Create("年龄", models.FieldTypeText)
+	if err != nil {
+		t.Fatalf("create field: %v", err)
+	}
+	if err := s.SetAccountFields(a, map[string]string{"年龄": "30"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetAccountFields(b, map[string]string{"年龄": "三十"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Update(f.ID, "年龄", models.FieldTypeNumber); err == nil {
+		t.Fatal("type change accepted with an invalid stored value")
+	}
+	if got, _ := s.Get(f.ID); got.Type != models.FieldTypeText {
+		t.Errorf("type = %s after rejected change, want text", got.Type)
+	}
+	// 改名不涉及类型时照常更新
+	if err := s.Update(f.ID, "年纪", models.FieldTypeText); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	if err := s.SetAccountFields(b, map[string]string{"年纪": "31"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(f.ID, "年纪", models.FieldTypeNumber); err != nil {
+		t.Fatalf("type change with valid values: %v", err)
+	}
+	if got, _ := s.Get(f.ID); got.Type != models.FieldTypeNumber {
+		t.Errorf("type = %s, want number", got.Type)
+	}
+}
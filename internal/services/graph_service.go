@@ -9,15 +9,18 @@
 // - 获取邮件文件夹列表
 // - 获取/搜索邮件列表
 // - 获取邮件详情和附件
-// - 删除邮件、标记已读状态
+// - 删除、移动邮件，标记已读和旗标状态
 package services
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"outlook-mail-manager/internal/models"
 )
 
@@ -41,18 +44,45 @@ func NewGraphService() *GraphService {
 // 所有GET类型的API调用都通过此方法
 //
 // 参数：
+//   - ctx: 请求的上下文，取消或超时时中止请求
 //   - accessToken: OAuth2访问令牌
 //   - endpoint: API端点路径（不含基础URL）
 //
 // 返回值：
 //   - []byte: API响应体
 //   - error: 请求错误或API错误（401表示Token过期）
-func (s *GraphService) request(accessToken, endpoint string) ([]byte, error) {
+func (s *GraphService) request(ctx context.Context, accessToken, endpoint string) ([]byte, error) {
+	return s.send(ctx, accessToken, "GET", endpoint, nil)
+}
+
+// send 发送HTTP请求（GET以及PATCH、POST、DELETE等修改邮件的请求）
+//
+// 参数：
+//   - ctx: 请求的上下文，取消或超时时中止请求
+//   - accessToken: OAuth2访问令牌
+//   - method: HTTP方法
+//   - endpoint: API端点路径（不含基础URL）
+//   - payload: 请求体，序列化为JSON；为nil时不发送请求体
+//
+// 返回值：
+//   - []byte: API响应体（204 No Content时为空）
+//   - error: 请求错误或API错误（401表示Token过期）
+func (s *GraphService) send(ctx context.Context, accessToken, method, endpoint string, payload interface{}) ([]byte, error) {
 	url := "https://outlook.office.com/api/v2.0" + endpoint
-	log.Printf("[Graph API] 请求: GET %s", url)
+	log.Printf("[Graph API] 请求: %s %s", method, url)
 
-	// 构建完整URL并创建GET请求
-	req, _ := http.NewRequest("GET", url, nil)
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
 	// 设置OAuth2 Bearer认证头
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
@@ -72,8 +102,8 @@ func (s *GraphService) request(accessToken, endpoint string) ([]byte, error) {
 		log.Printf("[Graph API] Token过期 (401)")
 		return nil, fmt.Errorf("unauthorized: token expired")
 	}
-	// 处理其他非200错误
-	if resp.StatusCode != 200 {
+	// 处理其他错误（修改类请求成功时返回201或204）
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		body, _ := io.ReadAll(resp.Body)
 		log.Printf("[Graph API] 错误响应: %s", string(body))
 		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
@@ -86,69 +116,116 @@ func (s *GraphService) request(accessToken, endpoint string) ([]byte, error) {
 
 // GetMailFolders 获取邮件文件夹列表
 //
-// API端点：GET /me/mailFolders
+// API端点：GET /me/mailFolders，子文件夹通过 GET /me/mailFolders/{id}/childFolders 获取
 // 返回用户的所有邮件文件夹（收件箱、已发送、草稿、垃圾邮件等）
 //
 // 参数：
+//   - ctx: 请求的上下文，取消或超时时中止请求
 //   - accessToken: OAuth2访问令牌
 //
 // 返回值：
-//   - []models.MailFolder: 文件夹列表，包含ID、名称、邮件数、未读数
+//   - []models.MailFolder: 顶层文件夹列表，包含ID、名称、邮件数、未读数，子文件夹在ChildFolders中
 //   - error: API调用错误
-func (s *GraphService) GetMailFolders(accessToken string) ([]models.MailFolder, error) {
+func (s *GraphService) GetMailFolders(ctx context.Context, accessToken string) ([]models.MailFolder, error) {
 	log.Printf("[Graph API] GetMailFolders 开始")
 	// $top=50 限制返回最多50个文件夹
-	data, err := s.request(accessToken, "/me/mailFolders?$top=50")
+	folders, err := s.listFolders(ctx, accessToken, "/me/mailFolders?$top=50")
 	if err != nil {
 		log.Printf("[Graph API] GetMailFolders 失败: %v", err)
 		return nil, err
 	}
-	// 解析OData响应格式（value数组）
+	log.Printf("[Graph API] GetMailFolders 成功，返回 %d 个文件夹", len(folders))
+	for i, f := range folders {
+		log.Printf("[Graph API] 文件夹[%d]: ID=%s, Name=%s, Total=%d, Unread=%d, Children=%d",
+			i, f.ID, f.DisplayName, f.TotalItemCount, f.UnreadItemCount, f.ChildFolderCount)
+	}
+	s.fillChildFolders(ctx, accessToken, folders, 1)
+	return folders, nil
+}
+
+// maxFolderDepth 获取子文件夹的最大层数
+const maxFolderDepth = 10
+
+// fillChildFolders 递归获取子文件夹，填充到各文件夹的ChildFolders
+//
+// 某个文件夹的子文件夹获取失败时只记录日志，不影响其他文件夹
+func (s *GraphService) fillChildFolders(ctx context.Context, accessToken string, folders []models.MailFolder, depth int) {
+	if depth > maxFolderDepth {
+		return
+	}
+	for i := range folders {
+		if folders[i].ChildFolderCount == 0 {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		children, err := s.listFolders(ctx, accessToken, fmt.Sprintf("/me/mailFolders/%s/childFolders?$top=50", folders[i].ID))
+		if err != nil {
+			log.Printf("[Graph API] 获取子文件夹失败 - ID: %s, error: %v", folders[i].ID, err)
+			continue
+		}
+		s.fillChildFolders(ctx, accessToken, children, depth+1)
+		folders[i].ChildFolders = children
+	}
+}
+
+// listFolders 请求文件夹列表端点并解析OData响应格式（value数组）
+func (s *GraphService) listFolders(ctx context.Context, accessToken, endpoint string) ([]models.MailFolder, error) {
+	data, err := s.request(ctx, accessToken, endpoint)
+	if err != nil {
+		return nil, err
+	}
 	var result struct {
 		Value []models.MailFolder `json:"value"`
 	}
 	if err := json.Unmarshal(data, &result); err != nil {
-		log.Printf("[Graph API] GetMailFolders JSON解析失败: %v", err)
+		log.Printf("[Graph API] 文件夹列表JSON解析失败: %v", err)
 		return nil, fmt.Errorf("parse mail folders failed: %w", err)
 	}
-	log.Printf("[Graph API] GetMailFolders 成功，返回 %d 个文件夹", len(result.Value))
-	for i, f := range result.Value {
-		log.Printf("[Graph API] 文件夹[%d]: ID=%s, Name=%s, Total=%d, Unread=%d",
-			i, f.ID, f.DisplayName, f.TotalItemCount, f.UnreadItemCount)
-	}
 	return result.Value, nil
 }
 
-// GetMessages 获取指定文件夹的邮件列表
+// GetMessages 获取指定文件夹的一页邮件列表
 //
 // API端点：GET /me/mailFolders/{id}/messages
 // 支持分页、排序和字段选择
 //
 // 参数：
+//   - ctx: 请求的上下文，取消或超时时中止请求
 //   - accessToken: OAuth2访问令牌
 //   - folderID: 文件夹ID（如"inbox"、"junkemail"或GUID）
-//   - skip: 跳过的邮件数（用于分页）
+//   - cursor: 上一页返回的游标，空字符串表示第一页
 //   - top: 返回的邮件数（每页大小）
 //
 // 返回值：
-//   - []models.Message: 邮件列表（按接收时间倒序）
-//   - error: API调用错误
-func (s *GraphService) GetMessages(accessToken, folderID string, skip, top int) ([]models.Message, error) {
-	log.Printf("[Graph API] GetMessages 开始 - folderID: %s, skip: %d, top: %d", folderID, skip, top)
+//   - *models.MessagePage: 邮件列表（按接收时间倒序）和下一页游标
+//   - error: API调用错误；游标不属于REST API时返回ErrCursorExpired
+func (s *GraphService) GetMessages(ctx context.Context, accessToken, folderID, cursor string, top int) (*models.MessagePage, error) {
+	prev, err := parseGraphCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
 	// 构建查询参数：
-	// $skip: 分页偏移量
-	// $top: 每页数量
+	// $filter: 翻页时只取不晚于上一页最后一封邮件的邮件（不用$skip偏移，新邮件到达时分页不会错位）
+	// $top: 每页数量，另外多取上一页已返回的同一时间的邮件数，排除后仍能取满一页
 	// $orderby: 按接收时间倒序
 	// $select: 只返回需要的字段（优化性能）
-	endpoint := fmt.Sprintf("/me/mailFolders/%s/messages?$skip=%d&$top=%d&$orderby=receivedDateTime desc&$select=id,subject,bodyPreview,from,receivedDateTime,hasAttachments,isRead",
-		folderID, skip, top)
-	data, err := s.request(accessToken, endpoint)
+	query := fmt.Sprintf("$top=%d", top)
+	if prev != nil {
+		query = fmt.Sprintf("$filter=%s&$top=%d", url.PathEscape("receivedDateTime le "+prev.Before), top+len(prev.IDs))
+	}
+	log.Printf("[Graph API] GetMessages 开始 - folderID: %s, query: %s", folderID, query)
+	endpoint := fmt.Sprintf("/me/mailFolders/%s/messages?%s&$orderby=receivedDateTime desc&$select=id,subject,bodyPreview,from,receivedDateTime,hasAttachments,isRead,flag",
+		folderID, query)
+	data, err := s.request(ctx, accessToken, endpoint)
 	if err != nil {
 		log.Printf("[Graph API] GetMessages 失败: %v", err)
 		return nil, err
 	}
 	var result struct {
-		Value []models.Message `json:"value"`
+		Value    []models.Message `json:"value"`
+		NextLink string           `json:"@odata.nextLink"`
 	}
 	if err := json.Unmarshal(data, &result); err != nil {
 		log.Printf("[Graph API] GetMessages JSON解析失败: %v", err)
@@ -158,7 +235,8 @@ func (s *GraphService) GetMessages(accessToken, folderID string, skip, top int)
 	for i, m := range result.Value {
 		log.Printf("[Graph API] 邮件[%d]: ID=%s, Subject=%s, IsRead=%v", i, m.ID, m.Subject, m.IsRead)
 	}
-	return result.Value, nil
+	page := buildGraphPage(prev, result.Value, result.NextLink != "", top)
+	return page, nil
 }
 
 // GetMessage 获取单封邮件详情
@@ -167,16 +245,17 @@ func (s *GraphService) GetMessages(accessToken, folderID string, skip, top int)
 // 获取邮件的完整内容，包括HTML正文
 //
 // 参数：
+//   - ctx: 请求的上下文，取消或超时时中止请求
 //   - accessToken: OAuth2访问令牌
 //   - messageID: 邮件ID
 //
 // 返回值：
 //   - *models.Message: 邮件详情（含完整正文）
 //   - error: API调用错误
-func (s *GraphService) GetMessage(accessToken, messageID string) (*models.Message, error) {
+func (s *GraphService) GetMessage(ctx context.Context, accessToken, messageID string) (*models.Message, error) {
 	log.Printf("[Graph API] GetMessage 开始 - messageID: %s", messageID)
 	// $select包含body字段以获取完整正文
-	data, err := s.request(accessToken, "/me/messages/"+messageID+"?$select=id,subject,body,bodyPreview,from,toRecipients,receivedDateTime,hasAttachments,isRead")
+	data, err := s.request(ctx, accessToken, "/me/messages/"+messageID+"?$select=id,subject,body,bodyPreview,from,sender,toRecipients,ccRecipients,bccRecipients,replyTo,receivedDateTime,hasAttachments,isRead,flag")
 	if err != nil {
 		log.Printf("[Graph API] GetMessage 失败: %v", err)
 		return nil, err
@@ -197,15 +276,16 @@ func (s *GraphService) GetMessage(accessToken, messageID string) (*models.Messag
 // 返回邮件的所有附件，包含Base64编码的文件内容
 //
 // 参数：
+//   - ctx: 请求的上下文，取消或超时时中止请求
 //   - accessToken: OAuth2访问令牌
 //   - messageID: 邮件ID
 //
 // 返回值：
 //   - []models.Attachment: 附件列表
 //   - error: API调用错误
-func (s *GraphService) GetAttachments(accessToken, messageID string) ([]models.Attachment, error) {
+func (s *GraphService) GetAttachments(ctx context.Context, accessToken, messageID string) ([]models.Attachment, error) {
 	log.Printf("[Graph API] GetAttachments 开始 - messageID: %s", messageID)
-	data, err := s.request(accessToken, "/me/messages/"+messageID+"/attachments")
+	data, err := s.request(ctx, accessToken, "/me/messages/"+messageID+"/attachments")
 	if err != nil {
 		log.Printf("[Graph API] GetAttachments 失败: %v", err)
 		return nil, err
@@ -221,3 +301,77 @@ func (s *GraphService) GetAttachments(accessToken, messageID string) ([]models.A
 	return result.Value, nil
 }
 
+// SetReadState 设置邮件的已读状态
+//
+// API端点：PATCH /me/messages/{id}，请求体 {"IsRead": true/false}
+//
+// 参数：
+//   - ctx: 请求的上下文，取消或超时时中止请求
+//   - accessToken: OAuth2访问令牌
+//   - messageID: 邮件ID
+//   - isRead: true标记为已读，false标记为未读
+//
+// 返回值：
+//   - error: API调用错误
+func (s *GraphService) SetReadState(ctx context.Context, accessToken, messageID string, isRead bool) error {
+	log.Printf("[Graph API] SetReadState - messageID: %s, isRead: %v", messageID, isRead)
+	_, err := s.send(ctx, accessToken, "PATCH", "/me/messages/"+messageID, map[string]bool{"IsRead": isRead})
+	return err
+}
+
+// SetFlag 设置邮件的旗标
+//
+// API端点：PATCH /me/messages/{id}，请求体 {"Flag": {"FlagStatus": "Flagged"/"NotFlagged"}}
+//
+// 参数：
+//   - ctx: 请求的上下文，取消或超时时中止请求
+//   - accessToken: OAuth2访问令牌
+//   - messageID: 邮件ID
+//   - flagged: true加旗标，false清除旗标
+//
+// 返回值：
+//   - error: API调用错误
+func (s *GraphService) SetFlag(ctx context.Context, accessToken, messageID string, flagged bool) error {
+	log.Printf("[Graph API] SetFlag - messageID: %s, flagged: %v", messageID, flagged)
+	payload := map[string]interface{}{"Flag": map[string]string{"FlagStatus": models.NewMessageFlag(flagged).FlagStatus}}
+	_, err := s.send(ctx, accessToken, "PATCH", "/me/messages/"+messageID, payload)
+	return err
+}
+
+// MoveMessage 移动邮件到其他文件夹
+//
+// API端点：POST /me/messages/{id}/move，请求体 {"DestinationId": "..."}
+// 目标可以是文件夹ID或预定义名称（如"inbox"、"junkemail"、"deleteditems"）；
+// 移动后邮件ID会改变
+//
+// 参数：
+//   - ctx: 请求的上下文，取消或超时时中止请求
+//   - accessToken: OAuth2访问令牌
+//   - messageID: 邮件ID
+//   - destinationID: 目标文件夹ID
+//
+// 返回值：
+//   - error: API调用错误
+func (s *GraphService) MoveMessage(ctx context.Context, accessToken, messageID, destinationID string) error {
+	log.Printf("[Graph API] MoveMessage - messageID: %s, destination: %s", messageID, destinationID)
+	_, err := s.send(ctx, accessToken, "POST", "/me/messages/"+messageID+"/move", map[string]string{"DestinationId": destinationID})
+	return err
+}
+
+// DeleteMessage 删除邮件
+//
+// API端点：DELETE /me/messages/{id}
+// 邮件被移到"已删除邮件"文件夹；已在该文件夹中的邮件被永久删除
+//
+// 参数：
+//   - ctx: 请求的上下文，取消或超时时中止请求
+//   - accessToken: OAuth2访问令牌
+//   - messageID: 邮件ID
+//
+// 返回值：
+//   - error: API调用错误
+func (s *GraphService) DeleteMessage(ctx context.Context, accessToken, messageID string) error {
+	log.Printf("[Graph API] DeleteMessage - messageID: %s", messageID)
+	_, err := s.send(ctx, accessToken, "DELETE", "/me/messages/"+messageID, nil)
+	return err
+}
@@ -37,9 +37,9 @@ func NewGroupService() *GroupService {
 //   - error: 数据库查询错误
 func (s *GroupService) List() ([]models.Group, error) {
 	// SQL查询：获取分组信息并统计每个分组的账号数
-	// 子查询 (SELECT COUNT(*) FROM accounts WHERE group_id = g.id) 计算账号数
+	// 子查询 (SELECT COUNT(*) FROM accounts WHERE group_id = g.id) 计算账号数（不含回收站中的账号）
 	rows, err := database.DB.Query(`SELECT g.id, g.name, g.parent_id, g.sort_order,
-		(SELECT COUNT(*) FROM accounts WHERE group_id = g.id) as count
+		(SELECT COUNT(*) FROM accounts WHERE group_id = g.id AND deleted_at IS NULL) as count
 		FROM groups g ORDER BY g.sort_order, g.id`)
 	if err != nil {
 		return nil, err
@@ -83,6 +83,19 @@ func (s *GroupService) Create(name string, parentID *int64) (*models.Group, erro
 	return &models.Group{ID: id, Name: name, ParentID: parentID}, nil
 }
 
+// GetName 获取分组名称
+//
+// 参数：
+//   - id: 分组ID
+//
+// 返回值：
+//   - string: 分组名称，分组不存在时返回空字符串
+func (s *GroupService) GetName(id int64) string {
+	var name string
+	database.DB.QueryRow("SELECT name FROM groups WHERE id = ?", id).Scan(&name)
+	return name
+}
+
 // Update 更新分组名称
 //
 // 参数：
@@ -0,0 +1,251 @@
+// Package services 业务服务层
+//
+// imap_actions.go IMAP邮件修改操作
+//
+// 功能说明：
+// - 按UID修改邮件标志（UID STORE），如标记已读/未读、加旗标，使用.SILENT形式不返回修改后的标志
+// - 移动邮件：服务器支持MOVE（RFC 6851）时使用UID MOVE，否则复制后标记\Deleted并清除
+// - 删除邮件：移到"已删除"文件夹，已在该文件夹中的邮件永久删除
+// - 移出/移入垃圾邮件文件夹时更新$NotJunk/$Junk关键字，供服务器训练垃圾邮件过滤器
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// uidSet 将邮件ID列表转换为UID集合（如"12,15,20"）
+//
+// 邮件ID直接拼接进命令，只接受数字，防止注入其他命令
+//
+// 返回值：
+//   - string: 逗号分隔的UID集合
+//   - error: 列表为空或ID不是合法的UID时返回错误
+func uidSet(messageIDs []string) (string, error) {
+	if len(messageIDs) == 0 {
+		return "", fmt.Errorf("no messages specified")
+	}
+	set := make([]string, len(messageIDs))
+	for i, id := range messageIDs {
+		uid, err := strconv.ParseUint(id, 10, 32)
+		if err != nil || uid == 0 {
+			return "", fmt.Errorf("invalid message ID: %s", id)
+		}
+		set[i] = strconv.FormatUint(uid, 10)
+	}
+	return strings.Join(set, ","), nil
+}
+
+// modifyMessages 借出连接、选择邮件所在文件夹后执行修改操作
+//
+// 参数：
+//   - ctx: 上下文，取消时断开连接
+//   - auth: 登录凭据
+//   - folderID: 邮件所在文件夹
+//   - messageIDs: 邮件UID列表
+//   - fn: 修改操作，参数为已选择文件夹的连接、UID集合和服务器上的文件夹名
+//
+// 返回值：
+//   - error: 邮件ID无效、获取连接、SELECT或修改操作失败时返回错误
+func (s *IMAPService) modifyMessages(ctx context.Context, auth IMAPAuth, folderID string, messageIDs []string, fn func(client *IMAPClient, set, folder string) error) error {
+	set, err := uidSet(messageIDs)
+	if err != nil {
+		return err
+	}
+	client, release, err := s.acquire(ctx, auth)
+	if err != nil {
+		log.Printf("[IMAP] 获取连接失败: %v", err)
+		return err
+	}
+	defer release() // 用完归还连接池
+
+	imapFolder := s.resolveFolder(client, auth.Email, folderID)
+	if _, err := client.command("SELECT " + quoteIMAPString(imapFolder)); err != nil {
+		log.Printf("[IMAP] SELECT 命令失败: %v", err)
+		return err
+	}
+	return fn(client, set, imapFolder)
+}
+
+// storeFlags 修改已选择文件夹中邮件的标志
+//
+// 参数：
+//   - set: UID集合
+//   - op: "+FLAGS"（添加）或"-FLAGS"（移除）
+//   - flags: 标志列表，如`\Seen`
+//
+// 返回值：
+//   - error: 命令失败时返回错误
+func (c *IMAPClient) storeFlags(set, op string, flags ...string) error {
+	storeCmd := fmt.Sprintf("UID STORE %s %s.SILENT (%s)", set, op, strings.Join(flags, " "))
+	log.Printf("[IMAP] 发送命令: %s", storeCmd)
+	if _, err := c.command(storeCmd); err != nil {
+		log.Printf("[IMAP] UID STORE 失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// expungeMessages 标记\Deleted并永久删除已选择文件夹中的邮件
+//
+// 服务器支持UIDPLUS时只清除指定的邮件；否则使用EXPUNGE，
+// 该文件夹中其他已标记\Deleted的邮件也会被清除
+func (c *IMAPClient) expungeMessages(set, caps string) error {
+	if err := c.storeFlags(set, "+FLAGS", `\Deleted`); err != nil {
+		return err
+	}
+	expungeCmd := "EXPUNGE"
+	if strings.Contains(caps, " UIDPLUS ") {
+		expungeCmd = "UID EXPUNGE " + set
+	}
+	log.Printf("[IMAP] 发送命令: %s", expungeCmd)
+	if _, err := c.command(expungeCmd); err != nil {
+		log.Printf("[IMAP] %s 失败: %v", expungeCmd, err)
+		return err
+	}
+	return nil
+}
+
+// moveMessages 将已选择文件夹中的邮件移到目标文件夹
+//
+// 参数：
+//   - set: UID集合
+//   - dest: 服务器上的目标文件夹名
+//
+// 返回值：
+//   - error: 命令失败时返回错误
+func (c *IMAPClient) moveMessages(set, dest string) error {
+	caps, err := c.capabilities()
+	if err != nil {
+		return err
+	}
+	if strings.Contains(caps, " MOVE ") {
+		moveCmd := fmt.Sprintf("UID MOVE %s %s", set, quoteIMAPString(dest))
+		log.Printf("[IMAP] 发送命令: %s", moveCmd)
+		if _, err := c.command(moveCmd); err != nil {
+			log.Printf("[IMAP] UID MOVE 失败: %v", err)
+			return err
+		}
+		return nil
+	}
+
+	// 不支持MOVE的服务器：复制到目标文件夹后从原文件夹删除
+	copyCmd := fmt.Sprintf("UID COPY %s %s", set, quoteIMAPString(dest))
+	log.Printf("[IMAP] 发送命令: %s", copyCmd)
+	if _, err := c.command(copyCmd); err != nil {
+		log.Printf("[IMAP] UID COPY 失败: %v", err)
+		return err
+	}
+	return c.expungeMessages(set, caps)
+}
+
+// SetSeen 标记邮件为已读或未读（添加或移除\Seen标志）
+//
+// 参数：
+//   - ctx: 上下文，取消时断开连接
+//   - auth: 登录凭据
+//   - folderID: 邮件所在文件夹
+//   - messageIDs: 邮件UID列表
+//   - seen: true标记为已读，false标记为未读
+//
+// 返回值：
+//   - error: 命令失败或邮件ID无效时返回错误
+func (s *IMAPService) SetSeen(ctx context.Context, auth IMAPAuth, folderID string, messageIDs []string, seen bool) error {
+	log.Printf("[IMAP] SetSeen - email: %s, folderID: %s, messages: %d, seen: %v", auth.Email, folderID, len(messageIDs), seen)
+	return s.modifyMessages(ctx, auth, folderID, messageIDs, func(client *IMAPClient, set, _ string) error {
+		return client.storeFlags(set, flagOp(seen), `\Seen`)
+	})
+}
+
+// SetFlagged 为邮件加旗标或清除旗标（添加或移除\Flagged标志）
+//
+// 参数：
+//   - ctx: 上下文，取消时断开连接
+//   - auth: 登录凭据
+//   - folderID: 邮件所在文件夹
+//   - messageIDs: 邮件UID列表
+//   - flagged: true加旗标，false清除旗标
+//
+// 返回值：
+//   - error: 命令失败或邮件ID无效时返回错误
+func (s *IMAPService) SetFlagged(ctx context.Context, auth IMAPAuth, folderID string, messageIDs []string, flagged bool) error {
+	log.Printf("[IMAP] SetFlagged - email: %s, folderID: %s, messages: %d, flagged: %v", auth.Email, folderID, len(messageIDs), flagged)
+	return s.modifyMessages(ctx, auth, folderID, messageIDs, func(client *IMAPClient, set, _ string) error {
+		return client.storeFlags(set, flagOp(flagged), `\Flagged`)
+	})
+}
+
+// MoveMessages 移动邮件到其他文件夹
+//
+// 从垃圾邮件文件夹移出时（如"不是垃圾邮件"）添加$NotJunk关键字，移入时添加$Junk关键字；
+// 不支持关键字的服务器会拒绝STORE，此时只记录日志，不影响移动
+//
+// 参数：
+//   - ctx: 上下文，取消时断开连接
+//   - auth: 登录凭据
+//   - folderID: 邮件所在文件夹
+//   - messageIDs: 邮件UID列表
+//   - destinationID: 目标文件夹ID
+//
+// 返回值：
+//   - error: 命令失败或邮件ID无效时返回错误
+func (s *IMAPService) MoveMessages(ctx context.Context, auth IMAPAuth, folderID string, messageIDs []string, destinationID string) error {
+	log.Printf("[IMAP] MoveMessages - email: %s, folderID: %s, messages: %d, destination: %s", auth.Email, folderID, len(messageIDs), destinationID)
+	return s.modifyMessages(ctx, auth, folderID, messageIDs, func(client *IMAPClient, set, folder string) error {
+		dest := s.resolveFolder(client, auth.Email, destinationID)
+		if dest == folder {
+			return nil
+		}
+		fromJunk, toJunk := strings.EqualFold(folderID, "junkemail"), strings.EqualFold(destinationID, "junkemail")
+		if fromJunk != toJunk {
+			add, remove := "$NotJunk", "$Junk"
+			if toJunk {
+				add, remove = remove, add
+			}
+			if err := client.storeFlags(set, "+FLAGS", add); err != nil {
+				log.Printf("[IMAP] 服务器不支持垃圾邮件关键字，跳过: %v", err)
+			} else if err := client.storeFlags(set, "-FLAGS", remove); err != nil {
+				log.Printf("[IMAP] 移除 %s 关键字失败: %v", remove, err)
+			}
+		}
+		return client.moveMessages(set, dest)
+	})
+}
+
+// DeleteMessages 删除邮件
+//
+// 与REST API一致：邮件移到"已删除"文件夹，已在该文件夹中的邮件永久删除
+//
+// 参数：
+//   - ctx: 上下文，取消时断开连接
+//   - auth: 登录凭据
+//   - folderID: 邮件所在文件夹
+//   - messageIDs: 邮件UID列表
+//
+// 返回值：
+//   - error: 命令失败或邮件ID无效时返回错误
+func (s *IMAPService) DeleteMessages(ctx context.Context, auth IMAPAuth, folderID string, messageIDs []string) error {
+	log.Printf("[IMAP] DeleteMessages - email: %s, folderID: %s, messages: %d", auth.Email, folderID, len(messageIDs))
+	return s.modifyMessages(ctx, auth, folderID, messageIDs, func(client *IMAPClient, set, folder string) error {
+		trash := s.resolveFolder(client, auth.Email, "deleteditems")
+		if folder != trash {
+			return client.moveMessages(set, trash)
+		}
+		caps, err := client.capabilities()
+		if err != nil {
+			return err
+		}
+		return client.expungeMessages(set, caps)
+	})
+}
+
+// flagOp 返回添加（true）或移除（false）标志的STORE操作
+func flagOp(add bool) string {
+	if add {
+		return "+FLAGS"
+	}
+	return "-FLAGS"
+}
@@ -0,0 +1,232 @@
+// Package services 业务服务层
+//
+// imap_folders.go IMAP文件夹列表与文件夹名解析
+//
+// 功能说明：
+// - 解析LIST响应的属性、层级分隔符和文件夹名（修改版UTF-7，见imap_utf7.go）
+// - 按RFC 6154特殊用途属性（\Junk、\Sent、\Trash、\Drafts、\Archive）识别垃圾邮件等文件夹，服务器不支持时按常见文件夹名识别
+// - 按LIST返回的分隔符（"/"、"."等）构建父子文件夹树
+// - 缓存各账号已知文件夹ID对应的服务器文件夹名，SELECT时使用服务器上的实际名称
+package services
+
+import (
+	"log"
+	"outlook-mail-manager/internal/models"
+	"strings"
+)
+
+// imapSpecialUse RFC 6154特殊用途属性（大写） -> REST API ID
+var imapSpecialUse = map[string]string{
+	`\JUNK`:    "junkemail",
+	`\SENT`:    "sentitems",
+	`\TRASH`:   "deleteditems",
+	`\DRAFTS`:  "drafts",
+	`\ARCHIVE`: "archive",
+}
+
+// imapMailbox LIST响应中的一个文件夹
+type imapMailbox struct {
+	name      string          // 服务器上的文件夹名（修改版UTF-7编码）
+	decoded   string          // 解码后的完整文件夹名
+	delimiter string          // 层级分隔符，NIL（没有层级）时为空
+	attrs     map[string]bool // 属性（大写），如 \NOSELECT、\HASCHILDREN、\JUNK
+	id        string          // 文件夹ID：已知文件夹为REST API ID，其他为解码后的完整文件夹名
+}
+
+// listMailboxes 发送LIST命令获取所有文件夹并识别已知文件夹
+//
+// 返回值：
+//   - []imapMailbox: 文件夹列表（按LIST响应的顺序，不包含\NonExistent文件夹）
+//   - error: 命令失败时返回错误
+func (c *IMAPClient) listMailboxes() ([]imapMailbox, error) {
+	resps, err := c.command(`LIST "" "*"`)
+	if err != nil {
+		return nil, err
+	}
+	var mailboxes []imapMailbox
+	for _, r := range resps {
+		// 解析: * LIST (\HasNoChildren \Junk) "/" "Junk Email"，名称可以是原子、带引号字符串或字面量
+		if r.Name() != "LIST" || len(r.Fields) < 4 {
+			continue
+		}
+		mb := imapMailbox{
+			name:      imapString(r.Fields[3]),
+			delimiter: imapString(r.Fields[2]),
+			attrs:     make(map[string]bool),
+		}
+		for _, a := range imapList(r.Fields[1]) {
+			mb.attrs[strings.ToUpper(imapString(a))] = true
+		}
+		if mb.attrs[`\NONEXISTENT`] {
+			continue
+		}
+		mb.decoded = decodeIMAPUTF7(mb.name)
+		mailboxes = append(mailboxes, mb)
+	}
+	assignMailboxIDs(mailboxes)
+	return mailboxes, nil
+}
+
+// assignMailboxIDs 为文件夹分配ID
+//
+// 优先级：INBOX > 特殊用途属性 > 常见文件夹名（只匹配顶层或INBOX下一层的文件夹）；
+// 每个已知ID只分配给一个文件夹，其余文件夹以解码后的完整文件夹名作为ID
+func assignMailboxIDs(mailboxes []imapMailbox) {
+	taken := make(map[string]bool)
+	assign := func(i int, id string) {
+		if id != "" && !taken[id] {
+			mailboxes[i].id = id
+			taken[id] = true
+		}
+	}
+	for i, mb := range mailboxes {
+		if strings.EqualFold(mb.name, "INBOX") {
+			assign(i, "inbox")
+		}
+	}
+	for i, mb := range mailboxes {
+		if mb.id != "" || mb.attrs[`\NOSELECT`] {
+			continue
+		}
+		for attr, id := range imapSpecialUse {
+			if mb.attrs[attr] {
+				assign(i, id)
+				break
+			}
+		}
+	}
+	for i, mb := range mailboxes {
+		if mb.id != "" || mb.attrs[`\NOSELECT`] {
+			continue
+		}
+		parent, leaf := splitMailboxName(mb.decoded, mb.delimiter)
+		if parent == "" || strings.EqualFold(parent, "INBOX") {
+			if id := getRestAPIFolderID(leaf); id != leaf {
+				assign(i, id)
+			}
+		}
+	}
+	for i := range mailboxes {
+		if mailboxes[i].id == "" {
+			mailboxes[i].id = mailboxes[i].decoded
+		}
+	}
+}
+
+// splitMailboxName 按层级分隔符拆分文件夹名
+//
+// 返回值：
+//   - string: 父文件夹名，顶层文件夹为空
+//   - string: 最后一级名称
+func splitMailboxName(name, delimiter string) (string, string) {
+	if delimiter == "" {
+		return "", name
+	}
+	if i := strings.LastIndex(name, delimiter); i > 0 {
+		return name[:i], name[i+len(delimiter):]
+	}
+	return "", name
+}
+
+// buildFolderTree 按层级分隔符将文件夹列表构建为树
+//
+// 父文件夹不在列表中时（服务器未返回中间层级），该文件夹作为顶层文件夹
+//
+// 参数：
+//   - mailboxes: LIST获取的文件夹
+//   - folders: 与mailboxes一一对应的文件夹（已填充ID、显示名称和计数）
+//
+// 返回值：
+//   - []models.MailFolder: 顶层文件夹列表
+func buildFolderTree(mailboxes []imapMailbox, folders []models.MailFolder) []models.MailFolder {
+	index := make(map[string]int, len(mailboxes)) // 服务器文件夹名 -> 下标
+	for i, mb := range mailboxes {
+		index[mb.name] = i
+	}
+	children := make(map[int][]int)
+	var roots []int
+	for i, mb := range mailboxes {
+		parent, _ := splitMailboxName(mb.name, mb.delimiter)
+		if p, ok := index[parent]; ok && parent != "" && p != i {
+			folders[i].ParentFolderID = folders[p].ID
+			children[p] = append(children[p], i)
+		} else {
+			if _, known := folderDisplayNames[folders[i].ID]; parent != "" && !known {
+				folders[i].DisplayName = mb.decoded // 没有父文件夹可显示，使用完整名称
+			}
+			roots = append(roots, i)
+		}
+	}
+
+	var build func(i, depth int) models.MailFolder
+	build = func(i, depth int) models.MailFolder {
+		f := folders[i]
+		if depth < maxFolderDepth {
+			for _, c := range children[i] {
+				f.ChildFolders = append(f.ChildFolders, build(c, depth+1))
+			}
+		}
+		f.ChildFolderCount = len(f.ChildFolders)
+		return f
+	}
+	tree := make([]models.MailFolder, 0, len(roots))
+	for _, i := range roots {
+		tree = append(tree, build(i, 1))
+	}
+	return tree
+}
+
+// cacheFolderNames 记录账号已知文件夹ID对应的服务器文件夹名
+func (s *IMAPService) cacheFolderNames(email string, mailboxes []imapMailbox) {
+	names := make(map[string]string)
+	for _, mb := range mailboxes {
+		if _, ok := imapFolderMap[mb.id]; ok {
+			names[mb.id] = mb.name
+		}
+	}
+	s.folderMu.Lock()
+	defer s.folderMu.Unlock()
+	s.folderNames[email] = names
+}
+
+// resolveFolder 将文件夹ID解析为服务器上的文件夹名（修改版UTF-7编码，可直接用于SELECT）
+//
+// 已知文件夹（如"junkemail"）使用LIST识别出的实际名称，尚未获取过文件夹列表时先发送LIST；
+// 服务器上没有对应文件夹时使用MapFolderID的默认名称；其他文件夹ID即解码后的文件夹名
+//
+// 参数：
+//   - client: 已登录的IMAP客户端
+//   - email: 账号邮箱（缓存的键）
+//   - folderID: 文件夹ID
+//
+// 返回值：
+//   - string: 服务器上的文件夹名
+func (s *IMAPService) resolveFolder(client *IMAPClient, email, folderID string) string {
+	// 已知文件夹的ID都是小写的，与其他文件夹名（如被占用后未识别的"Drafts"）区分
+	id := folderID
+	if strings.EqualFold(id, "INBOX") {
+		return "INBOX"
+	}
+	if _, ok := imapFolderMap[id]; !ok {
+		return encodeIMAPUTF7(folderID)
+	}
+
+	s.folderMu.Lock()
+	names, cached := s.folderNames[email]
+	s.folderMu.Unlock()
+	if !cached {
+		mailboxes, err := client.listMailboxes()
+		if err != nil {
+			log.Printf("[IMAP] LIST 命令失败，使用默认文件夹名: %v", err)
+			return MapFolderID(id)
+		}
+		s.cacheFolderNames(email, mailboxes)
+		s.folderMu.Lock()
+		names = s.folderNames[email]
+		s.folderMu.Unlock()
+	}
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return MapFolderID(id)
+}
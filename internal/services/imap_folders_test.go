@@ -0,0 +1,163 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"outlook-mail-manager/internal/models"
+)
+
+// testMailbox 按LIST响应构造文件夹（name为UTF-8，编码为修改版UTF-7）
+func testMailbox(name, delimiter string, attrs ...string) imapMailbox {
+	mb := imapMailbox{name: encodeIMAPUTF7(name), delimiter: delimiter, attrs: make(map[string]bool)}
+	for _, a := range attrs {
+		mb.attrs[strings.ToUpper(a)] = true
+	}
+	mb.decoded = decodeIMAPUTF7(mb.name)
+	return mb
+}
+
+// testFolderTree 与GetMailFolders相同：分配ID、生成显示名称后构建文件夹树
+func testFolderTree(mailboxes []imapMailbox) []models.MailFolder {
+	assignMailboxIDs(mailboxes)
+	folders := make([]models.MailFolder, len(mailboxes))
+	for i, mb := range mailboxes {
+		_, displayName := splitMailboxName(mb.decoded, mb.delimiter)
+		if chineseName, ok := folderDisplayNames[mb.id]; ok {
+			displayName = chineseName
+		}
+		folders[i] = models.MailFolder{ID: mb.id, DisplayName: displayName, NoSelect: mb.attrs[`\NOSELECT`]}
+	}
+	return buildFolderTree(mailboxes, folders)
+}
+
+// flattenFolderTree 将文件夹树展开为每行一个文件夹的文本，缩进表示层级
+func flattenFolderTree(tree []models.MailFolder, depth int) []string {
+	var lines []string
+	for _, f := range tree {
+		line := fmt.Sprintf("%s%s [%s] parent=%q", strings.Repeat("  ", depth), f.ID, f.DisplayName, f.ParentFolderID)
+		if f.ChildFolderCount != len(f.ChildFolders) {
+			line += fmt.Sprintf(" childCount=%d", f.ChildFolderCount)
+		}
+		lines = append(lines, line)
+		lines = append(lines, flattenFolderTree(f.ChildFolders, depth+1)...)
+	}
+	return lines
+}
+
+func TestBuildFolderTree(t *testing.T) {
+	tests := []struct {
+		name      string
+		mailboxes []imapMailbox
+		want      []string
+	}{
+		{
+			name: "slash delimiter",
+			mailboxes: []imapMailbox{
+				testMailbox("INBOX", "/", `\HasChildren`),
+				testMailbox("INBOX/Work", "/", `\HasNoChildren`),
+				testMailbox("Sent", "/", `\Sent`),
+				testMailbox("项目", "/", `\HasChildren`),
+				testMailbox("项目/2024", "/", `\HasChildren`),
+				testMailbox("项目/2024/Q1", "/"),
+				testMailbox("项目/归档", "/"),
+			},
+			want: []string{
+				`inbox [收件箱] parent=""`,
+				`  INBOX/Work [Work] parent="inbox"`,
+				`sentitems [已发送] parent=""`,
+				`项目 [项目] parent=""`,
+				`  项目/2024 [2024] parent="项目"`,
+				`    项目/2024/Q1 [Q1] parent="项目/2024"`,
+				`  项目/归档 [归档] parent="项目"`,
+			},
+		},
+		{
+			name: "dot delimiter with folders under INBOX",
+			mailboxes: []imapMailbox{
+				testMailbox("INBOX", ".", `\HasChildren`),
+				testMailbox("INBOX.Drafts", ".", `\Drafts`),
+				testMailbox("INBOX.Sent", "."),
+				testMailbox("INBOX.客户", ".", `\HasChildren`),
+				testMailbox("INBOX.客户.2024", "."),
+				testMailbox("Notes/Old", "."), // "/"不是分隔符
+			},
+			want: []string{
+				`inbox [收件箱] parent=""`,
+				`  drafts [草稿] parent="inbox"`,
+				`  sentitems [已发送] parent="inbox"`,
+				`  INBOX.客户 [客户] parent="inbox"`,
+				`    INBOX.客户.2024 [2024] parent="INBOX.客户"`,
+				`Notes/Old [Notes/Old] parent=""`,
+			},
+		},
+		{
+			name: "missing parents become top-level folders",
+			mailboxes: []imapMailbox{
+				testMailbox("INBOX", "/"),
+				testMailbox("[Gmail]/Spam", "/", `\Junk`),
+				testMailbox("Archive/2023", "/"),
+				testMailbox("Archive/2023/Q4", "/"),
+			},
+			want: []string{
+				`inbox [收件箱] parent=""`,
+				`junkemail [垃圾邮件] parent=""`,
+				`Archive/2023 [Archive/2023] parent=""`,
+				`  Archive/2023/Q4 [Q4] parent="Archive/2023"`,
+			},
+		},
+		{
+			name: "NIL delimiter",
+			mailboxes: []imapMailbox{
+				testMailbox("INBOX", ""),
+				testMailbox("a/b", ""),
+				testMailbox("a.b", ""),
+			},
+			want: []string{
+				`inbox [收件箱] parent=""`,
+				`a/b [a/b] parent=""`,
+				`a.b [a.b] parent=""`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := flattenFolderTree(testFolderTree(tt.mailboxes), 0)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("folder tree mismatch\n got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestBuildFolderTreeDepthLimit(t *testing.T) {
+	var mailboxes []imapMailbox
+	name := ""
+	for i := 1; i <= maxFolderDepth+2; i++ {
+		if name != "" {
+			name += "/"
+		}
+		name += "L" + strconv.Itoa(i)
+		mailboxes = append(mailboxes, testMailbox(name, "/"))
+	}
+	tree := testFolderTree(mailboxes)
+	if len(tree) != 1 {
+		t.Fatalf("got %d top-level folders, want 1", len(tree))
+	}
+	depth := 0
+	for f := &tree[0]; ; f = &f.ChildFolders[0] {
+		depth++
+		if len(f.ChildFolders) == 0 {
+			if f.ChildFolderCount != 0 {
+				t.Errorf("ChildFolderCount = %d at depth %d, want 0", f.ChildFolderCount, depth)
+			}
+			break
+		}
+	}
+	if depth != maxFolderDepth {
+		t.Errorf("tree depth = %d, want %d", depth, maxFolderDepth)
+	}
+}
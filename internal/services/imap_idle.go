@@ -0,0 +1,341 @@
+// Package services 业务服务层
+//
+// imap_idle.go 新邮件监听服务（IMAP IDLE推送，RFC 2177）
+//
+// 功能说明：
+// - 每个被监听的账号使用一条独立的IMAP连接（不占用连接池），只读打开收件箱
+// - 服务器支持IDLE时等待服务器推送，每隔imapIdleRefresh重新发出IDLE，避免超过服务器29分钟的限制
+// - 服务器不支持IDLE时每隔imapPollInterval发送NOOP轮询
+// - 收件箱邮件数增加时按UID获取新邮件的摘要（发件人、主题、时间）并回调
+// - 连接断开后按指数退避自动重连
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"outlook-mail-manager/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	imapIdleRefresh  = 25 * time.Minute // 重新发出IDLE的间隔（RFC 2177建议不超过29分钟）
+	imapPollInterval = 2 * time.Minute  // 服务器不支持IDLE时的轮询间隔
+	watchRetryMin    = 10 * time.Second // 重连的初始等待时间
+	watchRetryMax    = 5 * time.Minute  // 重连的最大等待时间
+)
+
+// ErrStopWatch 凭据函数返回此错误（或包装此错误）时停止监听，不再重连（如账号已被删除）
+var ErrStopWatch = errors.New("stop watching")
+
+// WatchAuthFunc 获取监听连接的登录凭据，每次（重新）连接时调用，以便使用刷新后的Token
+//
+// ctx在停止监听时取消
+type WatchAuthFunc func(ctx context.Context) (IMAPAuth, error)
+
+// WatchNewMailFunc 发现新邮件时的回调，messages为新邮件摘要（最新的在前，没有正文）
+type WatchNewMailFunc func(accountID int64, messages []models.Message)
+
+// WatchService 新邮件监听服务
+type WatchService struct {
+	mu      sync.Mutex
+	watches map[int64]context.CancelFunc // 账号ID -> 停止监听的函数
+}
+
+// NewWatchService 创建新邮件监听服务实例
+//
+// 返回值：
+//   - *WatchService: 服务实例
+func NewWatchService() *WatchService {
+	return &WatchService{watches: make(map[int64]context.CancelFunc)}
+}
+
+// Watch 开始监听账号的收件箱，已在监听时不做任何操作
+//
+// 参数：
+//   - accountID: 账号ID
+//   - auth: 获取登录凭据的函数
+//   - onNew: 发现新邮件时的回调（在监听协程中调用）
+func (s *WatchService) Watch(accountID int64, auth WatchAuthFunc, onNew WatchNewMailFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.watches[accountID]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.watches[accountID] = cancel
+	go s.run(ctx, accountID, auth, onNew)
+}
+
+// Unwatch 停止监听账号的收件箱并关闭监听连接
+//
+// 参数：
+//   - accountID: 账号ID
+func (s *WatchService) Unwatch(accountID int64) {
+	s.mu.Lock()
+	cancel, ok := s.watches[accountID]
+	delete(s.watches, accountID)
+	s.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// Watching 判断账号是否正在被监听
+func (s *WatchService) Watching(accountID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.watches[accountID]
+	return ok
+}
+
+// Close 停止所有监听（应用关闭时调用）
+func (s *WatchService) Close() {
+	s.mu.Lock()
+	watches := s.watches
+	s.watches = make(map[int64]context.CancelFunc)
+	s.mu.Unlock()
+	for _, cancel := range watches {
+		cancel()
+	}
+}
+
+// run 监听循环：连接断开后按指数退避重连，直到监听被停止
+func (s *WatchService) run(ctx context.Context, accountID int64, auth WatchAuthFunc, onNew WatchNewMailFunc) {
+	wait := watchRetryMin
+	for {
+		connected, err := watchInbox(ctx, accountID, auth, onNew)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrStopWatch) {
+			log.Printf("[IMAP Watch] 停止监听 - accountID: %d, 原因: %v", accountID, err)
+			s.mu.Lock()
+			delete(s.watches, accountID)
+			s.mu.Unlock()
+			return
+		}
+		if connected {
+			wait = watchRetryMin // 成功连接过，从最短等待时间重新开始退避
+		}
+		log.Printf("[IMAP Watch] 连接中断，%v 后重连 - accountID: %d, error: %v", wait, accountID, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > watchRetryMax {
+			wait = watchRetryMax
+		}
+	}
+}
+
+// watchInbox 建立一条监听连接并等待新邮件，直到连接出错或监听被停止
+//
+// 返回值：
+//   - bool: 是否成功打开过收件箱（用于重置重连退避）
+//   - error: 连接中断的原因
+func watchInbox(ctx context.Context, accountID int64, auth WatchAuthFunc, onNew WatchNewMailFunc) (bool, error) {
+	a, err := auth(ctx)
+	if err != nil {
+		return false, err
+	}
+	client, err := newIMAPClient(ctx, a)
+	if err != nil {
+		return false, err
+	}
+	// 停止监听时直接关闭连接，使阻塞中的读取立即返回
+	stop := context.AfterFunc(ctx, func() { client.conn.Close() })
+	defer func() {
+		if stop() {
+			client.Close()
+		}
+	}()
+
+	caps, err := client.capabilities()
+	if err != nil {
+		return false, err
+	}
+	idle := strings.Contains(caps, " IDLE ")
+
+	// 只读打开收件箱，不影响邮件的\Recent标志
+	resps, err := client.command("EXAMINE INBOX")
+	if err != nil {
+		return false, err
+	}
+	uidNext, err := client.inboxUIDNext(resps)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("[IMAP Watch] 开始监听 - email: %s, IDLE: %v, UIDNEXT: %d", a.Email, idle, uidNext)
+
+	for {
+		var changed bool
+		if idle {
+			changed, err = client.idle(imapIdleRefresh)
+		} else {
+			changed, err = client.poll(ctx, imapPollInterval)
+		}
+		if err != nil {
+			return true, err
+		}
+		if !changed {
+			continue
+		}
+		messages, next, err := client.fetchNewSince(uidNext)
+		if err != nil {
+			return true, err
+		}
+		uidNext = next
+		if len(messages) > 0 {
+			log.Printf("[IMAP Watch] 收到 %d 封新邮件 - email: %s", len(messages), a.Email)
+			onNew(accountID, messages)
+		}
+	}
+}
+
+// inboxUIDNext 从SELECT/EXAMINE的响应中取得下一封邮件将使用的UID
+//
+// 服务器没有返回UIDNEXT响应码时，取当前最后一封邮件的UID加1
+func (c *IMAPClient) inboxUIDNext(resps []*imapResponse) (uint32, error) {
+	exists := uint32(0)
+	for _, r := range resps {
+		if n, ok := responseCodeNumber(r, "UIDNEXT"); ok {
+			return n, nil
+		}
+		if r.Name() == "EXISTS" {
+			exists, _ = imapNumber(r.Fields[0])
+		}
+	}
+	if exists == 0 {
+		return 1, nil
+	}
+	fetchResp, err := c.command(fmt.Sprintf("FETCH %d (UID)", exists))
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range fetchResp {
+		if r.Name() == "FETCH" && len(r.Fields) >= 3 {
+			if uid, ok := imapNumber(imapPairs(imapList(r.Fields[2]))["UID"]); ok {
+				return uid + 1, nil
+			}
+		}
+	}
+	return 1, nil
+}
+
+// idle 发出IDLE命令并等待服务器推送，收到EXISTS或到达refresh时间后发送DONE结束
+//
+// 参数：
+//   - refresh: 最长等待时间，到达后结束本次IDLE（调用方随后重新发出IDLE）
+//
+// 返回值：
+//   - bool: 收件箱是否有新邮件（收到EXISTS响应）
+//   - error: 连接错误或服务器拒绝IDLE时返回错误
+func (c *IMAPClient) idle(refresh time.Duration) (bool, error) {
+	c.tagNum++
+	tag := fmt.Sprintf("A%03d", c.tagNum)
+	if _, err := c.conn.Write([]byte(tag + " IDLE\r\n")); err != nil {
+		return false, err
+	}
+
+	// DONE可能由计时器goroutine发送，写入错误通过带缓冲的通道传回读取循环
+	var doneOnce sync.Once
+	writeErr := make(chan error, 1)
+	done := func() {
+		doneOnce.Do(func() {
+			_, err := c.conn.Write([]byte("DONE\r\n"))
+			writeErr <- err
+		})
+	}
+	timer := time.AfterFunc(refresh, done)
+	defer timer.Stop()
+
+	changed := false
+	for {
+		// 等待时间内没有任何响应（包括DONE之后的完成响应）视为连接已失效
+		c.conn.SetReadDeadline(time.Now().Add(refresh + time.Minute))
+		resp, err := c.reader.ReadResponse()
+		if err != nil {
+			return changed, err
+		}
+		switch {
+		case resp.Tag == tag:
+			if resp.Status != "OK" {
+				return changed, statusError(resp)
+			}
+			// 计时器已触发时等待其中的写入结束（尚未开始写入时不再发送DONE）
+			timer.Stop()
+			doneOnce.Do(func() {})
+			select {
+			case err := <-writeErr:
+				return changed, err
+			default:
+				return changed, nil
+			}
+		case resp.Tag == "+":
+			// 服务器已进入IDLE状态
+		case resp.Status == "BYE":
+			return changed, statusError(resp)
+		case resp.Name() == "EXISTS":
+			changed = true
+			done()
+		}
+	}
+}
+
+// poll 等待interval后发送NOOP，用于不支持IDLE的服务器
+//
+// 返回值：
+//   - bool: 收件箱是否有新邮件（NOOP期间收到EXISTS响应）
+//   - error: 连接错误或监听被停止时返回错误
+func (c *IMAPClient) poll(ctx context.Context, interval time.Duration) (bool, error) {
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-time.After(interval):
+	}
+	resps, err := c.command("NOOP")
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resps {
+		if r.Name() == "EXISTS" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fetchNewSince 获取UID不小于uidNext的邮件摘要
+//
+// 参数：
+//   - uidNext: 上次记录的下一个UID
+//
+// 返回值：
+//   - []models.Message: 新邮件摘要（最新的在前）
+//   - uint32: 更新后的下一个UID
+//   - error: 命令失败时返回错误
+func (c *IMAPClient) fetchNewSince(uidNext uint32) ([]models.Message, uint32, error) {
+	// "n:*"在没有UID>=n的邮件时也会返回最后一封邮件，需要按UID过滤
+	resps, err := c.command(fmt.Sprintf("UID FETCH %d:* (UID FLAGS BODY.PEEK[HEADER.FIELDS (FROM SUBJECT DATE)])", uidNext))
+	if err != nil {
+		return nil, uidNext, err
+	}
+	var messages []models.Message
+	next := uidNext
+	for _, msg := range parseMessages(resps) {
+		uid, ok := imapNumber(msg.ID)
+		if !ok || uid < uidNext {
+			continue
+		}
+		if uid >= next {
+			next = uid + 1
+		}
+		messages = append(messages, msg)
+	}
+	return messages, next, nil
+}
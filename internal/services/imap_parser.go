@@ -0,0 +1,398 @@
+// Package services 业务服务层
+//
+// imap_parser.go IMAP服务器响应的词法和语法解析（RFC 3501 第9节）
+//
+// 功能说明：
+// - 逐条读取服务器响应：标签响应（A001 OK ...）、非标签响应（* ...）、继续请求（+ ...）
+// - 解析原子、带引号字符串、字面量（{n}后跟n字节，可跨行、可包含任意内容）、括号列表和NIL
+// - 状态响应（OK/NO/BAD/BYE/PREAUTH）拆分出方括号响应码和文本
+//
+// 值的表示：
+// - 原子、数字、带引号字符串、字面量：string
+// - NIL：nil
+// - 括号列表：[]interface{}
+//
+// 原子中的方括号部分作为原子的一部分，如 BODY[HEADER.FIELDS (FROM SUBJECT)] 和 BODY[]<0>
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxIMAPLiteral 单个字面量的最大字节数，防止异常响应耗尽内存
+const maxIMAPLiteral = 64 << 20
+
+// imapResponse 一条服务器响应
+type imapResponse struct {
+	Tag    string        // 命令标签；"*"表示非标签响应，"+"表示继续请求
+	Status string        // 状态响应的状态（OK/NO/BAD/BYE/PREAUTH，大写），数据响应为空
+	Code   string        // 状态响应方括号内的响应码（如 "UIDVALIDITY 3"），没有时为空
+	Text   string        // 状态响应或继续请求的文本
+	Fields []interface{} // 数据响应的各字段（标签之后），如 [172 EXISTS] 或 [3 FETCH (...)]
+}
+
+// Name 返回数据响应的类型（大写）
+//
+// 如 "* LIST ..." 返回LIST，"* 3 FETCH ..." 返回FETCH，"* 172 EXISTS" 返回EXISTS
+func (r *imapResponse) Name() string {
+	for i, f := range r.Fields {
+		s, ok := f.(string)
+		if !ok {
+			return ""
+		}
+		if i == 0 {
+			if _, err := strconv.ParseUint(s, 10, 32); err == nil {
+				continue // 消息序号
+			}
+		}
+		return strings.ToUpper(s)
+	}
+	return ""
+}
+
+// imapReader IMAP响应读取器
+type imapReader struct {
+	r *bufio.Reader
+}
+
+// newIMAPReader 创建IMAP响应读取器
+func newIMAPReader(r io.Reader) *imapReader {
+	return &imapReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// ReadResponse 读取并解析一条完整响应（包括其中的所有字面量）
+//
+// 返回值：
+//   - *imapResponse: 解析后的响应
+//   - error: 连接错误或响应格式错误
+func (p *imapReader) ReadResponse() (*imapResponse, error) {
+	tag, err := p.readAtom()
+	if err != nil {
+		return nil, err
+	}
+	if tag == "" {
+		return nil, p.syntaxError("missing tag")
+	}
+	resp := &imapResponse{Tag: tag}
+	if tag == "+" {
+		p.skipSpace()
+		resp.Text, err = p.readLine()
+		return resp, err
+	}
+	if err := p.expectSpace(); err != nil {
+		return nil, err
+	}
+
+	// 状态响应：标签之后是OK/NO/BAD/BYE/PREAUTH
+	b, err := p.r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] != '(' && b[0] != '"' && b[0] != '{' {
+		word, err := p.readAtom()
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToUpper(word) {
+		case "OK", "NO", "BAD", "BYE", "PREAUTH":
+			resp.Status = strings.ToUpper(word)
+			return resp, p.readRespText(resp)
+		}
+		resp.Fields = append(resp.Fields, word)
+	}
+
+	// 数据响应：逐个读取字段直到行尾
+	for {
+		end, err := p.atLineEnd()
+		if err != nil {
+			return nil, err
+		}
+		if end {
+			return resp, nil
+		}
+		if len(resp.Fields) > 0 {
+			if err := p.expectSpace(); err != nil {
+				return nil, err
+			}
+		}
+		v, err := p.readValue()
+		if err != nil {
+			return nil, err
+		}
+		resp.Fields = append(resp.Fields, v)
+	}
+}
+
+// readRespText 读取状态响应的 [响应码] 和文本，直到行尾
+func (p *imapReader) readRespText(resp *imapResponse) error {
+	p.skipSpace()
+	line, err := p.readLine()
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(line, "[") {
+		if end := strings.IndexByte(line, ']'); end > 0 {
+			resp.Code = line[1:end]
+			line = strings.TrimLeft(line[end+1:], " ")
+		}
+	}
+	resp.Text = line
+	return nil
+}
+
+// readValue 读取一个值：括号列表、带引号字符串、字面量、NIL或原子
+func (p *imapReader) readValue() (interface{}, error) {
+	b, err := p.r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch b[0] {
+	case '(':
+		return p.readList()
+	case '"':
+		return p.readQuoted()
+	case '{':
+		return p.readLiteral()
+	}
+	atom, err := p.readAtom()
+	if err != nil {
+		return nil, err
+	}
+	if atom == "" {
+		return nil, p.syntaxError(fmt.Sprintf("unexpected %q", b[0]))
+	}
+	if strings.EqualFold(atom, "NIL") {
+		return nil, nil
+	}
+	return atom, nil
+}
+
+// readList 读取括号列表（元素之间以单个空格分隔，可以嵌套）
+func (p *imapReader) readList() ([]interface{}, error) {
+	p.r.ReadByte() // '('
+	list := []interface{}{}
+	for {
+		b, err := p.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		switch b[0] {
+		case ')':
+			p.r.ReadByte()
+			return list, nil
+		case ' ':
+			p.r.ReadByte()
+			continue
+		case '\r', '\n':
+			return nil, p.syntaxError("unterminated list")
+		}
+		v, err := p.readValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+// readQuoted 读取带引号字符串，处理 \" 和 \\ 转义
+func (p *imapReader) readQuoted() (string, error) {
+	p.r.ReadByte() // '"'
+	var sb strings.Builder
+	for {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			c, err = p.r.ReadByte()
+			if err != nil {
+				return "", err
+			}
+		case '\r', '\n':
+			return "", p.syntaxError("unterminated quoted string")
+		}
+		sb.WriteByte(c)
+	}
+}
+
+// readLiteral 读取字面量：{n}（或LITERAL+的{n+}）、CRLF，之后的n个字节原样作为值
+func (p *imapReader) readLiteral() (string, error) {
+	p.r.ReadByte() // '{'
+	spec, err := p.r.ReadString('}')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(spec[:len(spec)-1], "+"))
+	if err != nil || n < 0 {
+		return "", p.syntaxError("invalid literal length {" + spec)
+	}
+	if n > maxIMAPLiteral {
+		return "", fmt.Errorf("IMAP literal too large: %d bytes", n)
+	}
+	if err := p.readCRLF(); err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(p.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// readAtom 读取原子（到空格、括号、引号、行尾为止），方括号内的内容（可含空格和括号）属于原子
+func (p *imapReader) readAtom() (string, error) {
+	var sb strings.Builder
+	depth := 0 // 方括号嵌套深度
+	for {
+		b, err := p.r.Peek(1)
+		if err != nil {
+			if err == io.EOF && sb.Len() > 0 {
+				return sb.String(), nil
+			}
+			return "", err
+		}
+		c := b[0]
+		if c == '\r' || c == '\n' {
+			return sb.String(), nil
+		}
+		if depth == 0 && (c == ' ' || c == '(' || c == ')' || c == '"' || c == '{' || c == ']') {
+			return sb.String(), nil
+		}
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+		p.r.ReadByte()
+		sb.WriteByte(c)
+	}
+}
+
+// readLine 读取到行尾的文本（不含CRLF）
+func (p *imapReader) readLine() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// atLineEnd 判断是否到达行尾，到达时消费CRLF
+func (p *imapReader) atLineEnd() (bool, error) {
+	b, err := p.r.Peek(1)
+	if err != nil {
+		return false, err
+	}
+	if b[0] != '\r' && b[0] != '\n' {
+		return false, nil
+	}
+	return true, p.readCRLF()
+}
+
+// readCRLF 读取行结束符（兼容只有LF的服务器）
+func (p *imapReader) readCRLF() error {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if c == '\r' {
+		c, err = p.r.ReadByte()
+		if err != nil {
+			return err
+		}
+	}
+	if c != '\n' {
+		return p.syntaxError("expected CRLF")
+	}
+	return nil
+}
+
+// skipSpace 跳过空格
+func (p *imapReader) skipSpace() {
+	for {
+		b, err := p.r.Peek(1)
+		if err != nil || b[0] != ' ' {
+			return
+		}
+		p.r.ReadByte()
+	}
+}
+
+// expectSpace 读取一个空格
+func (p *imapReader) expectSpace() error {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if c != ' ' {
+		p.r.UnreadByte()
+		return p.syntaxError(fmt.Sprintf("expected space, got %q", c))
+	}
+	return nil
+}
+
+// syntaxError 生成响应格式错误，并丢弃当前行剩余内容，使读取器可以继续读取下一条响应
+func (p *imapReader) syntaxError(msg string) error {
+	rest, _ := p.r.ReadString('\n')
+	return fmt.Errorf("IMAP syntax error: %s (near %q)", msg, strings.TrimRight(rest, "\r\n"))
+}
+
+// imapString 将值转换为字符串，NIL和列表返回空字符串
+func imapString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// imapNumber 将值转换为非负整数，不是数字时返回0和false
+func imapNumber(v interface{}) (uint32, bool) {
+	n, err := strconv.ParseUint(imapString(v), 10, 32)
+	return uint32(n), err == nil
+}
+
+// imapList 将值转换为列表，不是列表时返回nil
+func imapList(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+// imapPairs 将 (名称 值 名称 值 ...) 形式的列表转换为map，名称转为大写
+//
+// 用于FETCH响应的数据项和STATUS响应的计数
+func imapPairs(list []interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(list)/2)
+	for i := 0; i+1 < len(list); i += 2 {
+		m[strings.ToUpper(imapString(list[i]))] = list[i+1]
+	}
+	return m
+}
+
+// fetchItem 返回FETCH数据项中第一个名称以prefix开头的值（如 "BODY[" 匹配任何正文段）
+func fetchItem(items map[string]interface{}, prefix string) (interface{}, bool) {
+	if v, ok := items[prefix]; ok {
+		return v, true
+	}
+	for k, v := range items {
+		if strings.HasPrefix(k, prefix) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// hasFlag 判断FLAGS列表是否包含指定标志（不区分大小写）
+func hasFlag(flags []interface{}, flag string) bool {
+	for _, f := range flags {
+		if strings.EqualFold(imapString(f), flag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// literal 按IMAP字面量格式编码内容：{n}CRLF后跟n字节
+func literal(s string) string {
+	return "{" + strconv.Itoa(len(s)) + "}\r\n" + s
+}
+
+// readAllResponses 读取记录中的所有响应，直到EOF
+func readAllResponses(r io.Reader) ([]*imapResponse, error) {
+	reader := newIMAPReader(r)
+	var resps []*imapResponse
+	for {
+		resp, err := reader.ReadResponse()
+		if err == io.EOF {
+			return resps, nil
+		}
+		if err != nil {
+			return resps, err
+		}
+		resps = append(resps, resp)
+	}
+}
+
+// 邮件正文中包含与命令标签相同的"A005 OK"行，不能被当作命令完成
+var bodyWithTag = "Subject: test\r\n\r\nA005 OK looks like a tagged reply\r\n* BYE not really\r\n"
+
+func TestReadResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		transcript string
+		want       []*imapResponse
+	}{
+		{
+			name:       "tagged OK with response code",
+			transcript: "A001 OK [READ-WRITE] SELECT completed\r\n",
+			want:       []*imapResponse{{Tag: "A001", Status: "OK", Code: "READ-WRITE", Text: "SELECT completed"}},
+		},
+		{
+			name:       "tagged NO without code",
+			transcript: "A002 NO Mailbox does not exist\r\n",
+			want:       []*imapResponse{{Tag: "A002", Status: "NO", Text: "Mailbox does not exist"}},
+		},
+		{
+			name:       "untagged status with numeric code",
+			transcript: "* OK [UIDVALIDITY 3857529045] UIDs valid\r\n",
+			want:       []*imapResponse{{Tag: "*", Status: "OK", Code: "UIDVALIDITY 3857529045", Text: "UIDs valid"}},
+		},
+		{
+			name:       "untagged data",
+			transcript: "* 172 EXISTS\r\n* 1 RECENT\r\n",
+			want: []*imapResponse{
+				{Tag: "*", Fields: []interface{}{"172", "EXISTS"}},
+				{Tag: "*", Fields: []interface{}{"1", "RECENT"}},
+			},
+		},
+		{
+			name:       "continuation request",
+			transcript: "+ idling\r\n+\r\n",
+			want:       []*imapResponse{{Tag: "+", Text: "idling"}, {Tag: "+"}},
+		},
+		{
+			name: "literal body containing a tagged line",
+			transcript: "* 1 FETCH (UID 7 FLAGS (\\Seen) BODY[] " + literal(bodyWithTag) + ")\r\n" +
+				"A005 OK FETCH completed\r\n",
+			want: []*imapResponse{
+				{Tag: "*", Fields: []interface{}{"1", "FETCH", []interface{}{"UID", "7", "FLAGS", []interface{}{`\Seen`}, "BODY[]", bodyWithTag}}},
+				{Tag: "A005", Status: "OK", Text: "FETCH completed"},
+			},
+		},
+		{
+			name:       "empty literal",
+			transcript: "* 2 FETCH (UID 8 BODY[HEADER] {0}\r\n)\r\n",
+			want:       []*imapResponse{{Tag: "*", Fields: []interface{}{"2", "FETCH", []interface{}{"UID", "8", "BODY[HEADER]", ""}}}},
+		},
+		{
+			name:       "LITERAL+ non-synchronizing literal",
+			transcript: "* LIST () \"/\" {4+}\r\nTemp\r\n",
+			want:       []*imapResponse{{Tag: "*", Fields: []interface{}{"LIST", []interface{}{}, "/", "Temp"}}},
+		},
+		{
+			name: "section atom with spaces and parentheses",
+			transcript: "* 3 FETCH (UID 9 BODY[HEADER.FIELDS (FROM SUBJECT DATE)] " +
+				literal("From: a@example.com\r\n\r\n") + ")\r\n",
+			want: []*imapResponse{{Tag: "*", Fields: []interface{}{"3", "FETCH", []interface{}{
+				"UID", "9", "BODY[HEADER.FIELDS (FROM SUBJECT DATE)]", "From: a@example.com\r\n\r\n"}}}},
+		},
+		{
+			name: "nested lists",
+			transcript: "* 4 FETCH (UID 10 BODYSTRUCTURE ((\"TEXT\" \"PLAIN\" (\"CHARSET\" \"UTF-8\") NIL NIL \"7BIT\" 12 1)" +
+				"(\"TEXT\" \"HTML\" NIL NIL NIL \"QUOTED-PRINTABLE\" 40 2) \"ALTERNATIVE\"))\r\n",
+			want: []*imapResponse{{Tag: "*", Fields: []interface{}{"4", "FETCH", []interface{}{
+				"UID", "10", "BODYSTRUCTURE", []interface{}{
+					[]interface{}{"TEXT", "PLAIN", []interface{}{"CHARSET", "UTF-8"}, nil, nil, "7BIT", "12", "1"},
+					[]interface{}{"TEXT", "HTML", nil, nil, nil, "QUOTED-PRINTABLE", "40", "2"},
+					"ALTERNATIVE",
+				}}}}},
+		},
+		{
+			name:       "quoted string with escapes",
+			transcript: "* LIST (\\HasNoChildren) \"/\" \"Tom \\\"Jr\\\" \\\\ Folder\"\r\n",
+			want:       []*imapResponse{{Tag: "*", Fields: []interface{}{"LIST", []interface{}{`\HasNoChildren`}, "/", `Tom "Jr" \ Folder`}}},
+		},
+		{
+			name:       "NIL delimiter and empty quoted string",
+			transcript: "* LIST (\\Noselect) NIL \"\"\r\n",
+			want:       []*imapResponse{{Tag: "*", Fields: []interface{}{"LIST", []interface{}{`\Noselect`}, nil, ""}}},
+		},
+		{
+			name:       "search results",
+			transcript: "* SEARCH 3 5 8\r\n* SEARCH\r\n",
+			want: []*imapResponse{
+				{Tag: "*", Fields: []interface{}{"SEARCH", "3", "5", "8"}},
+				{Tag: "*", Fields: []interface{}{"SEARCH"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAllResponses(strings.NewReader(tt.transcript))
+			if err != nil {
+				t.Fatalf("ReadResponse error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("responses mismatch\n got: %#v\nwant: %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReadResponseSelectTranscript 一次完整的SELECT记录（Outlook服务器）
+func TestReadResponseSelectTranscript(t *testing.T) {
+	transcript := "* 172 EXISTS\r\n" +
+		"* 0 RECENT\r\n" +
+		"* FLAGS (\\Seen \\Answered \\Flagged \\Deleted \\Draft $MDNSent)\r\n" +
+		"* OK [PERMANENTFLAGS (\\Seen \\Answered \\Flagged \\Deleted \\Draft $MDNSent)] Permanent flags\r\n" +
+		"* OK [UNSEEN 3] Is the first unseen message\r\n" +
+		"* OK [UIDVALIDITY 14] UIDVALIDITY value\r\n" +
+		"* OK [UIDNEXT 2401] The next unique identifier value\r\n" +
+		"A003 OK [READ-WRITE] SELECT completed.\r\n"
+	resps, err := readAllResponses(strings.NewReader(transcript))
+	if err != nil {
+		t.Fatalf("ReadResponse error: %v", err)
+	}
+	if len(resps) != 8 {
+		t.Fatalf("got %d responses, want 8", len(resps))
+	}
+	if resps[0].Name() != "EXISTS" || resps[2].Name() != "FLAGS" {
+		t.Errorf("names = %q, %q", resps[0].Name(), resps[2].Name())
+	}
+	if resps[5].Status != "OK" || resps[5].Code != "UIDVALIDITY 14" {
+		t.Errorf("UIDVALIDITY response = %#v", resps[5])
+	}
+	if resps[3].Code != `PERMANENTFLAGS (\Seen \Answered \Flagged \Deleted \Draft $MDNSent)` {
+		t.Errorf("PERMANENTFLAGS code = %q", resps[3].Code)
+	}
+	if last := resps[7]; last.Tag != "A003" || last.Status != "OK" || last.Code != "READ-WRITE" {
+		t.Errorf("tagged response = %#v", last)
+	}
+}
+
+// TestReadResponseSplitReads 字面量和响应行跨越多次读取
+func TestReadResponseSplitReads(t *testing.T) {
+	big := strings.Repeat("0123456789abcdef\r\n", 8192) // 大于读取器的缓冲区
+	transcript := "* 1 FETCH (UID 7 BODY[] " + literal(bodyWithTag) + ")\r\n" +
+		"* 2 FETCH (UID 8 BODY[] " + literal(big) + ")\r\n" +
+		"A005 OK FETCH completed\r\n"
+
+	readers := map[string]func(io.Reader) io.Reader{
+		"one byte": iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+	}
+	for name, wrap := range readers {
+		t.Run(name, func(t *testing.T) {
+			resps, err := readAllResponses(wrap(strings.NewReader(transcript)))
+			if err != nil {
+				t.Fatalf("ReadResponse error: %v", err)
+			}
+			if len(resps) != 3 {
+				t.Fatalf("got %d responses, want 3", len(resps))
+			}
+			for i, want := range []string{bodyWithTag, big} {
+				items := imapPairs(imapList(resps[i].Fields[2]))
+				if body, _ := fetchItem(items, "BODY["); imapString(body) != want {
+					t.Errorf("literal %d: got %d bytes, want %d", i, len(imapString(body)), len(want))
+				}
+			}
+			if resps[2].Tag != "A005" || resps[2].Status != "OK" {
+				t.Errorf("tagged response = %#v", resps[2])
+			}
+		})
+	}
+}
+
+func TestReadResponseErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		transcript string
+		wantErr    string
+	}{
+		{"literal over 64 MB cap", "* 1 FETCH (BODY[] {" + strconv.Itoa(maxIMAPLiteral+1) + "}\r\n", "literal too large"},
+		{"invalid literal length", "* 1 FETCH (BODY[] {abc}\r\n", "invalid literal length"},
+		{"negative literal length", "* 1 FETCH (BODY[] {-1}\r\n", "invalid literal length"},
+		{"unterminated quoted string", "* LIST () \"/\" \"Inbox\r\n", "unterminated quoted string"},
+		{"unterminated list", "* 1 FETCH (UID 1\r\n", "unterminated list"},
+		{"truncated literal", "* 1 FETCH (BODY[] {10}\r\nshort", "EOF"},
+		{"missing tag", " OK\r\n", "missing tag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newIMAPReader(strings.NewReader(tt.transcript)).ReadResponse()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestReadResponseLiteralAtCap 正好64 MB的字面量可以读取
+func TestReadResponseLiteralAtCap(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocates a 64 MB literal")
+	}
+	r := io.MultiReader(
+		strings.NewReader("* 1 FETCH (BODY[] {"+strconv.Itoa(maxIMAPLiteral)+"}\r\n"),
+		io.LimitReader(zeroReader{}, maxIMAPLiteral),
+		strings.NewReader(")\r\n"),
+	)
+	resp, err := newIMAPReader(r).ReadResponse()
+	if err != nil {
+		t.Fatalf("ReadResponse error: %v", err)
+	}
+	body, _ := fetchItem(imapPairs(imapList(resp.Fields[2])), "BODY[")
+	if n := len(imapString(body)); n != maxIMAPLiteral {
+		t.Errorf("literal length = %d, want %d", n, maxIMAPLiteral)
+	}
+}
+
+// zeroReader 无限产生零字节
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,323 @@
+// Package services 业务服务层
+//
+// imap_pool.go IMAP连接池
+//
+// 功能说明：
+// - 借出/归还：每条连接同一时间只借给一个调用方，避免并发调用在同一连接上交错发送SELECT/FETCH
+// - 连接数限制：每个账号最多imapPoolMaxPerAccount条（Outlook限制每个邮箱的并发IMAP会话数），所有账号最多imapPoolMaxTotal条
+// - 达到上限时等待其他调用方归还；总连接数已满时关闭其他账号最久未使用的空闲连接
+// - 健康检查：空闲超过imapPoolCheckAfter的连接借出前发送NOOP，失败则重新建立连接
+// - 空闲清理：后台定期关闭空闲超过imapPoolIdleTimeout的连接
+// - 应用关闭时关闭所有连接（新邮件监听使用独立连接，不经过连接池）
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	imapPoolMaxPerAccount = 3                // 每个账号的最大连接数（给用户自己的邮件客户端留出会话余量）
+	imapPoolMaxTotal      = 20               // 所有账号的最大连接数
+	imapPoolIdleTimeout   = 5 * time.Minute  // 空闲超过此时间的连接被关闭
+	imapPoolCheckAfter    = 30 * time.Second // 空闲超过此时间的连接借出前发送NOOP检测
+	imapPoolWaitTimeout   = time.Minute      // 连接数达到上限时等待归还的最长时间
+	imapPoolReapInterval  = time.Minute      // 清理空闲连接的间隔
+	imapLogoutTimeout     = 3 * time.Second  // 关闭连接时等待LOGOUT响应的最长时间
+)
+
+// ErrPoolClosed 连接池已关闭（应用正在退出）
+var ErrPoolClosed = errors.New("IMAP connection pool closed")
+
+// ErrPoolTimeout 连接数达到上限，等待其他操作归还连接超时
+var ErrPoolTimeout = errors.New("timed out waiting for an IMAP connection")
+
+// pooledClient 池化的IMAP客户端
+type pooledClient struct {
+	client    *IMAPClient
+	email     string
+	secret    string // 建立连接时使用的凭据（见IMAPAuth.secret）
+	lastUsed  time.Time
+	createdAt time.Time
+}
+
+// imapPool IMAP连接池
+type imapPool struct {
+	mu      sync.Mutex
+	idle    map[string][]*pooledClient    // 空闲连接: email -> 连接（最近归还的在后）
+	busy    map[*IMAPClient]*pooledClient // 已借出的连接
+	open    map[string]int                // 各账号的连接数（空闲、借出和正在建立的）
+	total   int                           // 所有账号的连接数
+	closed  bool
+	changed chan struct{} // 连接归还或关闭时关闭并替换，唤醒等待连接的调用方
+	stop    chan struct{} // 停止后台清理
+}
+
+// newIMAPPool 创建连接池并启动后台空闲清理
+func newIMAPPool() *imapPool {
+	p := &imapPool{
+		idle:    make(map[string][]*pooledClient),
+		busy:    make(map[*IMAPClient]*pooledClient),
+		open:    make(map[string]int),
+		changed: make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	go p.reapLoop()
+	return p
+}
+
+// get 借出一条已登录的连接，用完后必须调用put归还
+//
+// 优先复用该账号最近归还的空闲连接（凭据已变化的连接直接关闭）；
+// 没有空闲连接且未达到连接数上限时建立新连接，否则等待其他调用方归还
+//
+// 参数：
+//   - ctx: 上下文，取消时停止等待或建立连接
+//   - auth: 登录凭据
+//
+// 返回值：
+//   - *IMAPClient: 已登录的连接
+//   - error: 建立连接失败、等待超时（ErrPoolTimeout）、连接池已关闭（ErrPoolClosed）或ctx的错误
+func (p *imapPool) get(ctx context.Context, auth IMAPAuth) (*IMAPClient, error) {
+	email := auth.Email
+	deadline := time.Now().Add(imapPoolWaitTimeout)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+
+		// 复用空闲连接
+		if list := p.idle[email]; len(list) > 0 {
+			pc := list[len(list)-1]
+			p.idle[email] = list[:len(list)-1]
+			p.busy[pc.client] = pc
+			p.mu.Unlock()
+
+			if pc.secret != auth.secret() {
+				log.Printf("[IMAP Pool] 凭据已变化，关闭旧连接 - email: %s", email)
+				p.discard(pc)
+				continue
+			}
+			if idle := time.Since(pc.lastUsed); idle > imapPoolCheckAfter {
+				if _, err := pc.client.command("NOOP"); err != nil {
+					log.Printf("[IMAP Pool] 空闲连接已失效，关闭 - email: %s, idle: %v, error: %v", email, idle, err)
+					p.discard(pc)
+					continue
+				}
+			}
+			pc.lastUsed = time.Now()
+			log.Printf("[IMAP Pool] 复用空闲连接 - email: %s", email)
+			return pc.client, nil
+		}
+
+		// 建立新连接
+		if p.open[email] < imapPoolMaxPerAccount && (p.total < imapPoolMaxTotal || p.evictLocked()) {
+			p.open[email]++
+			p.total++
+			p.mu.Unlock()
+			return p.dial(ctx, auth)
+		}
+
+		// 等待其他调用方归还连接
+		wait := p.changed
+		p.mu.Unlock()
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			log.Printf("[IMAP Pool] 等待连接超时 - email: %s", email)
+			return nil, ErrPoolTimeout
+		}
+		log.Printf("[IMAP Pool] 连接数已达上限，等待归还 - email: %s", email)
+		timer := time.NewTimer(remaining)
+		select {
+		case <-wait:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+	}
+}
+
+// dial 建立新连接（调用前已为其占用连接数）
+func (p *imapPool) dial(ctx context.Context, auth IMAPAuth) (*IMAPClient, error) {
+	log.Printf("[IMAP Pool] 创建新连接 - email: %s", auth.Email)
+	client, err := newIMAPClient(ctx, auth)
+
+	p.mu.Lock()
+	if err != nil || p.closed {
+		p.releaseLocked(auth.Email)
+		p.mu.Unlock()
+		if err != nil {
+			log.Printf("[IMAP Pool] 创建连接失败 - email: %s, error: %v", auth.Email, err)
+			return nil, err
+		}
+		closeIMAPClient(client)
+		return nil, ErrPoolClosed
+	}
+	now := time.Now()
+	p.busy[client] = &pooledClient{
+		client:    client,
+		email:     auth.Email,
+		secret:    auth.secret(),
+		lastUsed:  now,
+		createdAt: now,
+	}
+	log.Printf("[IMAP Pool] 创建连接成功 - email: %s, 账号连接数: %d, 总连接数: %d", auth.Email, p.open[auth.Email], p.total)
+	p.mu.Unlock()
+	return client, nil
+}
+
+// put 归还连接
+//
+// 命令执行中发生连接错误（读写失败、超时）的连接直接关闭，其他连接放回空闲列表
+func (p *imapPool) put(client *IMAPClient) {
+	p.mu.Lock()
+	pc, ok := p.busy[client]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.busy, client)
+	if p.closed || client.broken {
+		p.releaseLocked(pc.email)
+		p.mu.Unlock()
+		if client.broken {
+			log.Printf("[IMAP Pool] 连接出错，关闭 - email: %s", pc.email)
+		}
+		go closeIMAPClient(client)
+		return
+	}
+	pc.lastUsed = time.Now()
+	p.idle[pc.email] = append(p.idle[pc.email], pc)
+	p.notifyLocked()
+	p.mu.Unlock()
+}
+
+// discard 关闭一条已借出的连接
+func (p *imapPool) discard(pc *pooledClient) {
+	p.mu.Lock()
+	delete(p.busy, pc.client)
+	p.releaseLocked(pc.email)
+	p.mu.Unlock()
+	go closeIMAPClient(pc.client)
+}
+
+// releaseLocked 释放一条连接占用的连接数并唤醒等待者（调用方持有p.mu）
+func (p *imapPool) releaseLocked(email string) {
+	if p.open[email]--; p.open[email] <= 0 {
+		delete(p.open, email)
+	}
+	p.total--
+	p.notifyLocked()
+}
+
+// notifyLocked 唤醒所有等待连接的调用方（调用方持有p.mu）
+func (p *imapPool) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// evictLocked 总连接数已满时关闭最久未使用的一条空闲连接（调用方持有p.mu）
+//
+// 返回值：
+//   - bool: 是否关闭了连接（没有空闲连接时返回false）
+func (p *imapPool) evictLocked() bool {
+	var oldest *pooledClient
+	for _, list := range p.idle {
+		if len(list) > 0 && (oldest == nil || list[0].lastUsed.Before(oldest.lastUsed)) {
+			oldest = list[0]
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	p.idle[oldest.email] = p.idle[oldest.email][1:]
+	p.releaseLocked(oldest.email)
+	log.Printf("[IMAP Pool] 总连接数已满，关闭空闲连接 - email: %s", oldest.email)
+	go closeIMAPClient(oldest.client)
+	return true
+}
+
+// reapLoop 定期关闭空闲超时的连接，直到连接池关闭
+func (p *imapPool) reapLoop() {
+	ticker := time.NewTicker(imapPoolReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+		var expired []*pooledClient
+		p.mu.Lock()
+		for email, list := range p.idle {
+			// 空闲列表按归还时间排序，过期的连接在前
+			n := 0
+			for n < len(list) && time.Since(list[n].lastUsed) > imapPoolIdleTimeout {
+				n++
+			}
+			for _, pc := range list[:n] {
+				expired = append(expired, pc)
+				p.releaseLocked(email)
+			}
+			if n == len(list) {
+				delete(p.idle, email)
+			} else {
+				p.idle[email] = list[n:]
+			}
+		}
+		p.mu.Unlock()
+		for _, pc := range expired {
+			log.Printf("[IMAP Pool] 关闭空闲超时连接 - email: %s, idle: %v", pc.email, time.Since(pc.lastUsed))
+			closeIMAPClient(pc.client)
+		}
+	}
+}
+
+// close 关闭连接池和所有连接
+//
+// 空闲连接发送LOGOUT后关闭；已借出的连接直接断开，正在执行的命令立即返回错误
+func (p *imapPool) close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.stop)
+	var idle []*pooledClient
+	for _, list := range p.idle {
+		idle = append(idle, list...)
+	}
+	p.idle = make(map[string][]*pooledClient)
+	for client := range p.busy {
+		client.conn.Close()
+	}
+	p.notifyLocked()
+	p.mu.Unlock()
+
+	log.Printf("[IMAP Pool] 关闭连接池 - 空闲连接: %d", len(idle))
+	var wg sync.WaitGroup
+	for _, pc := range idle {
+		wg.Add(1)
+		go func(c *IMAPClient) {
+			defer wg.Done()
+			closeIMAPClient(c)
+		}(pc.client)
+	}
+	wg.Wait()
+}
+
+// closeIMAPClient 发送LOGOUT并关闭连接，最多等待imapLogoutTimeout
+func closeIMAPClient(c *IMAPClient) {
+	timer := time.AfterFunc(imapLogoutTimeout, func() { c.conn.Close() })
+	defer timer.Stop()
+	c.Close()
+}
@@ -1,53 +1,58 @@
 // Package services 业务服务层
 //
 // imap_service.go IMAP邮件服务（用于Hotmail等个人账户）
+//
+// 支持两种认证方式：
+// - XOAUTH2：使用OAuth2访问令牌（Outlook账号）
+// - 密码：AUTHENTICATE PLAIN或LOGIN（imap-password协议，非微软邮箱或应用专用密码）
 package services
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"fmt"
-	"io"
 	"log"
-	"mime"
-	"mime/quotedprintable"
 	"net"
 	"outlook-mail-manager/internal/models"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
-// 预编译正则表达式（性能优化）
-var (
-	existsRe    = regexp.MustCompile(`\* (\d+) EXISTS`)
-	msgRe       = regexp.MustCompile(`MESSAGES\s+(\d+)`)
-	unseenRe    = regexp.MustCompile(`UNSEEN\s+(\d+)`)
-	uidRe       = regexp.MustCompile(`\* \d+ FETCH \(UID (\d+)`)
-	fromRe      = regexp.MustCompile(`(?m)^From:\s*(.+)`)
-	toRe        = regexp.MustCompile(`(?m)^To:\s*(.+)`)
-	subjRe      = regexp.MustCompile(`(?m)^Subject:\s*(.+)`)
-	dateRe      = regexp.MustCompile(`(?m)^Date:\s*(.+)`)
-	seenRe      = regexp.MustCompile(`FLAGS \([^)]*\\Seen[^)]*\)`)
-	boundaryRe  = regexp.MustCompile(`boundary="?([^"\s\r\n]+)"?`)
-	htmlTagRe   = regexp.MustCompile(`<[^>]*>`)
-)
+// htmlTagRe 匹配HTML标签（预编译，用于生成正文预览）
+var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+
+// IMAPAuth IMAP登录凭据
+//
+// AccessToken和Password二选一：Password非空时使用密码认证，否则使用XOAUTH2
+type IMAPAuth struct {
+	Email       string // 邮箱地址（登录用户名）
+	AccessToken string // OAuth2访问令牌（IMAP scope）
+	Password    string // 邮箱密码或应用专用密码
+}
+
+// secret 返回用于认证的凭据，凭据变化时连接池中的连接不再复用
+func (a IMAPAuth) secret() string {
+	if a.Password != "" {
+		return "password:" + a.Password
+	}
+	return a.AccessToken
+}
 
 // IMAPService IMAP邮件服务
 type IMAPService struct {
-	pool   map[string]*pooledClient // 连接池: email -> client
-	poolMu sync.RWMutex
-}
+	pool *imapPool // 连接池（见imap_pool.go）
+
+	uidValidity map[string]uint32 // 各文件夹最近一次的UIDVALIDITY: email/文件夹名 -> UIDVALIDITY
+	validityMu  sync.Mutex
 
-// pooledClient 池化的IMAP客户端
-type pooledClient struct {
-	client    *IMAPClient
-	email     string
-	token     string
-	lastUsed  time.Time
-	createdAt time.Time
+	folderNames map[string]map[string]string // 已知文件夹的服务器文件夹名: email -> 文件夹ID -> 名称
+	folderMu    sync.Mutex
 }
 
 // IMAP文件夹名映射（REST API ID -> IMAP名称）
@@ -63,25 +68,29 @@ var imapFolderMap = map[string]string{
 }
 
 // reverseImapFolderMap 反向映射（IMAP名称 -> REST API ID）
-// 包含英文名和UTF-7编码名
+// 包含英文名和中文名（LIST返回的修改版UTF-7名称解码后匹配）
 var reverseImapFolderMap = map[string]string{
-	"INBOX":            "inbox",
-	"Junk":             "junkemail",
-	"Junk E-mail":      "junkemail",
-	"Junk E-Mail":      "junkemail",
-	"&Xn9USpCuTvY-":    "junkemail", // 垃圾邮件 UTF-7
-	"Drafts":           "drafts",
-	"&g0l6P3ux-":       "drafts", // 草稿 UTF-7
-	"Sent":             "sentitems",
-	"Sent Items":       "sentitems",
-	"&UXZO1mWHTvZZOQ-": "sentitems", // 已发送邮件 UTF-7
-	"Deleted":          "deleteditems",
-	"Deleted Items":    "deleteditems",
-	"&V4NXPpCuTvY-":    "deleteditems", // 已删除邮件 UTF-7
-	"Outbox":           "outbox",
-	"Notes":            "notes",
-	"Archive":          "archive",
-	"&W1hoYw-":         "archive", // 存档 UTF-7
+	"INBOX":         "inbox",
+	"Junk":          "junkemail",
+	"Junk E-mail":   "junkemail",
+	"Junk E-Mail":   "junkemail",
+	"垃圾邮件":          "junkemail",
+	"广告邮件":          "junkemail",
+	"Drafts":        "drafts",
+	"草稿":            "drafts",
+	"草稿箱":           "drafts",
+	"Sent":          "sentitems",
+	"Sent Items":    "sentitems",
+	"已发送":           "sentitems",
+	"已发送邮件":         "sentitems",
+	"Deleted":       "deleteditems",
+	"Deleted Items": "deleteditems",
+	"已删除":           "deleteditems",
+	"已删除邮件":         "deleteditems",
+	"Outbox":        "outbox",
+	"Notes":         "notes",
+	"Archive":       "archive",
+	"存档":            "archive",
 }
 
 // folderDisplayNames 文件夹ID到中文显示名的映射
@@ -106,83 +115,95 @@ func MapFolderID(folderID string) string {
 }
 
 // getRestAPIFolderID 将IMAP文件夹名映射为REST API ID（大小写不敏感）
-func getRestAPIFolderID(imapName string) string {
+//
+// 参数：
+//   - name: 解码后的文件夹名（UTF-8）
+//
+// 返回值：
+//   - string: 已知文件夹返回REST API ID，其他文件夹原样返回文件夹名（IMAP文件夹名区分大小写）
+func getRestAPIFolderID(name string) string {
 	// 精确匹配
-	if id, ok := reverseImapFolderMap[imapName]; ok {
+	if id, ok := reverseImapFolderMap[name]; ok {
 		return id
 	}
 	// 大小写不敏感匹配
-	lowerName := strings.ToLower(imapName)
+	lowerName := strings.ToLower(name)
 	for k, v := range reverseImapFolderMap {
 		if strings.ToLower(k) == lowerName {
 			return v
 		}
 	}
-	return strings.ToLower(imapName)
+	return name
 }
 
 // NewIMAPService 创建IMAPService实例
 func NewIMAPService() *IMAPService {
 	return &IMAPService{
-		pool: make(map[string]*pooledClient),
+		pool:        newIMAPPool(),
+		uidValidity: make(map[string]uint32),
+		folderNames: make(map[string]map[string]string),
 	}
 }
 
-// getClient 从连接池获取或创建新连接
-func (s *IMAPService) getClient(email, accessToken string) (*IMAPClient, error) {
-	s.poolMu.Lock()
-	defer s.poolMu.Unlock()
-
-	// 检查是否有可用的缓存连接
-	if pc, ok := s.pool[email]; ok {
-		age := time.Since(pc.lastUsed)
-		tokenMatch := pc.token == accessToken
-		log.Printf("[IMAP Pool] 找到缓存连接 - email: %s, age: %v, tokenMatch: %v", email, age, tokenMatch)
-		// 连接有效期5分钟，token必须相同
-		if age < 5*time.Minute && tokenMatch {
-			pc.lastUsed = time.Now()
-			log.Printf("[IMAP Pool] 复用缓存连接 - email: %s", email)
-			return pc.client, nil
-		}
-		// 连接过期或token变化，关闭旧连接
-		log.Printf("[IMAP Pool] 连接过期或token变化，关闭旧连接 - email: %s, age: %v, tokenMatch: %v", email, age, tokenMatch)
-		pc.client.Close()
-		delete(s.pool, email)
-	} else {
-		log.Printf("[IMAP Pool] 无缓存连接 - email: %s", email)
-	}
+// Close 关闭连接池中的所有连接（应用关闭时调用）
+func (s *IMAPService) Close() {
+	s.pool.close()
+}
 
-	// 创建新连接
-	log.Printf("[IMAP Pool] 创建新连接 - email: %s", email)
-	client, err := newIMAPClient(email, accessToken)
+// acquire 从连接池借出连接，并在ctx取消或超时时断开该连接，使正在执行的命令立即返回
+//
+// 返回值：
+//   - *IMAPClient: 已登录的连接
+//   - func(): 归还连接的函数，用完后必须调用（被断开的连接不会放回连接池）
+//   - error: 获取连接失败或ctx已取消时返回错误
+func (s *IMAPService) acquire(ctx context.Context, auth IMAPAuth) (*IMAPClient, func(), error) {
+	client, err := s.pool.get(ctx, auth)
 	if err != nil {
-		log.Printf("[IMAP Pool] 创建连接失败 - email: %s, error: %v", email, err)
-		return nil, err
-	}
-	log.Printf("[IMAP Pool] 创建连接成功 - email: %s", email)
-
-	// 缓存连接
-	s.pool[email] = &pooledClient{
-		client:    client,
-		email:     email,
-		token:     accessToken,
-		lastUsed:  time.Now(),
-		createdAt: time.Now(),
+		return nil, nil, err
 	}
-	log.Printf("[IMAP Pool] 连接已缓存 - email: %s, 池大小: %d", email, len(s.pool))
-
-	return client, nil
+	stop := context.AfterFunc(ctx, func() { client.conn.Close() })
+	return client, func() {
+		if !stop() {
+			client.broken = true // 已因取消而断开
+		}
+		s.pool.put(client)
+	}, nil
 }
 
 // IMAPClient 简单的IMAP客户端
 type IMAPClient struct {
 	conn   net.Conn
-	buffer []byte
+	reader *imapReader // 响应解析器（见imap_parser.go）
 	tagNum int
+	broken bool // 命令执行中发生过连接错误，连接池不再复用
+}
+
+// passwordIMAPServers 常见邮箱服务商的IMAP服务器（密码登录账号使用）
+var passwordIMAPServers = map[string]string{
+	"gmail.com":      "imap.gmail.com:993",
+	"googlemail.com": "imap.gmail.com:993",
+	"yahoo.com":      "imap.mail.yahoo.com:993",
+	"icloud.com":     "imap.mail.me.com:993",
+	"me.com":         "imap.mail.me.com:993",
+	"mac.com":        "imap.mail.me.com:993",
+	"aol.com":        "imap.aol.com:993",
+	"qq.com":         "imap.qq.com:993",
+	"foxmail.com":    "imap.qq.com:993",
+	"163.com":        "imap.163.com:993",
+	"126.com":        "imap.126.com:993",
+	"yeah.net":       "imap.yeah.net:993",
+	"sina.com":       "imap.sina.com:993",
+	"yandex.com":     "imap.yandex.com:993",
+	"yandex.ru":      "imap.yandex.com:993",
+	"gmx.com":        "imap.gmx.com:993",
+	"zoho.com":       "imap.zoho.com:993",
 }
 
 // getIMAPServer 根据邮箱域名选择IMAP服务器
-func getIMAPServer(email string) string {
+//
+// 微软个人账户使用imap-mail.outlook.com；密码登录的非微软邮箱按常见服务商选择，
+// 未知域名使用imap.<域名>:993；其他（OAuth2）账户使用outlook.office365.com
+func getIMAPServer(email string, password bool) string {
 	// 个人账户域名使用 imap-mail.outlook.com
 	personalDomains := []string{"@hotmail.", "@outlook.", "@live.", "@msn."}
 	emailLower := strings.ToLower(email)
@@ -191,208 +212,332 @@ func getIMAPServer(email string) string {
 			return "imap-mail.outlook.com:993"
 		}
 	}
+	if password {
+		domain := emailLower[strings.LastIndex(emailLower, "@")+1:]
+		if server, ok := passwordIMAPServers[domain]; ok {
+			return server
+		}
+		return "imap." + domain + ":993"
+	}
 	// 企业账户使用 outlook.office365.com
 	return "outlook.office365.com:993"
 }
 
-// newIMAPClient 创建IMAP连接
-func newIMAPClient(email, accessToken string) (*IMAPClient, error) {
-	server := getIMAPServer(email)
+// newIMAPClient 创建IMAP连接并完成认证
+//
+// auth.Password非空时使用密码认证（见loginWithPassword），否则使用XOAUTH2；
+// ctx在连接和认证完成前取消时断开连接并返回ctx的错误
+func newIMAPClient(ctx context.Context, auth IMAPAuth) (*IMAPClient, error) {
+	email := auth.Email
+	server := getIMAPServer(email, auth.Password != "")
 	host := strings.Split(server, ":")[0]
 	log.Printf("[IMAP Connect] 开始连接 %s - email: %s", server, email)
 
-	// 使用tls.DialWithDialer，自动处理SNI和超时
-	conn, err := tls.DialWithDialer(
-		&net.Dialer{Timeout: 30 * time.Second},
-		"tcp",
-		server,
-		&tls.Config{
+	// 使用tls.Dialer，自动处理SNI和超时
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 30 * time.Second},
+		Config: &tls.Config{
 			ServerName: host,
 			MinVersion: tls.VersionTLS12,
 		},
-	)
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", server)
 	if err != nil {
 		log.Printf("[IMAP Connect] TLS连接失败: %v", err)
 		return nil, fmt.Errorf("connect failed: %w", err)
 	}
 	log.Printf("[IMAP Connect] TLS连接成功")
 
-	client := &IMAPClient{conn: conn, buffer: make([]byte, 65536), tagNum: 0}
+	// 认证期间ctx取消时断开连接，使阻塞的读取立即返回
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	client, err := loginIMAPClient(conn, auth)
+	if !stop() {
+		return nil, ctx.Err() // 连接已因取消而断开
+	}
+	return client, err
+}
+
+// loginIMAPClient 读取欢迎消息并完成认证
+func loginIMAPClient(conn net.Conn, auth IMAPAuth) (*IMAPClient, error) {
+	email := auth.Email
+
+	client := &IMAPClient{conn: conn, reader: newIMAPReader(conn), tagNum: 0}
 
 	// 读取欢迎消息
 	log.Printf("[IMAP Connect] 读取欢迎消息...")
 	welcome, err := client.readResponse()
+	if err == nil && welcome.Status == "BYE" {
+		err = statusError(welcome)
+	}
 	if err != nil {
 		log.Printf("[IMAP Connect] 读取欢迎消息失败: %v", err)
 		conn.Close()
 		return nil, err
 	}
-	log.Printf("[IMAP Connect] 欢迎消息: %s", welcome)
+	log.Printf("[IMAP Connect] 欢迎消息: %s %s", welcome.Status, welcome.Text)
+
+	if auth.Password != "" {
+		if err := client.loginWithPassword(email, auth.Password); err != nil {
+			log.Printf("[IMAP Connect] 密码认证失败: %v", err)
+			conn.Close()
+			return nil, fmt.Errorf("auth failed: %w", err)
+		}
+		log.Printf("[IMAP Connect] 密码认证成功")
+		return client, nil
+	}
 
 	// XOAUTH2认证
-	authStr := fmt.Sprintf("user=%s\x01auth=Bearer %s\x01\x01", email, accessToken)
+	authStr := fmt.Sprintf("user=%s\x01auth=Bearer %s\x01\x01", email, auth.AccessToken)
 	authB64 := base64.StdEncoding.EncodeToString([]byte(authStr))
 	log.Printf("[IMAP Connect] 发送 AUTHENTICATE XOAUTH2 命令...")
 
-	authResp, err := client.command("AUTHENTICATE XOAUTH2 " + authB64)
-	if err != nil {
+	// 认证失败时服务器可能先发送继续请求（内容为错误详情），command会回复空行取消认证
+	if _, err := client.command("AUTHENTICATE XOAUTH2 " + authB64); err != nil {
 		log.Printf("[IMAP Connect] 认证失败: %v", err)
 		conn.Close()
 		return nil, fmt.Errorf("auth failed: %w", err)
 	}
-	log.Printf("[IMAP Connect] 认证成功: %s", authResp)
+	log.Printf("[IMAP Connect] 认证成功")
 
 	return client, nil
 }
 
-// command 发送IMAP命令并等待响应
+// loginWithPassword 使用密码认证
 //
-// IMAP协议要求每个命令都带有唯一的标签（tag），服务器响应时会包含相同的标签。
-// 本方法自动生成递增的标签（A001, A002, ...），发送命令并等待带有该标签的响应。
+// 服务器支持AUTH=PLAIN时使用AUTHENTICATE PLAIN（支持SASL-IR时直接附带凭据，
+// 否则等待服务器的"+"继续请求后发送），否则使用LOGIN命令；
+// 服务器声明LOGINDISABLED且不支持PLAIN时返回错误
 //
 // 参数：
-//   - cmd: IMAP命令字符串（不含标签），如 "LIST \"\" \"*\"" 或 "SELECT INBOX"
+//   - user: 用户名（邮箱地址）
+//   - password: 密码
 //
 // 返回值：
-//   - string: 服务器的完整响应内容
-//   - error: 发送失败或服务器返回错误时的错误信息
-//
-// IMAP命令格式：
+//   - error: 服务器拒绝认证或不支持密码认证时返回错误
+func (c *IMAPClient) loginWithPassword(user, password string) error {
+	caps, err := c.capabilities()
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(caps, " AUTH=PLAIN ") {
+		plain := base64.StdEncoding.EncodeToString([]byte("\x00" + user + "\x00" + password))
+		if strings.Contains(caps, " SASL-IR ") {
+			log.Printf("[IMAP Connect] 发送 AUTHENTICATE PLAIN 命令（SASL-IR）...")
+			_, err = c.command("AUTHENTICATE PLAIN " + plain)
+			return err
+		}
+		log.Printf("[IMAP Connect] 发送 AUTHENTICATE PLAIN 命令...")
+		_, err = c.commandWithContinuation("AUTHENTICATE PLAIN", plain)
+		return err
+	}
+
+	if strings.Contains(caps, " LOGINDISABLED ") {
+		return fmt.Errorf("server does not allow password login")
+	}
+	log.Printf("[IMAP Connect] 发送 LOGIN 命令...")
+	_, err = c.command("LOGIN " + quoteIMAPString(user) + " " + quoteIMAPString(password))
+	return err
+}
+
+// capabilities 查询服务器能力列表
 //
-//	客户端: A001 SELECT INBOX\r\n
-//	服务器: * 172 EXISTS\r\n
-//	        * 1 RECENT\r\n
-//	        A001 OK SELECT completed\r\n
-func (c *IMAPClient) command(cmd string) (string, error) {
-	c.tagNum++
-	tag := fmt.Sprintf("A%03d", c.tagNum)
-	_, err := c.conn.Write([]byte(tag + " " + cmd + "\r\n"))
+// 返回值：
+//   - string: 大写的能力名，以空格分隔且首尾各有一个空格，便于用 " IDLE " 形式判断
+//   - error: 命令失败时返回错误
+func (c *IMAPClient) capabilities() (string, error) {
+	resps, err := c.command("CAPABILITY")
 	if err != nil {
 		return "", err
 	}
-	return c.readUntilTag(tag)
+	var caps []string
+	for _, r := range resps {
+		if r.Name() == "CAPABILITY" {
+			for _, f := range r.Fields[1:] {
+				caps = append(caps, strings.ToUpper(imapString(f)))
+			}
+		}
+	}
+	return " " + strings.Join(caps, " ") + " ", nil
+}
+
+// quoteIMAPString 将字符串编码为IMAP带引号字符串（转义反斜杠和双引号）
+func quoteIMAPString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
 }
 
-// readResponse 读取服务器的单次响应
+// CheckLogin 检查能否登录IMAP服务器（用于密码登录账号的可用性检测）
 //
-// 从IMAP连接读取一次数据到缓冲区。用于读取服务器的初始欢迎消息等
-// 不需要等待特定标签的场景。
+// 使用新的连接登录后立即断开，不影响连接池
 //
-// 设置30秒读取超时，防止连接挂起。
+// 参数：
+//   - ctx: 上下文，取消时中止连接
+//   - auth: 登录凭据
 //
 // 返回值：
-//   - string: 读取到的响应内容
-//   - error: 读取超时或连接错误时返回错误
-func (c *IMAPClient) readResponse() (string, error) {
-	c.conn.SetReadDeadline(time.Now().Add(30 * time.Second))
-	n, err := c.conn.Read(c.buffer)
+//   - error: 连接或认证失败时返回错误
+func (s *IMAPService) CheckLogin(ctx context.Context, auth IMAPAuth) error {
+	client, err := newIMAPClient(ctx, auth)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return string(c.buffer[:n]), nil
+	client.Close()
+	return nil
 }
 
-// readUntilTag 持续读取直到收到指定标签的响应
+// command 发送IMAP命令并等待响应
+//
+// IMAP协议要求每个命令都带有唯一的标签（tag），服务器响应时会包含相同的标签。
+// 本方法自动生成递增的标签（A001, A002, ...），发送命令并读取响应直到带有该标签的完成响应。
+//
+// 参数：
+//   - cmd: IMAP命令字符串（不含标签），如 "LIST \"\" \"*\"" 或 "SELECT INBOX"
 //
-// IMAP服务器可能会发送多行响应（以*开头的非标签行），
-// 本方法持续读取直到收到以指定标签开头的最终响应行。
+// 返回值：
+//   - []*imapResponse: 完成响应之前收到的所有非标签响应（已解析，含字面量）
+//   - error: 发送失败、连接错误或服务器返回NO/BAD时的错误信息
 //
-// 响应状态判断：
-//   - "tag OK": 命令执行成功
-//   - "tag NO": 命令执行失败（如文件夹不存在）
-//   - "tag BAD": 命令语法错误
+// IMAP命令格式：
+//
+//	客户端: A001 SELECT INBOX\r\n
+//	服务器: * 172 EXISTS\r\n
+//	        * 1 RECENT\r\n
+//	        A001 OK SELECT completed\r\n
+func (c *IMAPClient) command(cmd string) ([]*imapResponse, error) {
+	return c.commandWithContinuation(cmd)
+}
+
+// commandWithContinuation 发送IMAP命令，收到继续请求（+）时依次发送conts中的内容
+//
+// conts用完后再收到继续请求时发送空行（用于取消SASL认证，如XOAUTH2失败时服务器返回的错误详情）
 //
 // 参数：
-//   - tag: 要等待的命令标签（如 "A001"）
+//   - cmd: IMAP命令字符串（不含标签）
+//   - conts: 对继续请求的应答（不含CRLF）
 //
 // 返回值：
-//   - string: 完整的响应内容（包括所有中间行）
-//   - error: 超时、连接错误或服务器返回NO/BAD时返回错误
-func (c *IMAPClient) readUntilTag(tag string) (string, error) {
-	var result strings.Builder
+//   - []*imapResponse: 完成响应之前收到的所有非标签响应
+//   - error: 发送失败、连接错误或服务器返回NO/BAD时的错误信息
+func (c *IMAPClient) commandWithContinuation(cmd string, conts ...string) ([]*imapResponse, error) {
+	c.tagNum++
+	tag := fmt.Sprintf("A%03d", c.tagNum)
+	if _, err := c.conn.Write([]byte(tag + " " + cmd + "\r\n")); err != nil {
+		c.broken = true
+		return nil, err
+	}
+
+	var untagged []*imapResponse
 	for {
-		c.conn.SetReadDeadline(time.Now().Add(30 * time.Second))
-		n, err := c.conn.Read(c.buffer)
+		resp, err := c.readResponse()
 		if err != nil {
-			return result.String(), err
-		}
-		result.Write(c.buffer[:n])
-		resp := result.String()
-		if strings.Contains(resp, tag+" OK") {
-			return resp, nil
+			c.broken = true // 超时或读取失败后响应流已不同步
+			return untagged, err
 		}
-		if strings.Contains(resp, tag+" NO") || strings.Contains(resp, tag+" BAD") {
-			return resp, fmt.Errorf("IMAP error: %s", resp)
+		switch resp.Tag {
+		case tag:
+			if resp.Status != "OK" {
+				return untagged, statusError(resp)
+			}
+			return untagged, nil
+		case "+":
+			line := ""
+			if len(conts) > 0 {
+				line, conts = conts[0], conts[1:]
+			}
+			if _, err := c.conn.Write([]byte(line + "\r\n")); err != nil {
+				c.broken = true
+				return untagged, err
+			}
+		case "*":
+			untagged = append(untagged, resp)
+		default:
+			// 其他标签的完成响应（不应出现），忽略
+			log.Printf("[IMAP] 忽略未知标签的响应: %s %s %s", resp.Tag, resp.Status, resp.Text)
 		}
 	}
 }
 
+// readResponse 读取服务器的一条完整响应
+//
+// 每条响应设置30秒读取超时，防止连接挂起；大的字面量（如完整邮件）按需多次读取
+//
+// 返回值：
+//   - *imapResponse: 解析后的响应
+//   - error: 读取超时、连接错误或响应格式错误时返回错误
+func (c *IMAPClient) readResponse() (*imapResponse, error) {
+	c.conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	return c.reader.ReadResponse()
+}
+
+// imapStatusError 服务器返回的NO/BAD/BYE状态响应
+//
+// NO和BAD表示命令被拒绝，连接仍然可用；其他错误（读写失败、超时）表示连接已不可用
+type imapStatusError struct {
+	Status string // NO、BAD或BYE
+	Code   string // 响应码（如 TRYCREATE、AUTHENTICATIONFAILED）
+	Text   string // 说明文字
+}
+
+// Error 返回错误信息
+func (e *imapStatusError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("IMAP error: %s [%s] %s", e.Status, e.Code, e.Text)
+	}
+	return fmt.Sprintf("IMAP error: %s %s", e.Status, e.Text)
+}
+
+// statusError 将NO/BAD/BYE状态响应转换为错误
+func statusError(resp *imapResponse) error {
+	return &imapStatusError{Status: resp.Status, Code: resp.Code, Text: resp.Text}
+}
+
 // Close 关闭IMAP连接
 //
 // 按照IMAP协议规范，先发送LOGOUT命令通知服务器断开连接，
 // 然后关闭底层TCP连接。这是优雅关闭连接的标准方式。
 //
 // 注意：连接池管理的连接不应直接调用此方法，
-// 而是由连接池在连接过期时自动调用。
+// 而是通过连接池归还，由连接池在连接过期或出错时关闭。
 func (c *IMAPClient) Close() {
 	c.command("LOGOUT")
 	c.conn.Close()
 }
 
 // GetMailFolders 获取邮件文件夹列表
-func (s *IMAPService) GetMailFolders(email, accessToken string) ([]models.MailFolder, error) {
-	log.Printf("[IMAP] GetMailFolders 开始 - email: %s", email)
+//
+// 返回值：
+//   - []models.MailFolder: 顶层文件夹列表，子文件夹在ChildFolders中
+//   - error: 连接或LIST命令失败时返回错误
+func (s *IMAPService) GetMailFolders(ctx context.Context, auth IMAPAuth) ([]models.MailFolder, error) {
+	log.Printf("[IMAP] GetMailFolders 开始 - email: %s", auth.Email)
 
-	client, err := s.getClient(email, accessToken)
+	client, release, err := s.acquire(ctx, auth)
 	if err != nil {
-		log.Printf("[IMAP] getClient 失败: %v", err)
+		log.Printf("[IMAP] 获取连接失败: %v", err)
 		return nil, err
 	}
-	log.Printf("[IMAP] getClient 成功，连接已建立")
-	// 不关闭连接，保持在连接池中
+	defer release() // 用完归还连接池
 
 	log.Printf("[IMAP] 发送 LIST 命令...")
-	resp, err := client.command("LIST \"\" \"*\"")
+	mailboxes, err := client.listMailboxes()
 	if err != nil {
 		log.Printf("[IMAP] LIST 命令失败: %v", err)
 		return nil, err
 	}
-	log.Printf("[IMAP] LIST 响应长度: %d 字节", len(resp))
-	log.Printf("[IMAP] LIST 响应内容:\n%s", resp)
-
-	var folders []models.MailFolder
-	// 存储原始IMAP名称用于STATUS查询
-	var imapNames []string
-	lines := strings.Split(resp, "\r\n")
-	log.Printf("[IMAP] 解析到 %d 行", len(lines))
-
-	for _, line := range lines {
-		if strings.HasPrefix(line, "* LIST") {
-			log.Printf("[IMAP] 解析文件夹行: %s", line)
-			// 解析: * LIST (\HasNoChildren) "/" Inbox 或 * LIST (\HasNoChildren) "/" "INBOX"
-			// 找到最后一个 "/" 后面的文件夹名
-			idx := strings.LastIndex(line, "\"/\" ")
-			if idx == -1 {
-				idx = strings.LastIndex(line, "\" ")
-			}
-			if idx != -1 {
-				name := strings.TrimSpace(line[idx+4:])
-				name = strings.Trim(name, "\"") // 移除可能的引号
-				decoded := decodeIMAPUTF7(name)
-				// 使用大小写不敏感的映射获取REST API风格的ID
-				id := getRestAPIFolderID(name)
-				// 使用中文显示名
-				displayName := decoded
-				if chineseName, ok := folderDisplayNames[id]; ok {
-					displayName = chineseName
-				}
-				log.Printf("[IMAP] 文件夹: name=%s, decoded=%s, id=%s, displayName=%s", name, decoded, id, displayName)
-				folders = append(folders, models.MailFolder{
-					ID:          id,
-					DisplayName: displayName,
-				})
-				imapNames = append(imapNames, name) // 保存原始名称
-			}
+	s.cacheFolderNames(auth.Email, mailboxes)
+
+	folders := make([]models.MailFolder, len(mailboxes))
+	for i, mb := range mailboxes {
+		// 已知文件夹使用中文显示名，其他文件夹显示最后一级名称
+		_, displayName := splitMailboxName(mb.decoded, mb.delimiter)
+		if chineseName, ok := folderDisplayNames[mb.id]; ok {
+			displayName = chineseName
+		}
+		log.Printf("[IMAP] 文件夹: name=%s, decoded=%s, delimiter=%q, id=%s, displayName=%s", mb.name, mb.decoded, mb.delimiter, mb.id, displayName)
+		folders[i] = models.MailFolder{
+			ID:          mb.id,
+			DisplayName: displayName,
+			NoSelect:    mb.attrs[`\NOSELECT`],
 		}
 	}
 	log.Printf("[IMAP] 共解析到 %d 个文件夹", len(folders))
@@ -403,174 +548,313 @@ func (s *IMAPService) GetMailFolders(email, accessToken string) ([]models.MailFo
 		if !targetFolders[folders[i].ID] {
 			continue
 		}
-		log.Printf("[IMAP] 获取文件夹 %s (IMAP名: %s) 的计数...", folders[i].ID, imapNames[i])
+		log.Printf("[IMAP] 获取文件夹 %s (IMAP名: %s) 的计数...", folders[i].ID, mailboxes[i].name)
 		// 使用原始IMAP文件夹名进行STATUS查询
-		statusCmd := fmt.Sprintf("STATUS \"%s\" (MESSAGES UNSEEN)", imapNames[i])
+		statusCmd := fmt.Sprintf("STATUS %s (MESSAGES UNSEEN)", quoteIMAPString(mailboxes[i].name))
 		log.Printf("[IMAP] 发送命令: %s", statusCmd)
 		statusResp, err := client.command(statusCmd)
 		if err != nil {
 			log.Printf("[IMAP] STATUS 命令失败: %v", err)
 			continue
 		}
-		log.Printf("[IMAP] STATUS 响应:\n%s", statusResp)
-
 		// 解析: * STATUS "INBOX" (MESSAGES 10 UNSEEN 2)
-		if m := msgRe.FindStringSubmatch(statusResp); len(m) > 1 {
-			folders[i].TotalItemCount, _ = strconv.Atoi(m[1])
-			log.Printf("[IMAP] 解析 MESSAGES: %s -> TotalItemCount=%d", m[1], folders[i].TotalItemCount)
-		} else {
-			log.Printf("[IMAP] 未能匹配 MESSAGES 正则: %s", msgRe.String())
-		}
-		if m := unseenRe.FindStringSubmatch(statusResp); len(m) > 1 {
-			folders[i].UnreadItemCount, _ = strconv.Atoi(m[1])
-			log.Printf("[IMAP] 解析 UNSEEN: %s -> UnreadItemCount=%d", m[1], folders[i].UnreadItemCount)
-		} else {
-			log.Printf("[IMAP] 未能匹配 UNSEEN 正则: %s", unseenRe.String())
+		for _, r := range statusResp {
+			if r.Name() != "STATUS" || len(r.Fields) < 3 {
+				continue
+			}
+			counts := imapPairs(imapList(r.Fields[2]))
+			if n, ok := imapNumber(counts["MESSAGES"]); ok {
+				folders[i].TotalItemCount = int(n)
+			}
+			if n, ok := imapNumber(counts["UNSEEN"]); ok {
+				folders[i].UnreadItemCount = int(n)
+			}
 		}
 		log.Printf("[IMAP] 文件夹 %s 最终计数: Total=%d, Unread=%d", folders[i].ID, folders[i].TotalItemCount, folders[i].UnreadItemCount)
 	}
 
 	log.Printf("[IMAP] GetMailFolders 完成，返回 %d 个文件夹", len(folders))
-	return folders, nil
+	return buildFolderTree(mailboxes, folders), nil
 }
 
-// GetMessages 获取邮件列表
-func (s *IMAPService) GetMessages(email, accessToken, folderID string, skip, top int) ([]models.Message, error) {
-	log.Printf("[IMAP] GetMessages 开始 - email: %s, folderID: %s, skip: %d, top: %d", email, folderID, skip, top)
+// GetMessages 获取一页邮件列表
+//
+// 按UID分页：第一页为UID最大的top封邮件，游标记录本页最小的UID和文件夹的UIDVALIDITY，
+// 下一页为UID小于该值的邮件，因此翻页期间新邮件到达或邮件被删除不会导致重复或遗漏
+//
+// 参数：
+//   - auth: 登录凭据
+//   - folderID: 文件夹ID（REST API的ID或IMAP文件夹名）
+//   - cursor: 上一页返回的游标，空字符串表示第一页
+//   - top: 每页邮件数
+//
+// 返回值：
+//   - *models.MessagePage: 邮件列表（最新的在前）和下一页游标
+//   - error: 命令失败时返回错误；游标不是IMAP游标或文件夹的UIDVALIDITY已变化时返回ErrCursorExpired
+func (s *IMAPService) GetMessages(ctx context.Context, auth IMAPAuth, folderID, cursor string, top int) (*models.MessagePage, error) {
+	log.Printf("[IMAP] GetMessages 开始 - email: %s, folderID: %s, cursor: %s, top: %d", auth.Email, folderID, cursor, top)
 
-	client, err := s.getClient(email, accessToken)
+	client, release, err := s.acquire(ctx, auth)
 	if err != nil {
-		log.Printf("[IMAP] getClient 失败: %v", err)
+		log.Printf("[IMAP] 获取连接失败: %v", err)
 		return nil, err
 	}
-	log.Printf("[IMAP] getClient 成功")
-	// 不关闭连接，保持在连接池中
+	defer release() // 用完归还连接池
 
-	// 映射文件夹名称
-	imapFolder := MapFolderID(folderID)
+	// 映射文件夹名称（已知文件夹使用服务器上的实际名称）
+	imapFolder := s.resolveFolder(client, auth.Email, folderID)
 	log.Printf("[IMAP] 文件夹映射: %s -> %s", folderID, imapFolder)
 
 	// 选择文件夹
-	selectCmd := fmt.Sprintf("SELECT \"%s\"", imapFolder)
+	selectCmd := "SELECT " + quoteIMAPString(imapFolder)
 	log.Printf("[IMAP] 发送命令: %s", selectCmd)
 	selectResp, err := client.command(selectCmd)
 	if err != nil {
 		log.Printf("[IMAP] SELECT 命令失败: %v", err)
 		return nil, err
 	}
-	log.Printf("[IMAP] SELECT 响应:\n%s", selectResp)
 
-	// 解析邮件总数
-	matches := existsRe.FindStringSubmatch(selectResp)
-	log.Printf("[IMAP] EXISTS 正则匹配结果: %v", matches)
-	if len(matches) < 2 {
-		log.Printf("[IMAP] 未找到 EXISTS，返回空列表")
-		return []models.Message{}, nil
+	// 解析邮件总数（* 172 EXISTS）和UIDVALIDITY（* OK [UIDVALIDITY 3857529045]）
+	total := -1
+	var validity uint32
+	for _, r := range selectResp {
+		if r.Name() == "EXISTS" {
+			n, _ := imapNumber(r.Fields[0])
+			total = int(n)
+		}
+		if v, ok := responseCodeNumber(r, "UIDVALIDITY"); ok {
+			validity = v
+		}
+	}
+	s.recordUIDValidity(auth.Email, imapFolder, validity)
+	page := &models.MessagePage{Messages: []models.Message{}, UIDValidity: validity}
+
+	// 解析游标：下一页为UID小于before的邮件
+	var before uint32
+	if cursor != "" {
+		v, uid, err := parseIMAPCursor(cursor)
+		if err != nil {
+			log.Printf("[IMAP] 游标无效: %s", cursor)
+			return nil, err
+		}
+		if v != validity {
+			log.Printf("[IMAP] UIDVALIDITY 已变化（%d -> %d），游标失效", v, validity)
+			return nil, ErrCursorExpired
+		}
+		before = uid
 	}
-	total, _ := strconv.Atoi(matches[1])
-	log.Printf("[IMAP] 邮件总数: %d", total)
-	if total == 0 {
-		log.Printf("[IMAP] 邮件总数为0，返回空列表")
-		return []models.Message{}, nil
+	if total <= 0 || cursor != "" && before <= 1 {
+		log.Printf("[IMAP] 没有更多邮件 - total: %d, before: %d", total, before)
+		return page, nil
 	}
 
-	// 计算获取范围（从最新的开始）
-	start := total - skip - top + 1
-	end := total - skip
-	log.Printf("[IMAP] 计算范围: total=%d, skip=%d, top=%d -> start=%d, end=%d", total, skip, top, start, end)
-	if start < 1 {
-		start = 1
-		log.Printf("[IMAP] start 调整为 1")
+	// 查找范围内的所有UID
+	searchCmd := "UID SEARCH ALL"
+	if before > 0 {
+		searchCmd = fmt.Sprintf("UID SEARCH UID 1:%d", before-1)
+	}
+	log.Printf("[IMAP] 发送命令: %s", searchCmd)
+	searchResp, err := client.command(searchCmd)
+	if err != nil {
+		log.Printf("[IMAP] UID SEARCH 失败: %v", err)
+		return nil, err
+	}
+	var uids []uint32
+	for _, r := range searchResp {
+		if r.Name() != "SEARCH" {
+			continue
+		}
+		for _, f := range r.Fields[1:] {
+			// "UID 1:n"在没有小于n的邮件时，部分服务器仍返回最大的UID，需要再次过滤
+			if uid, ok := imapNumber(f); ok && (before == 0 || uid < before) {
+				uids = append(uids, uid)
+			}
+		}
 	}
-	if end < 1 {
-		log.Printf("[IMAP] end < 1，返回空列表")
-		return []models.Message{}, nil
+	if len(uids) == 0 {
+		log.Printf("[IMAP] UID SEARCH 无结果，返回空列表")
+		return page, nil
 	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+
+	// 取UID最大的top封
+	if len(uids) > top {
+		uids = uids[len(uids)-top:]
+		page.HasMore = true
+		page.NextCursor = formatIMAPCursor(validity, uids[0])
+	}
+	set := make([]string, len(uids))
+	for i, uid := range uids {
+		set[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+	log.Printf("[IMAP] 本页 UID: %d - %d，共 %d 封，hasMore: %v", uids[0], uids[len(uids)-1], len(uids), page.HasMore)
 
 	// 获取邮件头
-	fetchCmd := fmt.Sprintf("FETCH %d:%d (UID FLAGS BODY.PEEK[HEADER.FIELDS (FROM SUBJECT DATE)])", start, end)
-	log.Printf("[IMAP] 发送命令: %s", fetchCmd)
+	fetchCmd := fmt.Sprintf("UID FETCH %s (UID FLAGS BODY.PEEK[HEADER.FIELDS (FROM SUBJECT DATE)])", strings.Join(set, ","))
+	log.Printf("[IMAP] 发送命令: UID FETCH（%d 封）", len(uids))
 	fetchResp, err := client.command(fetchCmd)
 	if err != nil {
 		log.Printf("[IMAP] FETCH 命令失败: %v", err)
 		return nil, err
 	}
-	log.Printf("[IMAP] FETCH 响应长度: %d 字节", len(fetchResp))
-	log.Printf("[IMAP] FETCH 响应内容:\n%s", fetchResp)
+	log.Printf("[IMAP] FETCH 响应: %d 条", len(fetchResp))
 
-	messages := parseMessages(fetchResp)
-	log.Printf("[IMAP] 解析到 %d 封邮件", len(messages))
-	for i, msg := range messages {
+	page.Messages = parseMessages(fetchResp)
+	log.Printf("[IMAP] 解析到 %d 封邮件", len(page.Messages))
+	for i, msg := range page.Messages {
 		log.Printf("[IMAP] 邮件[%d]: ID=%s, Subject=%s, From=%v", i, msg.ID, msg.Subject, msg.From)
 	}
 
-	return messages, nil
+	return page, nil
+}
+
+// responseCodeNumber 读取状态响应中 [NAME n] 形式的数字响应码（如UIDVALIDITY、UIDNEXT）
+func responseCodeNumber(r *imapResponse, name string) (uint32, bool) {
+	if r.Status == "" || !strings.HasPrefix(strings.ToUpper(r.Code), name+" ") {
+		return 0, false
+	}
+	return imapNumber(strings.TrimSpace(r.Code[len(name)+1:]))
+}
+
+// recordUIDValidity 记录文件夹的UIDVALIDITY，变化时（文件夹被重建，之前的UID全部失效）记录日志
+func (s *IMAPService) recordUIDValidity(email, folder string, validity uint32) {
+	key := email + "/" + folder
+	s.validityMu.Lock()
+	defer s.validityMu.Unlock()
+	if old, ok := s.uidValidity[key]; ok && old != validity {
+		log.Printf("[IMAP] 文件夹 UIDVALIDITY 变化 - %s: %d -> %d，之前的游标全部失效", key, old, validity)
+	}
+	s.uidValidity[key] = validity
 }
 
 // GetMessage 获取邮件详情
-func (s *IMAPService) GetMessage(email, accessToken, folderID, messageID string) (*models.Message, error) {
-	log.Printf("[IMAP] GetMessage 开始 - email: %s, folderID: %s, messageID: %s", email, folderID, messageID)
+//
+// 返回的邮件包含正文和附件（含内容）
+func (s *IMAPService) GetMessage(ctx context.Context, auth IMAPAuth, folderID, messageID string) (*models.Message, error) {
+	log.Printf("[IMAP] GetMessage 开始 - email: %s, folderID: %s, messageID: %s", auth.Email, folderID, messageID)
+
+	raw, flags, err := s.fetchRawMessage(ctx, auth, folderID, messageID)
+	if err != nil {
+		return nil, err
+	}
 
-	client, err := s.getClient(email, accessToken)
+	msg := parseFullMessage(raw)
+	msg.ID = messageID // 设置邮件ID，用于前端匹配选中状态
+	msg.IsRead = hasFlag(flags, `\Seen`)
+	msg.Flag = models.NewMessageFlag(hasFlag(flags, `\Flagged`))
+	log.Printf("[IMAP] 解析邮件: ID=%s, Subject=%s, From=%v, BodyType=%s, BodyLen=%d, Attachments=%d",
+		msg.ID, msg.Subject, msg.From, msg.Body.ContentType, len(msg.Body.Content), len(msg.Attachments))
+
+	return msg, nil
+}
+
+// GetAttachments 获取邮件附件列表（含Base64编码的内容）
+//
+// 参数：
+//   - auth: 登录凭据
+//   - folderID: 邮件所在文件夹
+//   - messageID: 邮件UID
+//
+// 返回值：
+//   - []models.Attachment: 附件列表，ID为附件在MIME树中的编号（如"1.2"）
+//   - error: 获取邮件失败时返回错误
+func (s *IMAPService) GetAttachments(ctx context.Context, auth IMAPAuth, folderID, messageID string) ([]models.Attachment, error) {
+	log.Printf("[IMAP] GetAttachments 开始 - email: %s, folderID: %s, messageID: %s", auth.Email, folderID, messageID)
+	raw, _, err := s.fetchRawMessage(ctx, auth, folderID, messageID)
 	if err != nil {
-		log.Printf("[IMAP] getClient 失败: %v", err)
 		return nil, err
 	}
-	log.Printf("[IMAP] getClient 成功")
-	// 不关闭连接，保持在连接池中
+	attachments := parseFullMessage(raw).Attachments
+	log.Printf("[IMAP] GetAttachments 完成，返回 %d 个附件", len(attachments))
+	return attachments, nil
+}
 
-	// 映射文件夹名称
-	imapFolder := MapFolderID(folderID)
+// fetchRawMessage 选择文件夹并按UID获取邮件原文和标志
+//
+// 返回值：
+//   - string: 邮件原文（BODY.PEEK[]，响应中为BODY[]）
+//   - []interface{}: FLAGS列表
+//   - error: 邮件ID不是合法的UID、命令失败或邮件不存在时返回错误
+func (s *IMAPService) fetchRawMessage(ctx context.Context, auth IMAPAuth, folderID, messageID string) (string, []interface{}, error) {
+	// 邮件ID来自前端，校验为UID后才能拼接进命令
+	uid, err := uidSet([]string{messageID})
+	if err != nil {
+		return "", nil, err
+	}
+	client, release, err := s.acquire(ctx, auth)
+	if err != nil {
+		log.Printf("[IMAP] 获取连接失败: %v", err)
+		return "", nil, err
+	}
+	defer release() // 用完归还连接池
+
+	// 映射文件夹名称（已知文件夹使用服务器上的实际名称）
+	imapFolder := s.resolveFolder(client, auth.Email, folderID)
 	log.Printf("[IMAP] 文件夹映射: %s -> %s", folderID, imapFolder)
 
-	selectCmd := fmt.Sprintf("SELECT \"%s\"", imapFolder)
+	selectCmd := "SELECT " + quoteIMAPString(imapFolder)
 	log.Printf("[IMAP] 发送命令: %s", selectCmd)
-	selectResp, _ := client.command(selectCmd)
-	log.Printf("[IMAP] SELECT 响应: %s", selectResp)
+	if _, err := client.command(selectCmd); err != nil {
+		log.Printf("[IMAP] SELECT 命令失败: %v", err)
+		return "", nil, err
+	}
 
-	// 使用UID获取完整邮件
-	fetchCmd := fmt.Sprintf("UID FETCH %s (FLAGS BODY[])", messageID)
+	// 使用UID获取完整邮件；BODY.PEEK[]不会设置\Seen标志，查看邮件不改变服务器上的已读状态
+	fetchCmd := fmt.Sprintf("UID FETCH %s (FLAGS BODY.PEEK[])", uid)
 	log.Printf("[IMAP] 发送命令: %s", fetchCmd)
 	fetchResp, err := client.command(fetchCmd)
 	if err != nil {
 		log.Printf("[IMAP] UID FETCH 失败: %v", err)
-		return nil, err
+		return "", nil, err
+	}
+	// 找到该UID的FETCH响应（服务器可能同时推送其他邮件的标志变化）
+	var raw string
+	var flags []interface{}
+	found := false
+	for _, r := range fetchResp {
+		if r.Name() != "FETCH" || len(r.Fields) < 3 {
+			continue
+		}
+		items := imapPairs(imapList(r.Fields[2]))
+		if n, ok := imapNumber(items["UID"]); !ok || strconv.FormatUint(uint64(n), 10) != uid {
+			continue
+		}
+		if body, ok := fetchItem(items, "BODY["); ok {
+			raw, found = imapString(body), true
+		}
+		flags = imapList(items["FLAGS"])
 	}
-	log.Printf("[IMAP] UID FETCH 响应长度: %d 字节", len(fetchResp))
-
-	msg := parseFullMessage(fetchResp)
-	msg.ID = messageID // 设置邮件ID，用于前端匹配选中状态
-	log.Printf("[IMAP] 解析邮件: ID=%s, Subject=%s, From=%v, BodyType=%s, BodyLen=%d",
-		msg.ID, msg.Subject, msg.From, msg.Body.ContentType, len(msg.Body.Content))
-
-	return msg, nil
+	if !found {
+		return "", nil, fmt.Errorf("message not found: %s", messageID)
+	}
+	log.Printf("[IMAP] UID FETCH 邮件长度: %d 字节", len(raw))
+	return raw, flags, nil
 }
 
 // parseMessages 解析邮件列表
-func parseMessages(resp string) []models.Message {
+//
+// 每条FETCH响应对应一封邮件：UID作为邮件ID，FLAGS判断已读和旗标，BODY[HEADER.FIELDS ...]为邮件头
+func parseMessages(resps []*imapResponse) []models.Message {
 	var messages []models.Message
 
-	blocks := strings.Split(resp, "* ")
-	for _, block := range blocks {
-		if !strings.Contains(block, "FETCH") {
+	for _, r := range resps {
+		if r.Name() != "FETCH" || len(r.Fields) < 3 {
 			continue
 		}
-		uidMatch := uidRe.FindStringSubmatch("* " + block)
-		if len(uidMatch) < 2 {
+		items := imapPairs(imapList(r.Fields[2]))
+		uid, ok := imapNumber(items["UID"])
+		if !ok {
 			continue
 		}
-
-		msg := models.Message{ID: uidMatch[1], IsRead: seenRe.MatchString(block)}
-
-		if m := fromRe.FindStringSubmatch(block); len(m) > 1 {
-			msg.From = &models.EmailAddr{}
-			msg.From.EmailAddress.Address = decodeHeader(strings.TrimSpace(m[1]))
+		flags := imapList(items["FLAGS"])
+		msg := models.Message{
+			ID:     strconv.FormatUint(uint64(uid), 10),
+			IsRead: hasFlag(flags, `\Seen`),
+			Flag:   models.NewMessageFlag(hasFlag(flags, `\Flagged`)),
 		}
-		if m := subjRe.FindStringSubmatch(block); len(m) > 1 {
-			msg.Subject = decodeHeader(strings.TrimSpace(m[1]))
-		}
-		if m := dateRe.FindStringSubmatch(block); len(m) > 1 {
-			msg.ReceivedDateTime = strings.TrimSpace(m[1])
+
+		if v, ok := fetchItem(items, "BODY["); ok {
+			parseHeaderFields(&msg, imapString(v))
 		}
 
 		messages = append(messages, msg)
@@ -583,38 +867,6 @@ func parseMessages(resp string) []models.Message {
 	return messages
 }
 
-// parseFullMessage 解析完整邮件
-func parseFullMessage(resp string) *models.Message {
-	msg := &models.Message{}
-
-	if m := fromRe.FindStringSubmatch(resp); len(m) > 1 {
-		msg.From = &models.EmailAddr{}
-		msg.From.EmailAddress.Address = decodeHeader(strings.TrimSpace(m[1]))
-	}
-	if m := toRe.FindStringSubmatch(resp); len(m) > 1 {
-		msg.ToRecipients = []models.EmailAddr{{}}
-		msg.ToRecipients[0].EmailAddress.Address = decodeHeader(strings.TrimSpace(m[1]))
-	}
-	if m := subjRe.FindStringSubmatch(resp); len(m) > 1 {
-		msg.Subject = decodeHeader(strings.TrimSpace(m[1]))
-	}
-	if m := dateRe.FindStringSubmatch(resp); len(m) > 1 {
-		msg.ReceivedDateTime = strings.TrimSpace(m[1])
-	}
-
-	// 提取正文
-	body, isHTML := extractBodyWithType(resp)
-	contentType := "Text"
-	if isHTML {
-		contentType = "HTML"
-		body = sanitizeHTML(body) // 清理HTML中的脚本
-	}
-	msg.Body = &models.MessageBody{ContentType: contentType, Content: body}
-	msg.BodyPreview = truncate(stripHTML(body), 200)
-
-	return msg
-}
-
 // stripHTML 移除HTML标签用于预览
 func stripHTML(s string) string {
 	return htmlTagRe.ReplaceAllString(s, "")
@@ -640,139 +892,6 @@ func sanitizeHTML(s string) string {
 	return s
 }
 
-// extractBodyWithType 提取邮件正文，返回内容和是否为HTML
-func extractBodyWithType(raw string) (string, bool) {
-	// 查找boundary
-	boundaryMatch := boundaryRe.FindStringSubmatch(raw)
-
-	// 如果是MIME多部分邮件
-	if len(boundaryMatch) > 1 {
-		boundary := boundaryMatch[1]
-		parts := strings.Split(raw, "--"+boundary)
-
-		var textContent, htmlContent string
-		for _, part := range parts {
-			partLower := strings.ToLower(part)
-
-			// 查找Content-Type
-			if strings.Contains(partLower, "content-type: text/plain") {
-				textContent = extractPartContent(part)
-			} else if strings.Contains(partLower, "content-type: text/html") {
-				htmlContent = extractPartContent(part)
-			}
-		}
-
-		// 优先返回HTML内容
-		if htmlContent != "" {
-			return htmlContent, true
-		}
-		if textContent != "" {
-			return textContent, false
-		}
-	}
-
-	// 非多部分邮件，直接提取正文
-	parts := strings.SplitN(raw, "\r\n\r\n", 2)
-	if len(parts) < 2 {
-		parts = strings.SplitN(raw, "\n\n", 2)
-	}
-	if len(parts) < 2 {
-		return "", false
-	}
-
-	body := parts[1]
-	// 移除IMAP结束标记
-	if idx := strings.LastIndex(body, ")\r\n"); idx > 0 {
-		body = body[:idx]
-	}
-	if idx := strings.LastIndex(body, " UID"); idx > 0 {
-		body = body[:idx]
-	}
-
-	content := decodeContent(parts[0], body)
-	isHTML := strings.Contains(strings.ToLower(parts[0]), "text/html")
-	return content, isHTML
-}
-
-// extractPartContent 从MIME部分提取内容
-func extractPartContent(part string) string {
-	// 分离头部和正文
-	parts := strings.SplitN(part, "\r\n\r\n", 2)
-	if len(parts) < 2 {
-		parts = strings.SplitN(part, "\n\n", 2)
-	}
-	if len(parts) < 2 {
-		return ""
-	}
-
-	header := parts[0]
-	body := strings.TrimSpace(parts[1])
-
-	// 移除结束boundary标记
-	if idx := strings.Index(body, "\r\n--"); idx > 0 {
-		body = body[:idx]
-	}
-	if idx := strings.Index(body, "\n--"); idx > 0 {
-		body = body[:idx]
-	}
-
-	return decodeContent(header, body)
-}
-
-// decodeContent 解码邮件内容
-func decodeContent(header, body string) string {
-	headerLower := strings.ToLower(header)
-
-	// 解码quoted-printable
-	if strings.Contains(headerLower, "quoted-printable") {
-		decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
-		if err == nil {
-			body = string(decoded)
-		}
-	}
-
-	// 解码base64
-	if strings.Contains(headerLower, "base64") {
-		cleanBody := strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", ""), "\n", "")
-		decoded, err := base64.StdEncoding.DecodeString(cleanBody)
-		if err == nil {
-			body = string(decoded)
-		}
-	}
-
-	return strings.TrimSpace(body)
-}
-
-// decodeHeader 解码邮件头（RFC 2047）
-func decodeHeader(s string) string {
-	dec := new(mime.WordDecoder)
-	decoded, err := dec.DecodeHeader(s)
-	if err != nil {
-		return s
-	}
-	return decoded
-}
-
-// decodeIMAPUTF7 解码IMAP修改版UTF-7
-func decodeIMAPUTF7(s string) string {
-	// 简单处理：将&替换为+，-替换为空
-	if !strings.Contains(s, "&") {
-		return s
-	}
-	// 常见中文文件夹映射
-	replacements := map[string]string{
-		"&UXZO1mWHTvZZOQ-": "已发送邮件",
-		"&V4NXPpCuTvY-":    "已删除邮件",
-		"&Xn9USpCuTvY-":    "垃圾邮件",
-		"&g0l6P3ux-":       "草稿",
-		"&W1hoYw-":         "存档",
-	}
-	if decoded, ok := replacements[s]; ok {
-		return decoded
-	}
-	return s
-}
-
 // truncate 截断字符串到指定长度
 //
 // 如果字符串长度超过指定值，截断并添加省略号"..."。
@@ -780,7 +899,7 @@ func decodeIMAPUTF7(s string) string {
 //
 // 参数：
 //   - s: 要截断的原始字符串
-//   - n: 最大保留长度（不含省略号）
+//   - n: 最大保留长度（字符数，不含省略号）
 //
 // 返回值：
 //   - string: 截断后的字符串，超长时末尾带"..."
@@ -789,9 +908,10 @@ func decodeIMAPUTF7(s string) string {
 //
 //	truncate("Hello World", 5) // => "Hello..."
 //	truncate("Hi", 5)          // => "Hi"
+//	truncate("你好世界", 2)      // => "你好..."
 func truncate(s string, n int) string {
-	if len(s) <= n {
+	if utf8.RuneCountInString(s) <= n {
 		return s
 	}
-	return s[:n] + "..."
+	return string([]rune(s)[:n]) + "..."
 }
@@ -0,0 +1,139 @@
+// Package services 业务服务层
+//
+// imap_utf7.go IMAP文件夹名编码（修改版UTF-7，RFC 3501 第5.1.3节）
+//
+// 编码规则：
+// - 可打印ASCII字符（0x20-0x7E）表示自身，"&"编码为"&-"
+// - 其他字符按UTF-16BE编码后做修改版base64（以","代替"/"，不补"="），包裹在"&"和"-"之间
+//
+// 示例："已发送邮件" <-> "&XfJT0ZABkK5O9g-"，"Tom & Jerry" <-> "Tom &- Jerry"
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// imapUTF7Encoding 修改版base64编码
+var imapUTF7Encoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+,").WithPadding(base64.NoPadding)
+
+// errInvalidUTF7 文件夹名不是合法的修改版UTF-7
+var errInvalidUTF7 = errors.New("invalid modified UTF-7 mailbox name")
+
+// encodeIMAPUTF7 将文件夹名编码为修改版UTF-7（发送SELECT、STATUS等命令时使用）
+//
+// 参数：
+//   - s: UTF-8文件夹名
+//
+// 返回值：
+//   - string: 编码后的文件夹名（纯ASCII）
+func encodeIMAPUTF7(s string) string {
+	var b strings.Builder
+	var run []rune // 待编码的非ASCII字符
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		units := utf16.Encode(run)
+		buf := make([]byte, 0, len(units)*2)
+		for _, u := range units {
+			buf = append(buf, byte(u>>8), byte(u))
+		}
+		b.WriteByte('&')
+		b.WriteString(imapUTF7Encoding.EncodeToString(buf))
+		b.WriteByte('-')
+		run = run[:0]
+	}
+	for _, r := range s {
+		switch {
+		case r == '&':
+			flush()
+			b.WriteString("&-")
+		case r >= 0x20 && r <= 0x7E:
+			flush()
+			b.WriteRune(r)
+		default:
+			run = append(run, r)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// decodeIMAPUTF7 解码修改版UTF-7文件夹名，格式错误时原样返回
+//
+// 参数：
+//   - s: LIST响应中的文件夹名
+//
+// 返回值：
+//   - string: UTF-8文件夹名
+func decodeIMAPUTF7(s string) string {
+	decoded, err := parseIMAPUTF7(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// parseIMAPUTF7 严格解码修改版UTF-7文件夹名
+//
+// 返回值：
+//   - string: UTF-8文件夹名
+//   - error: 含有非ASCII字节、"&"未闭合、base64或UTF-16格式错误时返回errInvalidUTF7
+func parseIMAPUTF7(s string) (string, error) {
+	if !strings.Contains(s, "&") {
+		if !isASCII(s) {
+			return "", errInvalidUTF7
+		}
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x80 {
+			return "", errInvalidUTF7
+		}
+		if c != '&' {
+			b.WriteByte(c)
+			continue
+		}
+		end := strings.IndexByte(s[i+1:], '-')
+		if end < 0 {
+			return "", errInvalidUTF7
+		}
+		encoded := s[i+1 : i+1+end]
+		i += end + 1
+		if encoded == "" {
+			b.WriteByte('&') // "&-"
+			continue
+		}
+		buf, err := imapUTF7Encoding.DecodeString(encoded)
+		if err != nil || len(buf)%2 != 0 {
+			return "", errInvalidUTF7
+		}
+		units := make([]uint16, len(buf)/2)
+		for j := range units {
+			units[j] = uint16(buf[2*j])<<8 | uint16(buf[2*j+1])
+		}
+		for _, r := range utf16.Decode(units) {
+			if r == utf8.RuneError {
+				return "", errInvalidUTF7 // 不成对的代理项
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
+
+// isASCII 判断字符串是否只包含ASCII字符
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
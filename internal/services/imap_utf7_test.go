@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIMAPUTF7RoundTrip(t *testing.T) {
+	tests := []struct {
+		decoded, encoded string
+	}{
+		{"INBOX", "INBOX"},
+		{"", ""},
+		{"已发送邮件", "&XfJT0ZABkK5O9g-"},
+		{"Tom & Jerry", "Tom &- Jerry"},
+		{"&", "&-"},
+		{"&&", "&-&-"},
+		{"~peter/mail/台北/日本語", "~peter/mail/&U,BTFw-/&ZeVnLIqe-"}, // RFC 3501 示例
+		{"Café & Bar", "Caf&AOk- &- Bar"},
+		{"😀", "&2D3eAA-"}, // 非BMP字符编码为代理对
+		{"项目😀/归档", "&mHl27tg93gA-/&X1JoYw-"},
+		{"Tab\there", "Tab&AAk-here"}, // 控制字符不能直接表示
+	}
+	for _, tt := range tests {
+		if got := encodeIMAPUTF7(tt.decoded); got != tt.encoded {
+			t.Errorf("encodeIMAPUTF7(%q) = %q, want %q", tt.decoded, got, tt.encoded)
+		}
+		got, err := parseIMAPUTF7(tt.encoded)
+		if err != nil || got != tt.decoded {
+			t.Errorf("parseIMAPUTF7(%q) = %q, %v, want %q", tt.encoded, got, err, tt.decoded)
+		}
+	}
+}
+
+func TestParseIMAPUTF7Invalid(t *testing.T) {
+	for _, s := range []string{
+		"已发送",        // 未编码的非ASCII字符
+		"Sent &-已发送", // 含"&"时的非ASCII字符
+		"&XfJT0Q",    // "&"未闭合
+		"&Xf!T-",     // 非法base64字符
+		"&AA-",       // UTF-16字节数为奇数
+		"&2D0-",      // 不成对的高代理项
+		"&3gA-",      // 不成对的低代理项
+	} {
+		if _, err := parseIMAPUTF7(s); !errors.Is(err, errInvalidUTF7) {
+			t.Errorf("parseIMAPUTF7(%q) error = %v, want errInvalidUTF7", s, err)
+		}
+		// 宽松解码时原样返回
+		if got := decodeIMAPUTF7(s); got != s {
+			t.Errorf("decodeIMAPUTF7(%q) = %q, want input unchanged", s, got)
+		}
+	}
+}
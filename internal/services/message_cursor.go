@@ -0,0 +1,145 @@
+// Package services 业务服务层
+//
+// message_cursor.go 邮件列表分页游标
+//
+// 游标对前端是不透明的字符串，带协议前缀，防止回退协议后误用另一种协议的游标：
+//   - IMAP："imap:<UIDVALIDITY>:<UID>"，下一页为UID小于该值的邮件
+//   - REST API："o2:<base64编码的JSON>"，记录上一页最后一封邮件的接收时间和该时间的邮件ID，
+//     下一页为接收时间不晚于该时间、且不是这些邮件的邮件（不使用偏移量，新邮件到达时分页不会错位）
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"outlook-mail-manager/internal/models"
+)
+
+// ErrCursorExpired 游标已失效（文件夹的UIDVALIDITY变化、协议切换或格式错误），需要从第一页重新加载
+var ErrCursorExpired = errors.New("cursor expired")
+
+// formatIMAPCursor 生成IMAP游标
+func formatIMAPCursor(uidValidity, beforeUID uint32) string {
+	return fmt.Sprintf("imap:%d:%d", uidValidity, beforeUID)
+}
+
+// parseIMAPCursor 解析IMAP游标
+//
+// 返回值：
+//   - uint32: 生成游标时文件夹的UIDVALIDITY
+//   - uint32: 下一页邮件的UID上限（不含）
+//   - error: 不是IMAP游标时返回ErrCursorExpired
+func parseIMAPCursor(cursor string) (uint32, uint32, error) {
+	parts := strings.Split(cursor, ":")
+	if len(parts) != 3 || parts[0] != "imap" {
+		return 0, 0, ErrCursorExpired
+	}
+	validity, err1 := strconv.ParseUint(parts[1], 10, 32)
+	uid, err2 := strconv.ParseUint(parts[2], 10, 32)
+	if err1 != nil || err2 != nil {
+		return 0, 0, ErrCursorExpired
+	}
+	return uint32(validity), uint32(uid), nil
+}
+
+// graphCursor REST API游标的内容
+type graphCursor struct {
+	Before string   `json:"t"`   // 上一页最后一封邮件的接收时间（RFC 3339，UTC）
+	IDs    []string `json:"ids"` // 已返回的、接收时间等于Before的邮件ID（下一页需要排除）
+}
+
+// formatGraphCursor 生成REST API游标
+func formatGraphCursor(c graphCursor) string {
+	data, _ := json.Marshal(c)
+	return "o2:" + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// parseGraphCursor 解析REST API游标，空游标表示第一页
+//
+// 返回值：
+//   - *graphCursor: 游标内容，第一页时为nil
+//   - error: 不是REST API游标或内容无效时返回ErrCursorExpired
+func parseGraphCursor(cursor string) (*graphCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(cursor, "o2:") {
+		return nil, ErrCursorExpired
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(cursor, "o2:"))
+	if err != nil {
+		return nil, ErrCursorExpired
+	}
+	var c graphCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrCursorExpired
+	}
+	// 接收时间直接拼接进$filter，只接受合法的时间
+	t, err := time.Parse(time.RFC3339, c.Before)
+	if err != nil {
+		return nil, ErrCursorExpired
+	}
+	c.Before = t.UTC().Format(time.RFC3339)
+	return &c, nil
+}
+
+// buildGraphPage 由REST API返回的邮件组装一页结果
+//
+// 查询时多取了len(prev.IDs)封邮件，这里排除上一页已返回的邮件后截取top封，
+// 并以本页最后一封邮件的接收时间生成下一页游标
+//
+// 参数：
+//   - prev: 本次请求使用的游标，第一页时为nil
+//   - messages: API返回的邮件（按接收时间倒序）
+//   - nextLink: API是否返回了@odata.nextLink
+//   - top: 每页数量
+//
+// 返回值：
+//   - *models.MessagePage: 一页邮件和下一页游标
+func buildGraphPage(prev *graphCursor, messages []models.Message, nextLink bool, top int) *models.MessagePage {
+	requested := top
+	seen := map[string]bool{}
+	if prev != nil {
+		requested += len(prev.IDs)
+		for _, id := range prev.IDs {
+			seen[id] = true
+		}
+	}
+	page := &models.MessagePage{Messages: []models.Message{}}
+	for _, m := range messages {
+		if len(page.Messages) == top {
+			break
+		}
+		if !seen[m.ID] {
+			page.Messages = append(page.Messages, m)
+		}
+	}
+	// 有nextLink、结果已满或多出的邮件未返回时认为还有下一页
+	if len(page.Messages) == 0 || (!nextLink && len(messages) < requested) {
+		return page
+	}
+	last, err := time.Parse(time.RFC3339, page.Messages[len(page.Messages)-1].ReceivedDateTime)
+	if err != nil {
+		log.Printf("[Graph API] 邮件接收时间无法解析，不再翻页: %v", err)
+		return page
+	}
+	next := graphCursor{Before: last.UTC().Format(time.RFC3339)}
+	for _, m := range page.Messages {
+		if t, err := time.Parse(time.RFC3339, m.ReceivedDateTime); err == nil && t.Equal(last) {
+			next.IDs = append(next.IDs, m.ID)
+		}
+	}
+	// 同一时间的邮件跨越多页时，继续排除更早页中已返回的邮件
+	if prev != nil && prev.Before == next.Before {
+		next.IDs = append(prev.IDs, next.IDs...)
+	}
+	page.HasMore = true
+	page.NextCursor = formatGraphCursor(next)
+	return page
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"outlook-mail-manager/internal/models"
+)
+
+func TestIMAPCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		validity, uid uint32
+		want          string
+	}{
+		{1, 1, "imap:1:1"},
+		{3857529045, 2401, "imap:3857529045:2401"},
+		{math.MaxUint32, math.MaxUint32, "imap:4294967295:4294967295"},
+	}
+	for _, tt := range tests {
+		cursor := formatIMAPCursor(tt.validity, tt.uid)
+		if cursor != tt.want {
+			t.Errorf("formatIMAPCursor(%d, %d) = %q, want %q", tt.validity, tt.uid, cursor, tt.want)
+		}
+		validity, uid, err := parseIMAPCursor(cursor)
+		if err != nil || validity != tt.validity || uid != tt.uid {
+			t.Errorf("parseIMAPCursor(%q) = %d, %d, %v", cursor, validity, uid, err)
+		}
+	}
+}
+
+func TestParseIMAPCursorInvalid(t *testing.T) {
+	for _, cursor := range []string{
+		"",
+		"o2:20",              // REST API游标
+		"imap:14",            // 缺少UID
+		"imap:14:20:1",       // 多余字段
+		"IMAP:14:20",         // 前缀区分大小写
+		"imap:x:20",          // UIDVALIDITY不是数字
+		"imap:14:-1",         // 负数
+		"imap:14:4294967296", // 超出uint32
+	} {
+		if _, _, err := parseIMAPCursor(cursor); !errors.Is(err, ErrCursorExpired) {
+			t.Errorf("parseIMAPCursor(%q) error = %v, want ErrCursorExpired", cursor, err)
+		}
+	}
+}
+
+func TestGraphCursorRoundTrip(t *testing.T) {
+	for _, c := range []graphCursor{
+		{Before: "2024-05-01T08:00:00Z"},
+		{Before: "2024-05-01T08:00:00Z", IDs: []string{"AAMk1", "AAMk2"}},
+	} {
+		cursor := formatGraphCursor(c)
+		got, err := parseGraphCursor(cursor)
+		if err != nil || !reflect.DeepEqual(*got, c) {
+			t.Errorf("parseGraphCursor(%q) = %+v, %v, want %+v", cursor, got, err, c)
+		}
+	}
+	if c, err := parseGraphCursor(""); err != nil || c != nil {
+		t.Errorf("parseGraphCursor(\"\") = %+v, %v, want first page", c, err)
+	}
+}
+
+func TestParseGraphCursorInvalid(t *testing.T) {
+	for _, cursor := range []string{
+		"imap:14:20", // IMAP游标
+		"o2:20",      // 偏移量不是有效游标
+		"o2:",
+		"o2:!!",
+		formatGraphCursor(graphCursor{Before: "2024-05-01 or true"}),
+		"eyJ0IjoiMjAyNC0wNS0wMVQwODowMDowMFoifQ", // 缺少前缀
+	} {
+		if _, err := parseGraphCursor(cursor); !errors.Is(err, ErrCursorExpired) {
+			t.Errorf("parseGraphCursor(%q) error = %v, want ErrCursorExpired", cursor, err)
+		}
+	}
+}
+
+// graphMessages 按"ID@时间"生成邮件列表，时间为2024-05-01当天的时:分
+func graphMessages(specs ...string) []models.Message {
+	var messages []models.Message
+	for _, spec := range specs {
+		id, hm, _ := strings.Cut(spec, "@")
+		messages = append(messages, models.Message{ID: id, ReceivedDateTime: "2024-05-01T" + hm + ":00Z"})
+	}
+	return messages
+}
+
+func TestBuildGraphPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		prev     *graphCursor
+		messages []models.Message
+		nextLink bool
+		wantIDs  []string
+		wantNext *graphCursor // nil表示没有下一页
+	}{
+		{
+			name:     "第一页",
+			messages: graphMessages("m1@10:00", "m2@09:00", "m3@09:00"),
+			nextLink: true,
+			wantIDs:  []string{"m1", "m2", "m3"},
+			wantNext: &graphCursor{Before: "2024-05-01T09:00:00Z", IDs: []string{"m2", "m3"}},
+		},
+		{
+			name:     "排除上一页已返回的同一时间邮件",
+			prev:     &graphCursor{Before: "2024-05-01T09:00:00Z", IDs: []string{"m2", "m3"}},
+			messages: graphMessages("m2@09:00", "m3@09:00", "m4@09:00", "m5@08:00", "m6@07:00"),
+			nextLink: true,
+			wantIDs:  []string{"m4", "m5", "m6"},
+			wantNext: &graphCursor{Before: "2024-05-01T07:00:00Z", IDs: []string{"m6"}},
+		},
+		{
+			name:     "同一时间的邮件跨越多页",
+			prev:     &graphCursor{Before: "2024-05-01T09:00:00Z", IDs: []string{"m2"}},
+			messages: graphMessages("m2@09:00", "m3@09:00", "m4@09:00", "m5@09:00"),
+			wantIDs:  []string{"m3", "m4", "m5"},
+			wantNext: &graphCursor{Before: "2024-05-01T09:00:00Z", IDs: []string{"m2", "m3", "m4", "m5"}},
+		},
+		{
+			name:     "最后一页",
+			prev:     &graphCursor{Before: "2024-05-01T09:00:00Z", IDs: []string{"m2"}},
+			messages: graphMessages("m2@09:00", "m3@08:00"),
+			wantIDs:  []string{"m3"},
+		},
+		{
+			name:     "没有更多邮件",
+			prev:     &graphCursor{Before: "2024-05-01T09:00:00Z", IDs: []string{"m2"}},
+			messages: graphMessages("m2@09:00"),
+			wantIDs:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		page := buildGraphPage(tt.prev, tt.messages, tt.nextLink, 3)
+		var ids []string
+		for _, m := range page.Messages {
+			ids = append(ids, m.ID)
+		}
+		if ids == nil {
+			ids = []string{}
+		}
+		if !reflect.DeepEqual(ids, tt.wantIDs) {
+			t.Errorf("%s: messages = %q, want %q", tt.name, ids, tt.wantIDs)
+		}
+		if page.HasMore != (tt.wantNext != nil) {
+			t.Errorf("%s: HasMore = %v", tt.name, page.HasMore)
+			continue
+		}
+		if tt.wantNext == nil {
+			continue
+		}
+		next, err := parseGraphCursor(page.NextCursor)
+		if err != nil || !reflect.DeepEqual(next, tt.wantNext) {
+			t.Errorf("%s: next cursor = %+v, %v, want %+v", tt.name, next, err, tt.wantNext)
+		}
+	}
+}
+
+// TestBuildGraphPageNewMail 翻页之间收到新邮件时，下一页既不重复也不遗漏
+func TestBuildGraphPageNewMail(t *testing.T) {
+	inbox := graphMessages("m1@10:00", "m2@09:00", "m3@08:00", "m4@07:00", "m5@06:00")
+	first := buildGraphPage(nil, inbox[:2], true, 2)
+	prev, err := parseGraphCursor(first.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 新邮件比游标时间晚，不会出现在按游标时间过滤的结果里；模拟服务端按$filter过滤
+	inbox = append(graphMessages("n1@11:00"), inbox...)
+	var filtered []models.Message
+	for _, m := range inbox {
+		if m.ReceivedDateTime <= prev.Before {
+			filtered = append(filtered, m)
+		}
+	}
+	second := buildGraphPage(prev, filtered[:2+len(prev.IDs)], true, 2)
+	var ids []string
+	for _, m := range second.Messages {
+		ids = append(ids, m.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"m3", "m4"}) {
+		t.Errorf("second page = %q, want [m3 m4]", ids)
+	}
+}
@@ -0,0 +1,304 @@
+// Package services 业务服务层
+//
+// mime_parser.go 邮件原文（RFC 5322 / MIME）解析
+//
+// 功能说明：
+// - 使用net/mail读取邮件头（处理折行），mime/multipart递归遍历整个MIME树
+// - 发件人、收件人、抄送、回复地址按地址列表解析，包含显示名称
+// - 支持任意嵌套的多部分邮件，如 mixed → alternative → related
+// - 正文：取第一个非附件的text/html和text/plain部分，优先使用HTML
+// - 附件：Content-Disposition为attachment或带文件名的部分（包括内嵌图片和转发的邮件）
+// - 解码base64和quoted-printable传输编码，按charset转换为UTF-8（见charset.go）
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"outlook-mail-manager/internal/models"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMIMEDepth MIME树的最大嵌套深度，防止构造的邮件导致无限递归
+const maxMIMEDepth = 20
+
+// mimeContent 遍历MIME树收集的正文和附件
+type mimeContent struct {
+	text        string              // 第一个text/plain正文
+	html        string              // 第一个text/html正文
+	hasText     bool                // 是否已找到text/plain正文
+	hasHTML     bool                // 是否已找到text/html正文
+	attachments []models.Attachment // 附件列表（按在邮件中出现的顺序）
+}
+
+// parseFullMessage 解析完整邮件
+//
+// 参数：
+//   - raw: 邮件原文（BODY[]字面量的内容）
+//
+// 返回值：
+//   - *models.Message: 邮件头、正文和附件（Attachments包含附件内容）
+func parseFullMessage(raw string) *models.Message {
+	msg := &models.Message{Attachments: []models.Attachment{}}
+
+	m, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		// 邮件头格式错误时整封邮件按纯文本显示
+		msg.Body = &models.MessageBody{ContentType: "Text", Content: raw}
+		msg.BodyPreview = truncate(raw, 200)
+		return msg
+	}
+	fillHeaderFields(msg, m.Header)
+	if sender := parseAddressList(m.Header.Get("Sender")); len(sender) > 0 {
+		msg.Sender = &sender[0]
+	}
+	msg.ToRecipients = parseAddressList(m.Header.Get("To"))
+	msg.CcRecipients = parseAddressList(m.Header.Get("Cc"))
+	msg.BccRecipients = parseAddressList(m.Header.Get("Bcc"))
+	msg.ReplyTo = parseAddressList(m.Header.Get("Reply-To"))
+
+	content := &mimeContent{}
+	walkMIMEPart(textproto.MIMEHeader(m.Header), m.Body, "1", 0, content)
+
+	body, contentType := content.text, "Text"
+	if content.hasHTML {
+		body, contentType = sanitizeHTML(content.html), "HTML" // 清理HTML中的脚本
+	}
+	msg.Body = &models.MessageBody{ContentType: contentType, Content: body}
+	msg.BodyPreview = truncate(stripHTML(body), 200)
+	msg.Attachments = append(msg.Attachments, content.attachments...)
+	msg.HasAttachments = len(msg.Attachments) > 0
+	return msg
+}
+
+// parseHeaderFields 解析FETCH返回的部分邮件头（BODY[HEADER.FIELDS (...)]），填充发件人、主题和时间
+func parseHeaderFields(msg *models.Message, header string) {
+	// 部分邮件头之后可能没有空行，补上空行使其成为完整的邮件
+	m, err := mail.ReadMessage(strings.NewReader(strings.TrimRight(header, "\r\n") + "\r\n\r\n"))
+	if err != nil {
+		return
+	}
+	fillHeaderFields(msg, m.Header)
+}
+
+// fillHeaderFields 从邮件头填充发件人、主题和时间
+func fillHeaderFields(msg *models.Message, h mail.Header) {
+	if from := parseAddressList(h.Get("From")); len(from) > 0 {
+		msg.From = &from[0]
+	}
+	msg.Subject = decodeHeader(h.Get("Subject"))
+	msg.ReceivedDateTime = h.Get("Date")
+}
+
+// parseAddressList 解析地址列表邮件头（From、To、Cc、Reply-To等）
+//
+// 按RFC 5322解析显示名称和地址，显示名称中的编码字按声明的字符集转换；
+// 整个列表无法解析时（如显示名称含未加引号的特殊字符）逐个地址解析，
+// 仍无法解析的地址尽量提取尖括号中的邮箱地址，其余部分作为显示名称
+//
+// 参数：
+//   - value: 邮件头的值（已由net/mail展开折行），为空时返回nil
+//
+// 返回值：
+//   - []models.EmailAddr: 地址列表（地址组展开为其成员）
+func parseAddressList(value string) []models.EmailAddr {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if !utf8.ValidString(value) {
+		value = detectAndDecode([]byte(value))
+	}
+	parser := &mail.AddressParser{WordDecoder: &mime.WordDecoder{CharsetReader: charsetReader}}
+	if list, err := parser.ParseList(value); err == nil {
+		addrs := make([]models.EmailAddr, 0, len(list))
+		for _, a := range list {
+			addrs = append(addrs, models.NewEmailAddr(a.Name, a.Address))
+		}
+		return addrs
+	}
+
+	var addrs []models.EmailAddr
+	for _, part := range splitAddressList(value) {
+		if a, err := parser.Parse(part); err == nil {
+			addrs = append(addrs, models.NewEmailAddr(a.Name, a.Address))
+			continue
+		}
+		part = decodeHeader(part)
+		name, address := part, ""
+		if i, j := strings.LastIndex(part, "<"), strings.LastIndex(part, ">"); i >= 0 && j > i {
+			name, address = part[:i], strings.TrimSpace(part[i+1:j])
+		} else if strings.Contains(part, "@") && !strings.ContainsAny(part, " \t") {
+			name, address = "", part
+		}
+		addrs = append(addrs, models.NewEmailAddr(strings.Trim(strings.TrimSpace(name), `"'`), address))
+	}
+	return addrs
+}
+
+// splitAddressList 按逗号拆分地址列表，忽略引号、尖括号和注释中的逗号
+func splitAddressList(value string) []string {
+	var parts []string
+	var quoted, escaped bool
+	angle, comment, start := 0, 0, 0
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"' && comment == 0:
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			comment++
+		case c == ')' && comment > 0:
+			comment--
+		case c == '<' && comment == 0:
+			angle++
+		case c == '>' && angle > 0:
+			angle--
+		case c == ',' && angle == 0 && comment == 0:
+			if p := strings.TrimSpace(value[start:i]); p != "" {
+				parts = append(parts, p)
+			}
+			start = i + 1
+		}
+	}
+	if p := strings.TrimSpace(value[start:]); p != "" {
+		parts = append(parts, p)
+	}
+	return parts
+}
+
+// walkMIMEPart 递归遍历一个MIME部分
+//
+// 参数：
+//   - header: 该部分的头（顶层为邮件头）
+//   - body: 该部分的内容（未解码传输编码）
+//   - path: 部分编号（如"1.2.1"），作为附件ID
+//   - depth: 当前嵌套深度
+//   - content: 收集结果
+func walkMIMEPart(header textproto.MIMEHeader, body io.Reader, path string, depth int, content *mimeContent) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{} // RFC 2045：缺失或无法解析时按text/plain处理
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" && depth < maxMIMEDepth {
+		mr := multipart.NewReader(body, params["boundary"])
+		for i := 1; ; i++ {
+			// NextRawPart不自动解码quoted-printable，统一由decodeTransferEncoding处理
+			part, err := mr.NextRawPart()
+			if err != nil {
+				return // io.EOF或结构损坏，保留已解析的部分
+			}
+			walkMIMEPart(part.Header, part, fmt.Sprintf("%s.%d", path, i), depth+1, content)
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil && len(data) == 0 {
+		return
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	filename = decodeHeader(filename)
+
+	isBody := disposition != "attachment" && filename == "" &&
+		(mediaType == "text/plain" || mediaType == "text/html")
+	if isBody {
+		charset := params["charset"]
+		if charset == "" && mediaType == "text/html" {
+			charset = htmlMetaCharset(data)
+		}
+		text := strings.TrimSpace(decodeCharset(data, charset))
+		if mediaType == "text/html" && !content.hasHTML {
+			content.html, content.hasHTML = text, true
+		} else if mediaType == "text/plain" && !content.hasText {
+			content.text, content.hasText = text, true
+		}
+		return
+	}
+
+	if filename == "" {
+		filename = "attachment-" + path
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			filename += exts[0]
+		} else if mediaType == "message/rfc822" {
+			filename += ".eml"
+		}
+	}
+	content.attachments = append(content.attachments, models.Attachment{
+		ID:           path,
+		Name:         filename,
+		ContentType:  mediaType,
+		Size:         len(data),
+		ContentBytes: base64.StdEncoding.EncodeToString(data),
+	})
+}
+
+// decodeTransferEncoding 按Content-Transfer-Encoding解码内容
+//
+// base64解码器会忽略换行；未知编码（7bit、8bit、binary等）原样返回
+func decodeTransferEncoding(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// base64Cleaner 去除base64内容中的空格、制表符等非编码字符
+//
+// 标准库的解码器只忽略CR和LF，部分邮件的base64行尾带有空格
+type base64Cleaner struct {
+	r io.Reader
+}
+
+// Read 读取并去除空格和制表符
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	for {
+		n, err := c.r.Read(p)
+		j := 0
+		for _, b := range p[:n] {
+			if b != ' ' && b != '\t' {
+				p[j] = b
+				j++
+			}
+		}
+		// 读到的全是空白时继续读取，避免返回(0, nil)
+		if j > 0 || err != nil || n == 0 {
+			return j, err
+		}
+	}
+}
+
+// decodeHeader 解码邮件头（RFC 2047）
+//
+// 编码字（=?charset?B/Q?...?=）按其声明的字符集转换；
+// 未编码直接包含8位字节的邮件头（部分中文邮件客户端的做法）按检测到的字符集转换
+func decodeHeader(s string) string {
+	if !utf8.ValidString(s) {
+		s = detectAndDecode([]byte(s))
+	}
+	dec := &mime.WordDecoder{CharsetReader: charsetReader}
+	decoded, err := dec.DecodeHeader(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"outlook-mail-manager/internal/models"
+)
+
+func TestSplitAddressList(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"single", "a@example.com", []string{"a@example.com"}},
+		{"plain list", "a@example.com, b@example.com,c@example.com", []string{"a@example.com", "b@example.com", "c@example.com"}},
+		{"comma in quoted name", `"Zhang, San" <zs@example.com>, b@example.com`, []string{`"Zhang, San" <zs@example.com>`, "b@example.com"}},
+		{"escaped quote in name", `"A \"x, y\" B" <a@example.com>, b@example.com`, []string{`"A \"x, y\" B" <a@example.com>`, "b@example.com"}},
+		{"comma in angle brackets", "<a,b@example.com>, c@example.com", []string{"<a,b@example.com>", "c@example.com"}},
+		{"comma in comment", "a@example.com (Sales, East), b@example.com", []string{"a@example.com (Sales, East)", "b@example.com"}},
+		{"nested comment", "a@example.com (x (y, z) w), b@example.com", []string{"a@example.com (x (y, z) w)", "b@example.com"}},
+		{"parenthesis in quotes", `"Li (Ops" <li@example.com>, b@example.com`, []string{`"Li (Ops" <li@example.com>`, "b@example.com"}},
+		{"empty entries", " , a@example.com,, ,b@example.com, ", []string{"a@example.com", "b@example.com"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitAddressList(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitAddressList(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAddressList(t *testing.T) {
+	addr := models.NewEmailAddr
+	tests := []struct {
+		name  string
+		value string
+		want  []models.EmailAddr
+	}{
+		{"empty", "  ", nil},
+		{"RFC 5322 list", `"Zhang, San" <zs@example.com>, b@example.com`,
+			[]models.EmailAddr{addr("Zhang, San", "zs@example.com"), addr("", "b@example.com")}},
+		{"encoded word", "=?UTF-8?B?5byg5LiJ?= <zs@example.com>",
+			[]models.EmailAddr{addr("张三", "zs@example.com")}},
+		{"GBK encoded word", "=?GBK?B?1cXI/Q==?= <zs@example.com>",
+			[]models.EmailAddr{addr("张三", "zs@example.com")}},
+		// 以下为不符合RFC 5322的地址，整体解析失败后逐个回退解析
+		{"fallback unquoted special characters", "Zhang San [Sales] <zs@example.com>, b@example.com",
+			[]models.EmailAddr{addr("Zhang San [Sales]", "zs@example.com"), addr("", "b@example.com")}},
+		{"fallback unquoted dot in name", "J.R. Smith <jr@example.com>",
+			[]models.EmailAddr{addr("J.R. Smith", "jr@example.com")}},
+		{"fallback bare address", "a@example.com, user@@example.com",
+			[]models.EmailAddr{addr("", "a@example.com"), addr("", "user@@example.com")}},
+		{"fallback name without address", "undisclosed recipients, a@example.com",
+			[]models.EmailAddr{addr("undisclosed recipients", ""), addr("", "a@example.com")}},
+		{"fallback single-quoted name", "'Zhang San' <zs@example..com>",
+			[]models.EmailAddr{addr("Zhang San", "zs@example..com")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseAddressList(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAddressList(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Package services 业务服务层
+//
+// setting_service.go 应用设置服务
+//
+// 功能说明：
+// - 以键值对形式持久化用户设置（settings表）
+// - 提供带默认值的读取方法
+package services
+
+import (
+	"outlook-mail-manager/internal/database"
+	"strconv"
+)
+
+// 设置项键名
+const (
+	// SettingRecycleRetentionDays 回收站保留天数，超过天数的已删除账号会被自动彻底删除，0表示永不自动清理
+	SettingRecycleRetentionDays = "recycle_retention_days"
+	// SettingWatchedAccounts 开启新邮件提醒的账号ID列表（逗号分隔），启动时自动恢复监听
+	SettingWatchedAccounts = "watched_accounts"
+	// SettingAutoMarkRead 查看邮件详情时是否自动标记为已读（"1"开启），默认关闭，查看邮件不改变服务器上的已读状态
+	SettingAutoMarkRead = "auto_mark_read"
+)
+
+// SettingService 应用设置服务
+//
+// 所有设置以文本形式存储，由调用方按需转换类型
+type SettingService struct{}
+
+// NewSettingService 创建设置服务实例
+//
+// 返回值：
+//   - *SettingService: 服务实例
+func NewSettingService() *SettingService {
+	return &SettingService{}
+}
+
+// Get 读取设置项
+//
+// 参数：
+//   - key: 设置项键名
+//   - def: 设置项不存在时的默认值
+//
+// 返回值：
+//   - string: 设置值
+func (s *SettingService) Get(key, def string) string {
+	var value string
+	if err := database.DB.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value); err != nil {
+		return def
+	}
+	return value
+}
+
+// GetInt 读取整数类型的设置项
+//
+// 参数：
+//   - key: 设置项键名
+//   - def: 设置项不存在或无法解析时的默认值
+//
+// 返回值：
+//   - int: 设置值
+func (s *SettingService) GetInt(key string, def int) int {
+	n, err := strconv.Atoi(s.Get(key, ""))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// Set 写入设置项（存在则覆盖）
+//
+// 参数：
+//   - key: 设置项键名
+//   - value: 设置值
+//
+// 返回值：
+//   - error: 写入失败时返回错误
+func (s *SettingService) Set(key, value string) error {
+	_, err := database.DB.Exec(`INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
@@ -0,0 +1,225 @@
+// Package services 业务服务层
+//
+// tag_service.go 标签服务
+//
+// 功能说明：
+// - 标签的CRUD操作（增删改查）
+// - 标签内账号数量统计
+// - 按账号ID批量打标签/取消标签
+// - 批量查询账号的标签（用于账号列表填充）
+package services
+
+import (
+	"fmt"
+	"outlook-mail-manager/internal/database"
+	"outlook-mail-manager/internal/models"
+	"strings"
+)
+
+// TagService 标签服务
+//
+// 提供标签相关的所有数据库操作
+// 标签与账号为多对多关系，关联存储在account_tags表
+type TagService struct{}
+
+// NewTagService 创建标签服务实例
+//
+// 返回值：
+//   - *TagService: 服务实例
+func NewTagService() *TagService {
+	return &TagService{}
+}
+
+// List 获取所有标签列表
+//
+// 使用子查询统计每个标签关联的账号数量，按名称排序
+//
+// 返回值：
+//   - []models.Tag: 标签列表，包含账号数量
+//   - error: 数据库查询错误
+func (s *TagService) List() ([]models.Tag, error) {
+	rows, err := database.DB.Query(`SELECT t.id, t.name, COALESCE(t.color,''),
+		(SELECT COUNT(*) FROM account_tags at JOIN accounts a ON at.account_id = a.id WHERE at.tag_id = t.id AND a.deleted_at IS NULL) as count
+		FROM tags t ORDER BY t.name, t.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Color, &t.Count); err != nil {
+			continue // 跳过解析失败的行
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// Get 根据ID获取标签
+//
+// 参数：
+//   - id: 标签ID
+//
+// 返回值：
+//   - *models.Tag: 标签对象
+//   - error: 标签不存在或数据库错误
+func (s *TagService) Get(id int64) (*models.Tag, error) {
+	var t models.Tag
+	err := database.DB.QueryRow("SELECT id, name, COALESCE(color,'') FROM tags WHERE id = ?", id).Scan(&t.ID, &t.Name, &t.Color)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create 创建新标签
+//
+// 参数：
+//   - name: 标签名称（不能为空，不能与已有标签重名）
+//   - color: 标签颜色（可为空）
+//
+// 返回值：
+//   - *models.Tag: 创建成功的标签对象
+//   - error: 名称为空或重名时返回错误
+func (s *TagService) Create(name, color string) (*models.Tag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("tag name is empty")
+	}
+	res, err := database.DB.Exec("INSERT INTO tags (name, color) VALUES (?, ?)", name, color)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := res.LastInsertId()
+	return &models.Tag{ID: id, Name: name, Color: color}, nil
+}
+
+// Update 更新标签名称和颜色
+//
+// 参数：
+//   - id: 标签ID
+//   - name: 新的标签名称
+//   - color: 新的标签颜色
+//
+// 返回值：
+//   - error: 更新失败时返回错误
+func (s *TagService) Update(id int64, name, color string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("tag name is empty")
+	}
+	_, err := database.DB.Exec("UPDATE tags SET name = ?, color = ? WHERE id = ?", name, color, id)
+	return err
+}
+
+// Delete 删除标签
+//
+// 同时删除该标签与所有账号的关联（账号本身不受影响）
+//
+// 参数：
+//   - id: 要删除的标签ID
+//
+// 返回值：
+//   - error: 删除失败时返回错误
+func (s *TagService) Delete(id int64) error {
+	// 先删除关联关系（SQLite默认未开启外键约束，需手动清理）
+	if _, err := database.DB.Exec("DELETE FROM account_tags WHERE tag_id = ?", id); err != nil {
+		return err
+	}
+	_, err := database.DB.Exec("DELETE FROM tags WHERE id = ?", id)
+	return err
+}
+
+// AddToAccounts 为多个账号批量打上标签
+//
+// 已有该标签的账号会被忽略（INSERT OR IGNORE）
+//
+// 参数：
+//   - tagID: 标签ID
+//   - accountIDs: 账号ID列表
+//
+// 返回值：
+//   - error: 写入失败时返回错误
+func (s *TagService) AddToAccounts(tagID int64, accountIDs []int64) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, id := range accountIDs {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO account_tags (account_id, tag_id) VALUES (?, ?)", id, tagID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveFromAccounts 批量移除多个账号的标签
+//
+// 参数：
+//   - tagID: 标签ID
+//   - accountIDs: 账号ID列表
+//
+// 返回值：
+//   - error: 删除失败时返回错误
+func (s *TagService) RemoveFromAccounts(tagID int64, accountIDs []int64) error {
+	if len(accountIDs) == 0 {
+		return nil
+	}
+	args := []interface{}{tagID}
+	for _, id := range accountIDs {
+		args = append(args, id)
+	}
+	_, err := database.DB.Exec("DELETE FROM account_tags WHERE tag_id = ? AND account_id IN ("+placeholders(len(accountIDs))+")", args...)
+	return err
+}
+
+// tagsByAccount 批量查询账号的标签
+//
+// 内部方法，供AccountService.List填充账号标签使用，避免逐个账号查询
+//
+// 返回值：
+//   - map[int64][]models.Tag: 账号ID -> 标签列表
+//   - error: 数据库查询错误
+func tagsByAccount() (map[int64][]models.Tag, error) {
+	rows, err := database.DB.Query(`SELECT at.account_id, t.id, t.name, COALESCE(t.color,'')
+		FROM account_tags at JOIN tags t ON at.tag_id = t.id ORDER BY t.name, t.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int64][]models.Tag)
+	for rows.Next() {
+		var accountID int64
+		var t models.Tag
+		if err := rows.Scan(&accountID, &t.ID, &t.Name, &t.Color); err != nil {
+			continue
+		}
+		result[accountID] = append(result[accountID], t)
+	}
+	return result, nil
+}
+
+// placeholders 生成n个SQL占位符，如 "?,?,?"
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// uniqueIDs 去除重复的ID，保持原有顺序
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	result := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
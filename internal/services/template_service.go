@@ -0,0 +1,225 @@
+// Package services 业务服务层
+//
+// template_service.go 导入模板服务
+//
+// 功能说明：
+// - 内置模板：standard（标准格式）、auto（自动识别）和password（密码登录账号）
+// - 用户自定义模板的CRUD操作（分隔符、字段顺序、忽略列）
+// - 模板字段校验（必需字段齐全且不重复）
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"outlook-mail-manager/internal/database"
+	"outlook-mail-manager/internal/models"
+	"strings"
+)
+
+// builtinTemplates 内置导入模板
+var builtinTemplates = []models.ImportTemplate{
+	{
+		Name: models.ImportTemplateStandard,
+		Fields: []string{
+			models.TemplateFieldEmail, models.TemplateFieldPassword, models.TemplateFieldClientID,
+			models.TemplateFieldRefreshToken, models.TemplateFieldGroup,
+		},
+		Builtin: true,
+	},
+	{Name: models.ImportTemplateAuto, Fields: []string{}, Builtin: true},
+	{
+		Name:    models.ImportTemplatePassword,
+		Fields:  []string{models.TemplateFieldEmail, models.TemplateFieldPassword, models.TemplateFieldGroup},
+		Builtin: true,
+	},
+}
+
+// TemplateService 导入模板服务
+type TemplateService struct{}
+
+// NewTemplateService 创建导入模板服务实例
+//
+// 返回值：
+//   - *TemplateService: 服务实例
+func NewTemplateService() *TemplateService {
+	return &TemplateService{}
+}
+
+// List 获取所有导入模板
+//
+// 返回值：
+//   - []models.ImportTemplate: 内置模板在前，自定义模板按ID排序
+//   - error: 数据库查询错误
+func (s *TemplateService) List() ([]models.ImportTemplate, error) {
+	templates := append([]models.ImportTemplate{}, builtinTemplates...)
+	rows, err := database.DB.Query("SELECT id, name, COALESCE(separator,''), fields FROM import_templates ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.ImportTemplate
+		var fields string
+		if err := rows.Scan(&t.ID, &t.Name, &t.Separator, &fields); err != nil {
+			continue // 跳过解析失败的行
+		}
+		if err := json.Unmarshal([]byte(fields), &t.Fields); err != nil {
+			continue
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// Get 按名称获取导入模板
+//
+// 参数：
+//   - name: 模板名称，为空时返回标准模板
+//
+// 返回值：
+//   - *models.ImportTemplate: 模板对象
+//   - error: 模板不存在或数据库错误
+func (s *TemplateService) Get(name string) (*models.ImportTemplate, error) {
+	return loadImportTemplate(name)
+}
+
+// GetByID 按ID获取自定义导入模板
+//
+// 参数：
+//   - id: 模板ID
+//
+// 返回值：
+//   - *models.ImportTemplate: 模板对象
+//   - error: 模板不存在或数据库错误
+func (s *TemplateService) GetByID(id int64) (*models.ImportTemplate, error) {
+	var name string
+	if err := database.DB.QueryRow("SELECT name FROM import_templates WHERE id = ?", id).Scan(&name); err != nil {
+		return nil, err
+	}
+	return loadImportTemplate(name)
+}
+
+// Create 创建自定义导入模板
+//
+// 参数：
+//   - tpl: 模板定义（名称、分隔符、字段顺序）
+//
+// 返回值：
+//   - *models.ImportTemplate: 创建成功的模板
+//   - error: 校验失败或重名时返回错误
+func (s *TemplateService) Create(tpl models.ImportTemplate) (*models.ImportTemplate, error) {
+	if err := validateTemplate(&tpl); err != nil {
+		return nil, err
+	}
+	fields, _ := json.Marshal(tpl.Fields)
+	res, err := database.DB.Exec("INSERT INTO import_templates (name, separator, fields) VALUES (?, ?, ?)",
+		tpl.Name, tpl.Separator, string(fields))
+	if err != nil {
+		return nil, err
+	}
+	tpl.ID, _ = res.LastInsertId()
+	tpl.Builtin = false
+	return &tpl, nil
+}
+
+// Update 修改自定义导入模板
+//
+// 参数：
+//   - tpl: 模板定义，按ID更新
+//
+// 返回值：
+//   - error: 校验失败或更新失败时返回错误
+func (s *TemplateService) Update(tpl models.ImportTemplate) error {
+	if err := validateTemplate(&tpl); err != nil {
+		return err
+	}
+	fields, _ := json.Marshal(tpl.Fields)
+	_, err := database.DB.Exec("UPDATE import_templates SET name = ?, separator = ?, fields = ? WHERE id = ?",
+		tpl.Name, tpl.Separator, string(fields), tpl.ID)
+	return err
+}
+
+// Delete 删除自定义导入模板
+//
+// 参数：
+//   - id: 模板ID
+//
+// 返回值：
+//   - error: 删除失败时返回错误
+func (s *TemplateService) Delete(id int64) error {
+	_, err := database.DB.Exec("DELETE FROM import_templates WHERE id = ?", id)
+	return err
+}
+
+// loadImportTemplate 按名称加载导入模板（内置或自定义）
+//
+// 内部方法，供导入和预览解析账号文本使用
+func loadImportTemplate(name string) (*models.ImportTemplate, error) {
+	if name == "" {
+		name = models.ImportTemplateStandard
+	}
+	for _, t := range builtinTemplates {
+		if t.Name == name {
+			tpl := t
+			return &tpl, nil
+		}
+	}
+	var t models.ImportTemplate
+	var fields string
+	err := database.DB.QueryRow("SELECT id, name, COALESCE(separator,''), fields FROM import_templates WHERE name = ?", name).
+		Scan(&t.ID, &t.Name, &t.Separator, &fields)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("import template not found: %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(fields), &t.Fields); err != nil {
+		return nil, fmt.Errorf("import template %s: %w", name, err)
+	}
+	return &t, nil
+}
+
+// validateTemplate 校验并规范化模板定义
+//
+// 名称不能为空且不能与内置模板重名；邮箱、ClientID、RefreshToken必须各出现一次
+// （密码登录账号的模板没有ClientID和RefreshToken，必须有密码），
+// 其他内置字段最多出现一次；空字段名视为忽略列
+func validateTemplate(tpl *models.ImportTemplate) error {
+	tpl.Name = strings.TrimSpace(tpl.Name)
+	if tpl.Name == "" {
+		return fmt.Errorf("template name is empty")
+	}
+	for _, t := range builtinTemplates {
+		if t.Name == tpl.Name {
+			return fmt.Errorf("template name is reserved: %s", tpl.Name)
+		}
+	}
+	counts := make(map[string]int)
+	for i, f := range tpl.Fields {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			f = models.TemplateFieldIgnore
+		}
+		tpl.Fields[i] = f
+		if f != models.TemplateFieldIgnore {
+			counts[f]++
+			if counts[f] > 1 {
+				return fmt.Errorf("duplicate template field: %s", f)
+			}
+		}
+	}
+	required := []string{models.TemplateFieldEmail, models.TemplateFieldClientID, models.TemplateFieldRefreshToken}
+	if counts[models.TemplateFieldClientID] == 0 && counts[models.TemplateFieldRefreshToken] == 0 && counts[models.TemplateFieldPassword] > 0 {
+		// 密码登录账号的模板
+		required = []string{models.TemplateFieldEmail, models.TemplateFieldPassword}
+	}
+	for _, required := range required {
+		if counts[required] == 0 {
+			return fmt.Errorf("template must contain field: %s", required)
+		}
+	}
+	return nil
+}
@@ -16,6 +16,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -53,23 +54,27 @@ const (
 )
 
 // RefreshAccessToken 刷新访问令牌（REST API，不设置scope使用原始权限）
-func RefreshAccessToken(clientID, refreshToken string) (*TokenResponse, error) {
+//
+// ctx取消或超时时中止请求
+func RefreshAccessToken(ctx context.Context, clientID, refreshToken string) (*TokenResponse, error) {
 	// 尝试consumers端点（个人账户）
-	token, err := refreshWithEndpoint(clientID, refreshToken, "", "consumers")
+	token, err := refreshWithEndpoint(ctx, clientID, refreshToken, "", "consumers")
 	if err != nil && strings.Contains(err.Error(), "invalid_grant") {
 		// 回退到common端点（工作/学校账户）
-		return refreshWithEndpoint(clientID, refreshToken, "", "common")
+		return refreshWithEndpoint(ctx, clientID, refreshToken, "", "common")
 	}
 	return token, err
 }
 
 // RefreshAccessTokenForIMAP 刷新访问令牌（IMAP scope）
-func RefreshAccessTokenForIMAP(clientID, refreshToken string) (*TokenResponse, error) {
+//
+// ctx取消或超时时中止请求
+func RefreshAccessTokenForIMAP(ctx context.Context, clientID, refreshToken string) (*TokenResponse, error) {
 	// 尝试consumers端点（个人账户）
-	token, err := refreshWithEndpoint(clientID, refreshToken, ScopeIMAP, "consumers")
+	token, err := refreshWithEndpoint(ctx, clientID, refreshToken, ScopeIMAP, "consumers")
 	if err != nil && strings.Contains(err.Error(), "invalid_grant") {
 		// 回退到common端点（工作/学校账户）
-		return refreshWithEndpoint(clientID, refreshToken, ScopeIMAP, "common")
+		return refreshWithEndpoint(ctx, clientID, refreshToken, ScopeIMAP, "common")
 	}
 	return token, err
 }
@@ -80,6 +85,7 @@ func RefreshAccessTokenForIMAP(clientID, refreshToken string) (*TokenResponse, e
 // 支持不同的租户端点以适配个人账户和工作/学校账户。
 //
 // 参数：
+//   - ctx: 上下文，取消时中止刷新请求
 //   - clientID: OAuth2应用程序的客户端ID（在Azure AD中注册）
 //   - refreshToken: 用于获取新访问令牌的刷新令牌
 //   - scope: 请求的权限范围（IMAP需要设置，REST API传空字符串）
@@ -96,7 +102,7 @@ func RefreshAccessTokenForIMAP(clientID, refreshToken string) (*TokenResponse, e
 //	POST https://login.microsoftonline.com/{tenant}/oauth2/v2.0/token
 //	Content-Type: application/x-www-form-urlencoded
 //	Body: client_id=xxx&grant_type=refresh_token&refresh_token=xxx&scope=xxx
-func refreshWithEndpoint(clientID, refreshToken, scope, tenant string) (*TokenResponse, error) {
+func refreshWithEndpoint(ctx context.Context, clientID, refreshToken, scope, tenant string) (*TokenResponse, error) {
 	endpoint := "https://login.microsoftonline.com/" + tenant + "/oauth2/v2.0/token"
 
 	data := url.Values{}
@@ -109,7 +115,12 @@ func refreshWithEndpoint(clientID, refreshToken, scope, tenant string) (*TokenRe
 	}
 
 	// 发送POST请求，Content-Type为application/x-www-form-urlencoded
-	resp, err := http.Post(endpoint, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -0,0 +1,232 @@
+// Package utils 工具函数包
+//
+// account_reader.go 流式账号读取器
+//
+// 功能说明：
+// - 从io.Reader逐条读取并解析账号记录，不需要把整个文件读入内存
+// - 支持文本行（按导入模板）、带表头的CSV、JSON对象数组三种格式
+// - ParseAccountLinesWithTemplate、ParseAccountsCSV、ParseAccountsJSON基于这些读取器实现
+package utils
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"outlook-mail-manager/internal/models"
+	"strings"
+)
+
+// csvSniffSize 识别CSV分隔符时预读的最大字节数（需包含完整的表头行）
+const csvSniffSize = 64 * 1024
+
+// AccountReader 账号记录读取器
+type AccountReader interface {
+	// Next 读取下一条记录，没有更多记录时返回io.EOF
+	//
+	// 单条记录格式错误时返回带Err的ParsedLine和nil错误，可以继续读取；
+	// 返回非nil错误（io.EOF除外）表示内容无法继续解析
+	Next() (ParsedLine, error)
+}
+
+// ReadAccountLines 读取全部剩余记录
+//
+// 参数：
+//   - r: 账号记录读取器
+//
+// 返回值：
+//   - []ParsedLine: 按顺序排列的解析结果
+//   - error: 内容无法继续解析时返回错误
+func ReadAccountLines(r AccountReader) ([]ParsedLine, error) {
+	var result []ParsedLine
+	for {
+		p, err := r.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		result = append(result, p)
+	}
+}
+
+// lineAccountReader 按行读取的文本账号读取器
+type lineAccountReader struct {
+	r    *bufio.Reader
+	tpl  *models.ImportTemplate
+	line int  // 已读取的行数
+	done bool // 已读到末尾
+}
+
+// NewLineAccountReader 创建按行读取文本账号的读取器
+//
+// 每个非空行按导入模板解析为一条记录，空行跳过；行长度不受限制
+//
+// 参数：
+//   - r: 文本输入
+//   - tpl: 导入模板，nil表示标准格式
+//
+// 返回值：
+//   - AccountReader: 账号记录读取器，记录的Line为行号
+func NewLineAccountReader(r io.Reader, tpl *models.ImportTemplate) AccountReader {
+	return &lineAccountReader{r: bufio.NewReader(r), tpl: tpl}
+}
+
+// Next 读取下一个非空行
+func (l *lineAccountReader) Next() (ParsedLine, error) {
+	for !l.done {
+		text, err := l.r.ReadString('\n')
+		if err == io.EOF {
+			l.done = true
+			if text == "" {
+				break
+			}
+		} else if err != nil {
+			return ParsedLine{}, err
+		}
+		l.line++
+		text = strings.TrimRight(text, "\r\n")
+		// 跳过空行
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		acc, group, perr := ParseAccountLineWithTemplate(text, l.tpl)
+		return ParsedLine{Line: l.line, Account: acc, Group: group, Err: perr}, nil
+	}
+	return ParsedLine{}, io.EOF
+}
+
+// csvAccountReader 带表头行的CSV账号读取器
+type csvAccountReader struct {
+	r      *csv.Reader
+	fields []string // 每一列对应的模板字段名
+}
+
+// NewCSVAccountReader 创建CSV账号读取器
+//
+// 读取并映射表头行，自动识别逗号、分号或Tab分隔；之后每条记录对应一个账号，空记录跳过
+//
+// 参数：
+//   - r: CSV输入
+//   - mapping: 表头映射表（表头名 -> 模板字段名），nil表示只按别名识别
+//
+// 返回值：
+//   - AccountReader: 账号记录读取器，记录的Line为记录在文本中的起始行号
+//   - error: 内容为空、CSV格式错误或缺少必需列时返回错误
+func NewCSVAccountReader(r io.Reader, mapping map[string]string) (AccountReader, error) {
+	br := bufio.NewReaderSize(r, csvSniffSize)
+	head, _ := br.Peek(csvSniffSize) // 内容不足时返回已有的部分
+	cr := csv.NewReader(br)
+	cr.Comma = detectCSVComma(string(head))
+	cr.FieldsPerRecord = -1 // 允许各行列数不同，缺失的列按空值处理
+	cr.LazyQuotes = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty csv")
+	}
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, len(header))
+	for i, h := range header {
+		fields[i] = MapHeader(h, mapping)
+	}
+	if err := checkRequiredColumns(fields); err != nil {
+		return nil, err
+	}
+	return &csvAccountReader{r: cr, fields: fields}, nil
+}
+
+// Next 读取下一条非空记录
+func (c *csvAccountReader) Next() (ParsedLine, error) {
+	for {
+		record, err := c.r.Read()
+		if err == io.EOF {
+			return ParsedLine{}, io.EOF
+		}
+		line, _ := c.r.FieldPos(0)
+		if err != nil {
+			// 单条记录格式错误（如引号不匹配），csv.Reader可以继续读取
+			return ParsedLine{Line: line, Err: err}, nil
+		}
+		values := make(map[string]string, len(record))
+		for i, v := range record {
+			if i < len(c.fields) {
+				values[c.fields[i]] = v
+			}
+		}
+		if isBlankRecord(values) {
+			continue
+		}
+		acc, group, perr := accountFromValues(values)
+		return ParsedLine{Line: line, Account: acc, Group: group, Err: perr}, nil
+	}
+}
+
+// jsonAccountReader JSON对象数组账号读取器
+type jsonAccountReader struct {
+	dec     *json.Decoder
+	mapping map[string]string
+	index   int  // 已读取的对象数
+	done    bool // 已读到数组末尾
+}
+
+// NewJSONAccountReader 创建JSON对象数组账号读取器
+//
+// 逐个解码数组中的对象，每个对象对应一个账号，键名按表头规则映射；
+// 值可以是字符串、数字或布尔值，键名为customFields的对象会展开为自定义字段
+//
+// 参数：
+//   - r: JSON输入（对象数组）
+//   - mapping: 键名映射表（键名 -> 模板字段名），nil表示只按别名识别
+//
+// 返回值：
+//   - AccountReader: 账号记录读取器，记录的Line为对象在数组中的序号（从1开始）
+//   - error: 内容不是JSON数组时返回错误
+func NewJSONAccountReader(r io.Reader, mapping map[string]string) (AccountReader, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("invalid json: expected array")
+	}
+	return &jsonAccountReader{dec: dec, mapping: mapping}, nil
+}
+
+// Next 解码数组中的下一个对象
+func (j *jsonAccountReader) Next() (ParsedLine, error) {
+	if j.done {
+		return ParsedLine{}, io.EOF
+	}
+	if !j.dec.More() {
+		j.done = true
+		if _, err := j.dec.Token(); err != nil { // 数组结束符
+			return ParsedLine{}, fmt.Errorf("invalid json: %w", err)
+		}
+		return ParsedLine{}, io.EOF
+	}
+	var item map[string]interface{}
+	if err := j.dec.Decode(&item); err != nil {
+		return ParsedLine{}, fmt.Errorf("invalid json at item %d: %w", j.index+1, err)
+	}
+	j.index++
+
+	values := make(map[string]string, len(item))
+	for key, raw := range item {
+		if nested, ok := raw.(map[string]interface{}); ok && strings.EqualFold(key, "customFields") {
+			for k, v := range nested {
+				values[k] = jsonValueString(v)
+			}
+			continue
+		}
+		values[MapHeader(key, j.mapping)] = jsonValueString(raw)
+	}
+	acc, group, err := accountFromValues(values)
+	return ParsedLine{Line: j.index, Account: acc, Group: group, Err: err}, nil
+}
@@ -0,0 +1,157 @@
+// Package utils 工具函数包
+//
+// bundle.go 加密账号包的封装和解封
+//
+// 功能说明：
+// - 用口令加密导出内容，便于把一批账号安全地交给他人
+// - 密钥派生：PBKDF2-HMAC-SHA256（随机盐）
+// - 认证加密：AES-256-GCM，文件头作为附加认证数据，篡改任何字节都会解密失败
+// - 文本封装：Base64并加上首尾标记行，可以作为文件传递，也可以直接粘贴
+//
+// 二进制结构（Base64之前）：
+//
+//	magic "OMMB"(4) | 版本(1) | KDF类型(1) | 迭代次数(4, 大端) | 盐(16) | Nonce(12) | 密文+认证标签
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 加密包文本封装的首尾标记
+const (
+	bundleBegin = "-----BEGIN OUTLOOK MAIL MANAGER BUNDLE-----"
+	bundleEnd   = "-----END OUTLOOK MAIL MANAGER BUNDLE-----"
+)
+
+// 加密包二进制头
+const (
+	bundleMagic      = "OMMB"
+	bundleVersion    = 1      // 当前版本
+	kdfPBKDF2SHA256  = 1      // KDF类型：PBKDF2-HMAC-SHA256
+	bundleIterations = 600000 // PBKDF2迭代次数
+	maxIterations    = 10000000
+	bundleSaltLen    = 16
+	bundleNonceLen   = 12
+	bundleHeaderLen  = 4 + 1 + 1 + 4 + bundleSaltLen + bundleNonceLen
+)
+
+// 加密包错误
+var (
+	ErrBundleFormat     = errors.New("not an encrypted bundle")
+	ErrBundleVersion    = errors.New("unsupported bundle version")
+	ErrBundlePassphrase = errors.New("wrong passphrase or corrupted bundle")
+)
+
+// IsBundle 判断文本是否为加密包
+func IsBundle(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), bundleBegin)
+}
+
+// SealBundle 用口令加密内容，生成加密包文本
+//
+// 参数：
+//   - plaintext: 要加密的内容
+//   - passphrase: 口令（不能为空）
+//
+// 返回值：
+//   - string: 加密包文本（带首尾标记的Base64）
+//   - error: 口令为空或加密失败时返回错误
+func SealBundle(plaintext []byte, passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase is empty")
+	}
+	header := make([]byte, bundleHeaderLen)
+	copy(header, bundleMagic)
+	header[4] = bundleVersion
+	header[5] = kdfPBKDF2SHA256
+	binary.BigEndian.PutUint32(header[6:10], bundleIterations)
+	salt := header[10 : 10+bundleSaltLen]
+	nonce := header[10+bundleSaltLen:]
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	aead, err := bundleAEAD(passphrase, salt, bundleIterations)
+	if err != nil {
+		return "", err
+	}
+	data := aead.Seal(header, nonce, plaintext, header)
+
+	// Base64按76字符折行
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	b.WriteString(bundleBegin + "\n")
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\n" + bundleEnd + "\n")
+	return b.String(), nil
+}
+
+// OpenBundle 用口令解密加密包
+//
+// 参数：
+//   - text: 加密包文本
+//   - passphrase: 口令
+//
+// 返回值：
+//   - []byte: 解密后的内容
+//   - error: 格式错误、版本不支持、口令错误或内容被篡改时返回错误
+func OpenBundle(text, passphrase string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, bundleBegin) || !strings.HasSuffix(text, bundleEnd) {
+		return nil, ErrBundleFormat
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(text, bundleBegin), bundleEnd)
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil || len(data) < bundleHeaderLen || !bytes.Equal(data[:4], []byte(bundleMagic)) {
+		return nil, ErrBundleFormat
+	}
+	if data[4] != bundleVersion || data[5] != kdfPBKDF2SHA256 {
+		return nil, fmt.Errorf("%w: version %d, kdf %d", ErrBundleVersion, data[4], data[5])
+	}
+	iterations := binary.BigEndian.Uint32(data[6:10])
+	if iterations == 0 || iterations > maxIterations {
+		return nil, ErrBundleFormat
+	}
+	header := data[:bundleHeaderLen]
+	salt := header[10 : 10+bundleSaltLen]
+	nonce := header[10+bundleSaltLen:]
+
+	aead, err := bundleAEAD(passphrase, salt, int(iterations))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, data[bundleHeaderLen:], header)
+	if err != nil {
+		return nil, ErrBundlePassphrase
+	}
+	return plaintext, nil
+}
+
+// bundleAEAD 由口令派生密钥并创建AES-256-GCM
+func bundleAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
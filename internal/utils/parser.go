@@ -6,10 +6,14 @@
 // - 解析用户导入的账号文本
 // - 支持多种分隔符格式
 // - 批量解析多行文本
+// - 按导入模板（自定义分隔符和字段顺序）解析
+// - 按内容特征自动识别字段（GUID形式的ClientID、M.C开头的RefreshToken）
 //
 // 支持的文本格式：
 // 1. 四横线分隔：邮箱----密码----ClientID----RefreshToken----分组名
 // 2. Tab分隔：邮箱\t密码\tClientID\tRefreshToken\t分组名
+// 3. 其他顺序或分隔符：通过导入模板（models.ImportTemplate）描述，或使用自动识别
+// 4. 密码登录的IMAP账号：邮箱----密码----分组名，或ClientID和RefreshToken留空的格式1（导出的密码账号）
 //
 // 字段说明：
 // - 邮箱（必填）：Outlook邮箱地址
@@ -17,20 +21,35 @@
 // - ClientID（必填）：Azure应用注册的客户端ID
 // - RefreshToken（必填）：OAuth2刷新令牌
 // - 分组名（可选）：账号所属分组，默认为"默认分组"
+// - 附加字段（可选）：分组名之后的"字段名=值"，notes/备注 为账号备注，其余为自定义字段
+// - 没有ClientID和RefreshToken但有密码的账号作为密码登录的IMAP账号（imap-password协议）
+// 例如：邮箱----密码----ClientID----RefreshToken----分组名----notes=老客户----辅助邮箱=a@b.com
 package utils
 
 import (
 	"fmt"
+	"net/mail"
 	"outlook-mail-manager/internal/models"
+	"regexp"
 	"strings"
+	"unicode"
 )
 
+// defaultGroupName 未指定分组时的默认分组
+const defaultGroupName = "默认分组"
+
+// guidRe GUID格式（Azure应用的ClientID）
+var guidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
 // ParseAccountLine 解析单行账号文本
 //
 // 支持两种分隔符：
 // - "----"（四横线）：常见的账号导出格式
 // - "\t"（Tab）：Excel/表格复制格式
 //
+// ClientID和RefreshToken为空（如"邮箱----密码--------分组名"）或只有三个字段
+// （"邮箱----密码----分组名"）时，作为密码登录的IMAP账号
+//
 // 参数：
 //   - line: 单行账号文本
 //
@@ -46,13 +65,11 @@ func ParseAccountLine(line string) (*models.Account, string, error) {
 	}
 
 	// 根据分隔符类型拆分字段
-	var parts []string
-	if strings.Contains(line, "----") {
-		// 四横线分隔格式
-		parts = strings.Split(line, "----")
-	} else {
-		// Tab分隔格式（Excel复制）
-		parts = strings.Split(line, "\t")
+	parts := splitLine(line, "")
+
+	// 三个字段为密码登录账号的简写：邮箱----密码----分组名
+	if len(parts) == 3 && !LooksLikeClientID(strings.TrimSpace(parts[2])) {
+		parts = []string{parts[0], parts[1], "", "", parts[2]}
 	}
 
 	// 验证必填字段数量（至少4个：邮箱、密码、ClientID、RefreshToken）
@@ -61,19 +78,301 @@ func ParseAccountLine(line string) (*models.Account, string, error) {
 	}
 
 	// 解析分组名（第5个字段，可选）
-	groupName := "默认分组"
+	groupName := defaultGroupName
 	if len(parts) >= 5 && strings.TrimSpace(parts[4]) != "" {
 		groupName = strings.TrimSpace(parts[4])
 	}
 
 	// 构建账号对象
-	return &models.Account{
+	acc := &models.Account{
 		Email:        strings.TrimSpace(parts[0]), // 邮箱地址
 		Password:     strings.TrimSpace(parts[1]), // 密码
 		ClientID:     strings.TrimSpace(parts[2]), // OAuth2客户端ID
 		RefreshToken: strings.TrimSpace(parts[3]), // OAuth2刷新令牌
 		Status:       "active",                    // 默认状态为active
-	}, groupName, nil
+	}
+	// ClientID和RefreshToken为空时按密码登录账号导入（默认字段导出的密码账号）
+	if err := checkRequiredFields(acc); err != nil {
+		return nil, "", err
+	}
+	// 解析附加字段（第6个字段起，格式为"字段名=值"）
+	if len(parts) > 5 {
+		parseExtraFields(acc, parts[5:])
+	}
+	return acc, groupName, nil
+}
+
+// splitLine 按分隔符拆分一行
+//
+// 分隔符为空时自动识别："----"（四横线）优先，否则按Tab（Excel复制格式）拆分
+func splitLine(line, sep string) []string {
+	if sep == "" {
+		if strings.Contains(line, "----") {
+			sep = "----"
+		} else {
+			sep = "\t"
+		}
+	}
+	return strings.Split(line, sep)
+}
+
+// ParseAccountLineWithTemplate 按导入模板解析单行账号文本
+//
+// 模板为nil或标准模板时等同于ParseAccountLine，自动识别模板使用DetectAccountLine
+//
+// 参数：
+//   - line: 单行账号文本
+//   - tpl: 导入模板
+//
+// 返回值：
+//   - *models.Account: 解析成功的账号对象
+//   - string: 分组名称（默认为"默认分组"）
+//   - error: 解析失败时返回错误（空行或缺少必需字段）
+func ParseAccountLineWithTemplate(line string, tpl *models.ImportTemplate) (*models.Account, string, error) {
+	if tpl == nil || tpl.Name == models.ImportTemplateStandard {
+		return ParseAccountLine(line)
+	}
+	if tpl.Name == models.ImportTemplateAuto {
+		return DetectAccountLine(line)
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, "", fmt.Errorf("empty line")
+	}
+	parts := splitLine(line, tpl.Separator)
+
+	values := make(map[string]string, len(tpl.Fields))
+	for i, field := range tpl.Fields {
+		if i < len(parts) {
+			values[field] = parts[i]
+		}
+	}
+	acc, groupName, err := accountFromValues(values)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w (template %s, got %d fields)", err, tpl.Name, len(parts))
+	}
+	// 模板之外的列按"字段名=值"解析
+	if len(parts) > len(tpl.Fields) {
+		parseExtraFields(acc, parts[len(tpl.Fields):])
+	}
+	return acc, groupName, nil
+}
+
+// DetectAccountLine 自动识别字段解析单行账号文本
+//
+// 按内容特征识别各列，适用于字段顺序不固定的来源：
+// - 第一个邮箱形式的列为邮箱，其余邮箱形式的列（如辅助邮箱）忽略
+// - GUID形式的列为ClientID
+// - RefreshToken形式的列（见LooksLikeRefreshToken）为RefreshToken
+// - "字段名=值"形式的列为备注或自定义字段
+// - 其余列：出现在ClientID/RefreshToken之前的第一列为密码，之后的第一列为分组名，其他忽略
+// - 没有ClientID和RefreshToken时（密码登录账号），密码之后的第一列为分组名
+//
+// 参数：
+//   - line: 单行账号文本
+//
+// 返回值：
+//   - *models.Account: 解析成功的账号对象
+//   - string: 分组名称（默认为"默认分组"）
+//   - error: 解析失败时返回错误（空行或无法识别必需字段）
+func DetectAccountLine(line string) (*models.Account, string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, "", fmt.Errorf("empty line")
+	}
+	acc := &models.Account{Status: "active"}
+	groupName := ""
+	pending := "" // 出现在ClientID/RefreshToken之前、密码之后的第一列
+	var extras []string
+	for _, part := range splitLine(line, "") {
+		value := strings.TrimSpace(part)
+		switch {
+		case value == "":
+			continue
+		case LooksLikeClientID(value) && acc.ClientID == "":
+			acc.ClientID = value
+		case LooksLikeRefreshToken(value) && acc.RefreshToken == "":
+			acc.RefreshToken = value
+		case looksLikeEmail(value):
+			if acc.Email == "" {
+				acc.Email = value
+			}
+		case strings.Contains(value, "="):
+			extras = append(extras, value)
+		case acc.ClientID == "" && acc.RefreshToken == "":
+			if acc.Password == "" {
+				acc.Password = value
+			} else if pending == "" {
+				pending = value
+			}
+		default:
+			if groupName == "" {
+				groupName = value
+			}
+		}
+	}
+	if err := checkRequiredFields(acc); err != nil {
+		return nil, "", err
+	}
+	if groupName == "" && acc.Protocol == models.ProtocolIMAPPassword {
+		groupName = pending
+	}
+	if groupName == "" {
+		groupName = defaultGroupName
+	}
+	parseExtraFields(acc, extras)
+	return acc, groupName, nil
+}
+
+// LooksLikeClientID 判断字符串是否为GUID形式的ClientID
+func LooksLikeClientID(s string) bool {
+	return guidRe.MatchString(s)
+}
+
+// LooksLikeRefreshToken 判断字符串是否像Microsoft的RefreshToken
+//
+// 个人账号的RefreshToken以"M.C"开头；其他情况要求足够长且不含空白、"@"
+func LooksLikeRefreshToken(s string) bool {
+	if strings.HasPrefix(s, "M.C") {
+		return true
+	}
+	return len(s) >= 200 && !strings.ContainsAny(s, " \t@")
+}
+
+// looksLikeEmail 粗略判断字符串是否为邮箱地址
+func looksLikeEmail(s string) bool {
+	at := strings.LastIndex(s, "@")
+	return at > 0 && strings.Contains(s[at+1:], ".") && !strings.ContainsAny(s, " \t")
+}
+
+// minRefreshTokenLen RefreshToken的最小合理长度（Microsoft签发的RefreshToken通常有数百个字符）
+const minRefreshTokenLen = 100
+
+// ValidateAccount 校验导入账号的字段格式
+//
+// 只检查格式是否合理，不访问网络：
+// - 邮箱必须是不带显示名称的合法地址
+// - ClientID必须是GUID形式
+// - RefreshToken不能含空白或控制字符，长度不能过短
+// - 密码登录账号（imap-password协议）不检查ClientID和RefreshToken
+//
+// 参数：
+//   - acc: 要校验的账号
+//
+// 返回值：
+//   - error: 第一个不合法的字段及原因，全部合法时返回nil
+func ValidateAccount(acc *models.Account) error {
+	addr, err := mail.ParseAddress(acc.Email)
+	if err != nil || addr.Name != "" || addr.Address != acc.Email || !looksLikeEmail(acc.Email) {
+		return fmt.Errorf("invalid email: %s", acc.Email)
+	}
+	if acc.Protocol == models.ProtocolIMAPPassword {
+		return nil
+	}
+	if !LooksLikeClientID(acc.ClientID) {
+		return fmt.Errorf("client_id is not a GUID: %s", acc.ClientID)
+	}
+	if strings.IndexFunc(acc.RefreshToken, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return fmt.Errorf("refresh_token contains whitespace")
+	}
+	if len(acc.RefreshToken) < minRefreshTokenLen {
+		return fmt.Errorf("refresh_token too short (%d chars)", len(acc.RefreshToken))
+	}
+	return nil
+}
+
+// checkRequiredFields 检查必需字段是否齐全
+//
+// 邮箱必需；ClientID和RefreshToken都为空但有密码时，
+// 账号作为密码登录的IMAP账号（Protocol设为imap-password），否则两者都必需
+func checkRequiredFields(acc *models.Account) error {
+	var missing []string
+	if acc.Email == "" {
+		missing = append(missing, models.TemplateFieldEmail)
+	}
+	if acc.ClientID == "" && acc.RefreshToken == "" && acc.Password != "" {
+		acc.Protocol = models.ProtocolIMAPPassword
+	} else if acc.ClientID == "" && acc.RefreshToken == "" {
+		missing = append(missing, models.TemplateFieldClientID+", "+models.TemplateFieldRefreshToken+" (or "+models.TemplateFieldPassword+")")
+	} else {
+		if acc.ClientID == "" {
+			missing = append(missing, models.TemplateFieldClientID)
+		}
+		if acc.RefreshToken == "" {
+			missing = append(missing, models.TemplateFieldRefreshToken)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// parseExtraFields 解析"字段名=值"形式的附加字段
+//
+// notes/备注 写入账号备注，其余写入自定义字段；不含"="的片段会被忽略
+//
+// 参数：
+//   - acc: 要填充的账号对象
+//   - extras: 附加字段片段列表
+func parseExtraFields(acc *models.Account, extras []string) {
+	for _, extra := range extras {
+		key, value, ok := strings.Cut(extra, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(key) {
+		case "notes", "note", "备注":
+			acc.Notes = value
+		default:
+			if acc.CustomFields == nil {
+				acc.CustomFields = make(map[string]string)
+			}
+			acc.CustomFields[key] = value
+		}
+	}
+}
+
+// ParsedLine 单行解析结果
+//
+// 保留原始行号，解析失败时Err非空、Account为nil；
+// 解析成功但校验失败（见ValidateAccount）时Err和Account都非空
+type ParsedLine struct {
+	Line    int             // 行号（从1开始）
+	Account *models.Account // 解析成功的账号对象
+	Group   string          // 分组名称
+	Err     error           // 解析错误
+}
+
+// ParseAccountLines 逐行解析账号文本，保留每一行的结果
+//
+// 与ParseAccountsText不同，解析失败的行也会返回（带行号和错误），
+// 便于生成逐行导入报告；空行直接跳过
+//
+// 参数：
+//   - text: 包含多行账号信息的文本
+//
+// 返回值：
+//   - []ParsedLine: 每个非空行的解析结果，按行号顺序
+func ParseAccountLines(text string) []ParsedLine {
+	return ParseAccountLinesWithTemplate(text, nil)
+}
+
+// ParseAccountLinesWithTemplate 按导入模板逐行解析账号文本
+//
+// 参数：
+//   - text: 包含多行账号信息的文本
+//   - tpl: 导入模板，nil表示标准格式
+//
+// 返回值：
+//   - []ParsedLine: 每个非空行的解析结果，按行号顺序
+func ParseAccountLinesWithTemplate(text string, tpl *models.ImportTemplate) []ParsedLine {
+	// 从字符串读取不会产生读取错误
+	result, _ := ReadAccountLines(NewLineAccountReader(strings.NewReader(text), tpl))
+	return result
 }
 
 // ParseAccountsText 批量解析账号文本
@@ -89,28 +388,17 @@ func ParseAccountLine(line string) (*models.Account, string, error) {
 //   - []string: 对应的分组名称列表（与账号列表一一对应）
 //   - []error: 解析错误列表（包含行号信息）
 func ParseAccountsText(text string) ([]*models.Account, []string, []error) {
-	// 按换行符拆分为多行
-	lines := strings.Split(text, "\n")
 	var accounts []*models.Account
 	var groups []string
 	var errors []error
-
-	// 逐行解析
-	for i, line := range lines {
-		// 跳过空行
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		// 解析单行
-		acc, group, err := ParseAccountLine(line)
-		if err != nil {
+	for _, p := range ParseAccountLines(text) {
+		if p.Err != nil {
 			// 记录错误（包含行号，便于用户定位问题）
-			errors = append(errors, fmt.Errorf("line %d: %w", i+1, err))
+			errors = append(errors, fmt.Errorf("line %d: %w", p.Line, p.Err))
 			continue
 		}
-		// 添加到结果列表
-		accounts = append(accounts, acc)
-		groups = append(groups, group)
+		accounts = append(accounts, p.Account)
+		groups = append(groups, p.Group)
 	}
 	return accounts, groups, errors
 }
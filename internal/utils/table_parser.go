@@ -0,0 +1,229 @@
+// Package utils 工具函数包
+//
+// table_parser.go 表格（CSV）和JSON格式的账号解析工具
+//
+// 功能说明：
+// - 解析带表头行的CSV（支持引号包裹的字段、字段内换行）
+// - 解析JSON对象数组
+// - 按表头名（或JSON键名）映射到账号字段，未识别的列作为自定义字段
+//
+// 表头映射：
+// - 默认按常见别名识别（如 email/邮箱、client_id/ClientID、refresh_token/令牌、group/分组、notes/备注）
+// - 可传入映射表覆盖：表头名 -> 模板字段名（models.TemplateFieldXxx、"-"忽略或自定义字段名）
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"outlook-mail-manager/internal/models"
+	"sort"
+	"strings"
+)
+
+// headerAliases 表头别名（规范化后）-> 模板字段名
+var headerAliases = map[string]string{
+	"email":        models.TemplateFieldEmail,
+	"mail":         models.TemplateFieldEmail,
+	"emailaddress": models.TemplateFieldEmail,
+	"account":      models.TemplateFieldEmail,
+	"邮箱":           models.TemplateFieldEmail,
+	"账号":           models.TemplateFieldEmail,
+	"password":     models.TemplateFieldPassword,
+	"pass":         models.TemplateFieldPassword,
+	"pwd":          models.TemplateFieldPassword,
+	"密码":           models.TemplateFieldPassword,
+	"clientid":     models.TemplateFieldClientID,
+	"appid":        models.TemplateFieldClientID,
+	"客户端id":        models.TemplateFieldClientID,
+	"refreshtoken": models.TemplateFieldRefreshToken,
+	"token":        models.TemplateFieldRefreshToken,
+	"令牌":           models.TemplateFieldRefreshToken,
+	"group":        models.TemplateFieldGroup,
+	"groupname":    models.TemplateFieldGroup,
+	"分组":           models.TemplateFieldGroup,
+	"notes":        models.TemplateFieldNotes,
+	"note":         models.TemplateFieldNotes,
+	"remark":       models.TemplateFieldNotes,
+	"remarks":      models.TemplateFieldNotes,
+	"备注":           models.TemplateFieldNotes,
+}
+
+// MapHeader 将表头名映射为模板字段名
+//
+// 优先使用调用方提供的映射表（按原始表头名匹配），其次按常见别名识别，
+// 都不匹配时原样返回，作为同名自定义字段
+//
+// 参数：
+//   - header: 表头名或JSON键名
+//   - mapping: 自定义映射表，可为nil
+//
+// 返回值：
+//   - string: 模板字段名
+func MapHeader(header string, mapping map[string]string) string {
+	header = strings.TrimSpace(strings.TrimPrefix(header, "\ufeff")) // 去除Excel导出的BOM
+	if field, ok := mapping[header]; ok {
+		return field
+	}
+	key := strings.ToLower(header)
+	key = strings.NewReplacer(" ", "", "_", "", "-", "").Replace(key)
+	if field, ok := headerAliases[key]; ok {
+		return field
+	}
+	return header
+}
+
+// ParseAccountsCSV 解析带表头行的CSV账号数据
+//
+// 第一条记录为表头，之后每条记录对应一个账号；自动识别逗号、分号或Tab分隔
+// 空记录跳过，字段缺失的记录作为无效行返回
+//
+// 参数：
+//   - text: CSV文本
+//   - mapping: 表头映射表（表头名 -> 模板字段名），nil表示只按别名识别
+//
+// 返回值：
+//   - []ParsedLine: 每条记录的解析结果，Line为记录在文本中的起始行号
+//   - error: CSV格式错误或缺少必需列时返回错误
+func ParseAccountsCSV(text string, mapping map[string]string) ([]ParsedLine, error) {
+	r, err := NewCSVAccountReader(strings.NewReader(text), mapping)
+	if err != nil {
+		return nil, err
+	}
+	return ReadAccountLines(r)
+}
+
+// ParseAccountsJSON 解析JSON对象数组形式的账号数据
+//
+// 每个对象对应一个账号，键名按表头规则映射；值可以是字符串、数字或布尔值，
+// 键名为customFields的对象会展开为自定义字段
+//
+// 参数：
+//   - text: JSON文本（对象数组）
+//   - mapping: 键名映射表（键名 -> 模板字段名），nil表示只按别名识别
+//
+// 返回值：
+//   - []ParsedLine: 每个对象的解析结果，Line为对象在数组中的序号（从1开始）
+//   - error: 不是合法的JSON数组时返回错误
+func ParseAccountsJSON(text string, mapping map[string]string) ([]ParsedLine, error) {
+	r, err := NewJSONAccountReader(strings.NewReader(text), mapping)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := ReadAccountLines(r)
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// accountFromValues 由"模板字段名 -> 值"构建账号对象
+//
+// 内置字段名写入账号对应属性，"-"或空字段名忽略，其他非空值作为自定义字段
+func accountFromValues(values map[string]string) (*models.Account, string, error) {
+	acc := &models.Account{Status: "active"}
+	groupName := defaultGroupName
+	// 按字段名排序，保证自定义字段的处理顺序稳定
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, field := range keys {
+		value := strings.TrimSpace(values[field])
+		switch field {
+		case models.TemplateFieldEmail:
+			acc.Email = value
+		case models.TemplateFieldPassword:
+			acc.Password = value
+		case models.TemplateFieldClientID:
+			acc.ClientID = value
+		case models.TemplateFieldRefreshToken:
+			acc.RefreshToken = value
+		case models.TemplateFieldGroup:
+			if value != "" {
+				groupName = value
+			}
+		case models.TemplateFieldNotes:
+			acc.Notes = value
+		case models.TemplateFieldIgnore, "":
+			// 忽略该列
+		default:
+			if value != "" {
+				if acc.CustomFields == nil {
+					acc.CustomFields = make(map[string]string)
+				}
+				acc.CustomFields[field] = value
+			}
+		}
+	}
+	if err := checkRequiredFields(acc); err != nil {
+		return nil, "", err
+	}
+	return acc, groupName, nil
+}
+
+// checkRequiredColumns 检查表头是否包含必需列
+//
+// 必需邮箱列，以及ClientID和RefreshToken列；两者都没有时必需密码列（密码登录账号）
+func checkRequiredColumns(fields []string) error {
+	has := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		has[f] = true
+	}
+	required := []string{models.TemplateFieldEmail, models.TemplateFieldClientID, models.TemplateFieldRefreshToken}
+	if !has[models.TemplateFieldClientID] && !has[models.TemplateFieldRefreshToken] && has[models.TemplateFieldPassword] {
+		required = []string{models.TemplateFieldEmail, models.TemplateFieldPassword}
+	}
+	var missing []string
+	for _, f := range required {
+		if !has[f] {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("csv header missing column: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// detectCSVComma 根据表头行识别CSV分隔符（逗号、分号或Tab，取出现次数最多的）
+func detectCSVComma(text string) rune {
+	header, _, _ := strings.Cut(text, "\n")
+	comma, best := ',', strings.Count(header, ",")
+	for _, c := range []rune{';', '\t'} {
+		if n := strings.Count(header, string(c)); n > best {
+			comma, best = c, n
+		}
+	}
+	return comma
+}
+
+// isBlankRecord 判断记录是否所有值都为空
+func isBlankRecord(values map[string]string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonValueString 将JSON值转换为字符串，null和对象/数组以外的值按字面形式输出
+func jsonValueString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		if val {
+			return "true"
+		}
+		return "false"
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}